@@ -19,7 +19,9 @@ import (
 
 	"shortly/app/accounts"
 	"shortly/app/billing"
+	"shortly/app/links"
 	"shortly/app/rbac"
+	"shortly/cache"
 	"shortly/config"
 )
 
@@ -501,6 +503,7 @@ type ProfileResponse struct {
 	BillingPlanExpiredAt string                         `json:"billingPlanExpiredAt"`
 	PlansAvailable       []BillingPlanOptionResponse    `json:"plansAvailable"`
 	BillingUsage         []BillingOptionCounterResponse `json:"billingUsage"`
+	DefaultRedirectType  int                            `json:"defaultRedirectType"`
 }
 
 // GetProfile ...
@@ -589,8 +592,53 @@ func GetProfile(repo *accounts.UsersRepository, rbacRepo rbac.IRbacRepository, b
 			BillingPlanExpiredAt: billingPlan.End.Format(time.RFC3339),
 			PlansAvailable:       plansOptionsResponse,
 			BillingUsage:         billingPlanUsageResponse,
+			DefaultRedirectType:  account.DefaultRedirectType,
 		}
 
 		response.Object(w, resp, http.StatusOK)
 	})
 }
+
+// AccountSettingsForm ...
+type AccountSettingsForm struct {
+	DefaultRedirectType int `json:"defaultRedirectType"`
+}
+
+// UpdateAccountSettings changes account defaults, cached links of the account are refreshed
+func UpdateAccountSettings(repo *accounts.UsersRepository, linksRepo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		var form AccountSettingsForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		if err := links.ValidateRedirectType(form.DefaultRedirectType); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := repo.UpdateDefaultRedirectType(claims.AccountID, form.DefaultRedirectType); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rows, err := linksRepo.GetAccountLinks(claims.AccountID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, link := range rows {
+			links.StoreCache(urlCache, link)
+		}
+
+		response.Ok(w)
+	})
+}
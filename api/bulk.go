@@ -0,0 +1,264 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/app/accounts"
+	"shortly/app/bulk"
+	"shortly/app/campaigns"
+	"shortly/app/links"
+)
+
+const (
+	// bulkMaxLinks limits a number of links changed by a single bulk job
+	bulkMaxLinks = 10000
+	// bulkJobsLimit is a number of the latest jobs returned by api
+	bulkJobsLimit = 50
+)
+
+// BulkLinksFilterForm ...
+type BulkLinksFilterForm struct {
+	Tags     []string `json:"tags"`
+	ShortUrl []string `json:"shortUrl"`
+	LongUrl  []string `json:"longUrl"`
+	FullText string   `json:"fullText"`
+}
+
+// BulkLinksForm selects links by ids or by a filter, both are combined when provided
+type BulkLinksForm struct {
+	Action     string               `json:"action"`
+	LinkIDs    []int64              `json:"linkIds"`
+	Filter     *BulkLinksFilterForm `json:"filter"`
+	Tag        string               `json:"tag"`
+	GroupID    int64                `json:"groupId"`
+	CampaignID int64                `json:"campaignId"`
+	ChannelID  int64                `json:"channelId"`
+	UtmSource  string               `json:"utmSource"`
+	UtmMedium  string               `json:"utmMedium"`
+	UtmTerm    string               `json:"utmTerm"`
+	UtmContent string               `json:"utmContent"`
+	UtmMode    string               `json:"utmMode"`
+}
+
+// BulkJobResponse ...
+type BulkJobResponse struct {
+	ID         int64             `json:"id"`
+	Action     string            `json:"action"`
+	Status     string            `json:"status"`
+	Total      int               `json:"total"`
+	Processed  int               `json:"processed"`
+	Failed     int               `json:"failed"`
+	Results    []bulk.ItemResult `json:"results,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+}
+
+func bulkJobResponse(job bulk.Job) BulkJobResponse {
+	return BulkJobResponse{
+		ID:         job.ID,
+		Action:     job.Action,
+		Status:     job.Status,
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Failed:     job.Failed,
+		Results:    job.Results,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// checkBulkTarget verifies that a group or a campaign channel of a bulk action belongs to account
+func checkBulkTarget(req bulk.Request, accountID int64, usersRepo *accounts.UsersRepository, campaignsRepo *campaigns.Repository) (bool, error) {
+
+	switch req.Action {
+	case bulk.ActionGroup:
+		groups, err := usersRepo.GetAccountGroups(accountID)
+		if err != nil {
+			return false, err
+		}
+		for _, group := range groups {
+			if group.ID == req.GroupID {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case bulk.ActionCampaign:
+		channels, err := campaignsRepo.GetCampaignChannels(accountID, req.CampaignID)
+		if err != nil {
+			return false, err
+		}
+		for _, channel := range channels {
+			if channel.ID == req.ChannelID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// CreateBulkJob starts a bulk action over selected links, a job report is available by job id
+func CreateBulkJob(repo *links.LinksRepository, usersRepo *accounts.UsersRepository, campaignsRepo *campaigns.Repository, runner *bulk.Runner, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		var form BulkLinksForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		req := bulk.Request{
+			Action:     form.Action,
+			LinkIDs:    form.LinkIDs,
+			Tag:        form.Tag,
+			GroupID:    form.GroupID,
+			CampaignID: form.CampaignID,
+			ChannelID:  form.ChannelID,
+			UTM: campaigns.UTMSetting{
+				Source:  form.UtmSource,
+				Medium:  form.UtmMedium,
+				Term:    form.UtmTerm,
+				Content: form.UtmContent,
+				Mode:    form.UtmMode,
+			},
+		}
+		if req.UTM.Mode == "" {
+			req.UTM.Mode = links.UTMPreserve
+		}
+
+		var filters []links.LinkFilter
+		if len(form.LinkIDs) > 0 {
+			filters = append(filters, links.LinkFilter{LinkIDs: form.LinkIDs})
+		}
+		if form.Filter != nil {
+			req.Filter = &links.LinkFilter{
+				Tags:     form.Filter.Tags,
+				ShortUrl: form.Filter.ShortUrl,
+				LongUrl:  form.Filter.LongUrl,
+				FullText: form.Filter.FullText,
+			}
+			filters = append(filters, *req.Filter)
+		}
+
+		if err := req.Validate(); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(form.LinkIDs) > bulkMaxLinks {
+			response.Error(w, fmt.Sprintf("bulk action is limited to %d links", bulkMaxLinks), http.StatusBadRequest)
+			return
+		}
+
+		if ok, err := checkBulkTarget(req, claims.AccountID, usersRepo, campaignsRepo); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		} else if !ok {
+			response.Error(w, "group or campaign channel not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := repo.GetUserLinks(claims.AccountID, claims.UserID, bulkMaxLinks+1, 0, filters...)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if len(result.Rows) > bulkMaxLinks {
+			response.Error(w, fmt.Sprintf("bulk action is limited to %d links", bulkMaxLinks), http.StatusBadRequest)
+			return
+		}
+
+		// requested ids which are not found or filtered out are reported as failed items
+		var missing []int64
+		if len(form.LinkIDs) > 0 {
+			found := make(map[int64]bool, len(result.Rows))
+			for _, link := range result.Rows {
+				found[link.ID] = true
+			}
+			for _, linkID := range form.LinkIDs {
+				if !found[linkID] {
+					missing = append(missing, linkID)
+					found[linkID] = true
+				}
+			}
+		}
+
+		job, err := runner.Start(claims.AccountID, claims.UserID, req, result.Rows, missing)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		jobResponse := bulkJobResponse(*job)
+		response.Object(w, &jobResponse, http.StatusAccepted)
+
+	})
+}
+
+// GetBulkJobs ...
+func GetBulkJobs(repo *bulk.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		jobs, err := repo.GetJobs(GetAccountID(r), bulkJobsLimit)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]BulkJobResponse, 0, len(jobs))
+		for _, job := range jobs {
+			list = append(list, bulkJobResponse(job))
+		}
+
+		response.Object(w, &list, http.StatusOK)
+
+	})
+}
+
+// GetBulkJob returns a job progress and a per link report of a finished job
+func GetBulkJob(repo *bulk.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+		if err != nil {
+			response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		job, err := repo.GetJob(GetAccountID(r), jobID)
+		if err == sql.ErrNoRows {
+			response.Error(w, "job not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		jobResponse := bulkJobResponse(job)
+		response.Object(w, &jobResponse, http.StatusOK)
+
+	})
+}
@@ -11,11 +11,13 @@ import (
 
 	"shortly/api/response"
 	"shortly/app/campaigns"
+	"shortly/app/links"
 	"shortly/app/rbac"
+	"shortly/cache"
 )
 
 // CampaignRoutes ...
-func CampaignRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.HandlerFunc, repo *campaigns.Repository, logger *log.Logger) {
+func CampaignRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.HandlerFunc, repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) {
 	r.Get("/api/v1/campaigns", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns", "read_campaigns", "GET"),
 		GetUserCampaigns(repo, logger),
@@ -26,15 +28,15 @@ func CampaignRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.
 	)))
 	r.Post("/api/v1/campaigns/start", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/start", "start_campaign", "POST"),
-		StartCampaign(repo, logger),
+		StartCampaign(repo, urlCache, logger),
 	)))
 	r.Post("/api/v1/campaigns/stop", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/stop", "stop_campaign", "POST"),
-		StopCampaign(repo, logger),
+		StopCampaign(repo, urlCache, logger),
 	)))
 	r.Delete("/api/v1/campaigns/{id}", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/{id}", "delete_campaign", "DELETE"),
-		DeleteCampaign(repo, logger),
+		DeleteCampaign(repo, urlCache, logger),
 	)))
 	r.Get("/api/v1/campaigns/{id}/channels/{channelId}/links", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/{id}/channels/{channelId}/links", "get_links_for_channel", "GET"),
@@ -42,11 +44,11 @@ func CampaignRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.
 	)))
 	r.Post("/api/v1/campaigns/{id}/channels/{channelId}/links", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/{id}/channels/{channelId}/links", "add_link_to_channel", "POST"),
-		AddLinkToCampaignChannel(repo, logger),
+		AddLinkToCampaignChannel(repo, urlCache, logger),
 	)))
 	r.Delete("/api/v1/campaigns/{id}/channels/{channelId}/links/{linkId}", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/{id}/channels/{channelId}/links/{linkId}", "delete_link_from_channel", "DELETE"),
-		DeleteLinkFromCampaignChannel(repo, logger),
+		DeleteLinkFromCampaignChannel(repo, urlCache, logger),
 	)))
 	r.Get("/api/v1/campaigns/data", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/data", "get_link_data_for_campaign", "GET"),
@@ -74,7 +76,7 @@ func CampaignRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.
 	)))
 	r.Delete("/api/v1/campaigns/{id}/channels/{channelId}", http.HandlerFunc(auth(
 		rbac.NewPermission("/api/v1/campaigns/{id}/channels/{channelId}", "delete_channel_from_campaign", "DELETE"),
-		DeleteChannelFromCampaign(repo, logger),
+		DeleteChannelFromCampaign(repo, urlCache, logger),
 	)))
 }
 
@@ -201,7 +203,7 @@ type StartCampaignForm struct {
 }
 
 // StartCampaign ...
-func StartCampaign(repo *campaigns.Repository, logger *log.Logger) http.Handler {
+func StartCampaign(repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -225,6 +227,8 @@ func StartCampaign(repo *campaigns.Repository, logger *log.Logger) http.Handler
 			return
 		}
 
+		invalidateCampaignLinks(repo, urlCache, form.CampaignID, logger)
+
 		response.Ok(w)
 	})
 }
@@ -235,7 +239,7 @@ type StopCampaignForm struct {
 }
 
 // StopCampaign ...
-func StopCampaign(repo *campaigns.Repository, logger *log.Logger) http.Handler {
+func StopCampaign(repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		var form StopCampaignForm
@@ -258,12 +262,14 @@ func StopCampaign(repo *campaigns.Repository, logger *log.Logger) http.Handler {
 			return
 		}
 
+		invalidateCampaignLinks(repo, urlCache, form.CampaignID, logger)
+
 		response.Ok(w)
 	})
 }
 
 // DeleteCampaign ...
-func DeleteCampaign(repo *campaigns.Repository, logger *log.Logger) http.Handler {
+func DeleteCampaign(repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -285,12 +291,17 @@ func DeleteCampaign(repo *campaigns.Repository, logger *log.Logger) http.Handler
 			return
 		}
 
+		// links are read before delete, they are out of the campaign after it
+		keys := campaignLinkKeys(repo, id, logger)
+
 		if err := repo.DeleteCampaign(id); err != nil {
 			logError(logger, err)
 			response.Error(w, "create form error", http.StatusBadRequest)
 			return
 		}
 
+		invalidateLinks(urlCache, keys)
+
 		response.Ok(w)
 	})
 }
@@ -363,10 +374,12 @@ type AddLinkToCampaignForm struct {
 	UtmMedium  string `json:"utmMedium"`
 	UtmTerm    string `json:"utmTerm"`
 	UtmContent string `json:"utmContent"`
+	// UtmMode is "preserve" to keep parameters of a destination url or "override" to replace them
+	UtmMode string `json:"utmMode"`
 }
 
 // AddLinkToCampaignChannel ...
-func AddLinkToCampaignChannel(repo *campaigns.Repository, logger *log.Logger) http.Handler {
+func AddLinkToCampaignChannel(repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		var form AddLinkToCampaignForm
@@ -418,12 +431,22 @@ func AddLinkToCampaignChannel(repo *campaigns.Repository, logger *log.Logger) ht
 			return
 		}
 
+		if err := links.ValidateUTMMode(form.UtmMode); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		utm := campaigns.UTMSetting{
 			Source:  form.UtmSource,
 			Medium:  form.UtmMedium,
 			Term:    form.UtmTerm,
 			Content: form.UtmContent,
+			Mode:    form.UtmMode,
+		}
+		if utm.Mode == "" {
+			utm.Mode = links.UTMPreserve
 		}
+
 		_, err = repo.AddLinkToCampaignChannel(campaignID, channelID, form.LinkID, utm)
 		if err != nil {
 			logError(logger, err)
@@ -431,12 +454,14 @@ func AddLinkToCampaignChannel(repo *campaigns.Repository, logger *log.Logger) ht
 			return
 		}
 
+		invalidateCampaignLinks(repo, urlCache, campaignID, logger)
+
 		response.Ok(w)
 	})
 }
 
 // DeleteLinkFromCampaignChannel ...
-func DeleteLinkFromCampaignChannel(repo *campaigns.Repository, logger *log.Logger) http.Handler {
+func DeleteLinkFromCampaignChannel(repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		campaignIDArg := chi.URLParam(r, "id")
@@ -493,6 +518,8 @@ func DeleteLinkFromCampaignChannel(repo *campaigns.Repository, logger *log.Logge
 			return
 		}
 
+		keys := campaignLinkKeys(repo, campaignID, logger)
+
 		err = repo.DeleteLinkFromCampaignChannel(campaignID, channelID, linkID)
 		if err != nil {
 			logError(logger, err)
@@ -500,6 +527,8 @@ func DeleteLinkFromCampaignChannel(repo *campaigns.Repository, logger *log.Logge
 			return
 		}
 
+		invalidateLinks(urlCache, keys)
+
 		response.Ok(w)
 	})
 }
@@ -800,7 +829,7 @@ func AddChannelToCampaign(repo *campaigns.Repository, logger *log.Logger) http.H
 }
 
 // DeleteChannelFromCampaign ...
-func DeleteChannelFromCampaign(repo *campaigns.Repository, logger *log.Logger) http.HandlerFunc {
+func DeleteChannelFromCampaign(repo *campaigns.Repository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		campaignIDArg := chi.URLParam(r, "id")
@@ -839,6 +868,8 @@ func DeleteChannelFromCampaign(repo *campaigns.Repository, logger *log.Logger) h
 			return
 		}
 
+		keys := campaignLinkKeys(repo, campaignID, logger)
+
 		_, err = repo.DeleteChannelFromCampaign(campaignID, channelID)
 		if err != nil {
 			logError(logger, err)
@@ -846,7 +877,31 @@ func DeleteChannelFromCampaign(repo *campaigns.Repository, logger *log.Logger) h
 			return
 		}
 
+		invalidateLinks(urlCache, keys)
+
 		response.Ok(w)
 
 	})
 }
+
+// campaignLinkKeys reads url cache keys of campaign links, errors are logged only
+func campaignLinkKeys(repo *campaigns.Repository, campaignID int64, logger *log.Logger) []string {
+	keys, err := repo.GetCampaignLinkKeys(campaignID)
+	if err != nil {
+		logError(logger, err)
+	}
+	return keys
+}
+
+// invalidateLinks removes links from url cache, so they are reloaded on next redirect
+func invalidateLinks(urlCache cache.UrlCache, keys []string) {
+	for _, key := range keys {
+		urlCache.Delete(key)
+	}
+}
+
+// invalidateCampaignLinks removes campaign links from url cache,
+// so utm parameters are reloaded with the campaign state on next redirect
+func invalidateCampaignLinks(repo *campaigns.Repository, urlCache cache.UrlCache, campaignID int64, logger *log.Logger) {
+	invalidateLinks(urlCache, campaignLinkKeys(repo, campaignID, logger))
+}
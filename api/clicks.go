@@ -99,9 +99,12 @@ func GetDayClicksData(repo *clicks.Repository, logger *log.Logger) http.HandlerF
 
 // LinkStatResponse ...
 type LinkStatResponse struct {
-	Clicks    DataResponse `json:"clicks"`
+	Clicks DataResponse `json:"clicks"`
+	// Scans are daily clicks made by scanning qr codes, they are counted in Clicks too
+	Scans     DataResponse `json:"scans"`
 	Referrers DataResponse `json:"referrers"`
 	Locations DataResponse `json:"locations"`
+	Sources   DataResponse `json:"sources"`
 }
 
 // GetLinkStat ...
@@ -128,6 +131,9 @@ func GetLinkStat(repo *clicks.Repository, historyDB *data.HistoryDB, billingLimi
 			Clicks: DataResponse{
 				Datasets: []DataSetResponse{{Label: ""}},
 			},
+			Scans: DataResponse{
+				Datasets: []DataSetResponse{{Label: ""}},
+			},
 			Referrers: DataResponse{
 				Labels:   []string{},
 				Datasets: []DataSetResponse{{Label: "", Data: []interface{}{}}},
@@ -136,6 +142,10 @@ func GetLinkStat(repo *clicks.Repository, historyDB *data.HistoryDB, billingLimi
 				Labels:   []string{},
 				Datasets: []DataSetResponse{{Label: "", Data: []interface{}{}}},
 			},
+			Sources: DataResponse{
+				Labels:   []string{},
+				Datasets: []DataSetResponse{{Label: "", Data: []interface{}{}}},
+			},
 		}
 
 		clickData := make(map[int64]int64)
@@ -145,8 +155,16 @@ func GetLinkStat(repo *clicks.Repository, historyDB *data.HistoryDB, billingLimi
 			clickData[ts.Unix()] += r.Count
 		}
 
+		scanData := make(map[int64]int64)
+		for _, r := range data.Scans {
+			t := r.Time
+			ts := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			scanData[ts.Unix()] += r.Count
+		}
+
 		referers := make(map[string]int)
 		location := make(map[string]int)
+		sources := make(map[string]int)
 
 		for _, i := range data.Infos {
 			for k, v := range i.Info.Referrers {
@@ -155,6 +173,9 @@ func GetLinkStat(repo *clicks.Repository, historyDB *data.HistoryDB, billingLimi
 			for k, v := range i.Info.Locations {
 				location[k] += v
 			}
+			for k, v := range i.Info.Sources {
+				sources[k] += v
+			}
 		}
 
 		for k, v := range referers {
@@ -167,10 +188,17 @@ func GetLinkStat(repo *clicks.Repository, historyDB *data.HistoryDB, billingLimi
 			resp.Locations.Datasets[0].Data = append(resp.Locations.Datasets[0].Data, v)
 		}
 
+		for k, v := range sources {
+			resp.Sources.Labels = append(resp.Sources.Labels, k)
+			resp.Sources.Datasets[0].Data = append(resp.Sources.Datasets[0].Data, v)
+		}
+
 		for i := 0; i < int(defaultDayLimit); i++ {
 			ts := startTime.Add(time.Hour * 24 * time.Duration(i))
 			resp.Clicks.Datasets[0].Data = append(resp.Clicks.Datasets[0].Data, clickData[ts.Unix()])
 			resp.Clicks.Labels = append(resp.Clicks.Labels, ts.Format("01-02"))
+			resp.Scans.Datasets[0].Data = append(resp.Scans.Datasets[0].Data, scanData[ts.Unix()])
+			resp.Scans.Labels = append(resp.Scans.Labels, ts.Format("01-02"))
 		}
 
 		response.Object(w, resp, http.StatusOK)
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"shortly/api/response"
+
+	"shortly/app/links"
+)
+
+// CodeSettingsResponse ...
+type CodeSettingsResponse struct {
+	Strategy      string `json:"strategy"`
+	Alphabet      string `json:"alphabet"`
+	MinLength     int    `json:"minLength"`
+	CaseSensitive bool   `json:"caseSensitive"`
+}
+
+// CodeSettingsForm ...
+type CodeSettingsForm CodeSettingsResponse
+
+// GetCodeSettings ...
+func GetCodeSettings(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		settings, err := repo.GetCodeSettings(claims.AccountID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &CodeSettingsResponse{
+			Strategy:      settings.Strategy,
+			Alphabet:      settings.Alphabet,
+			MinLength:     settings.MinLength,
+			CaseSensitive: settings.CaseSensitive,
+		}, http.StatusOK)
+	})
+}
+
+// UpdateCodeSettings ...
+func UpdateCodeSettings(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		var form CodeSettingsForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		settings := links.CodeSettings{
+			AccountID:     claims.AccountID,
+			Strategy:      form.Strategy,
+			Alphabet:      form.Alphabet,
+			MinLength:     form.MinLength,
+			CaseSensitive: form.CaseSensitive,
+		}
+		if settings.Strategy == "" {
+			settings.Strategy = links.CodeStrategyRandom
+		}
+		if settings.Alphabet == "" {
+			settings.Alphabet = links.DefaultCodeAlphabet
+		}
+		if settings.MinLength == 0 {
+			settings.MinLength = links.DefaultCodeMinLength
+		}
+
+		if err := settings.Validate(); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := repo.UpdateCodeSettings(settings); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &CodeSettingsResponse{
+			Strategy:      settings.Strategy,
+			Alphabet:      settings.Alphabet,
+			MinLength:     settings.MinLength,
+			CaseSensitive: settings.CaseSensitive,
+		}, http.StatusOK)
+	})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+var deepLinkPageTemplate = template.Must(template.New("deeplink").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <meta name="robots" content="noindex, nofollow">
+    <title>Opening application</title>
+    <style>
+        body { font-family: sans-serif; background: #f4f6f9; display: flex; justify-content: center; padding-top: 10vh; }
+    </style>
+</head>
+<body>
+    <p>Opening application... <a href="{{.Fallback}}">Continue in browser</a></p>
+    <script>
+        var fallback = setTimeout(function () { window.location.replace({{.Fallback}}); }, 1500);
+        document.addEventListener("visibilitychange", function () {
+            if (document.hidden) { clearTimeout(fallback); }
+        });
+        window.location.href = {{.DeepLink}};
+    </script>
+</body>
+</html>
+`))
+
+// deepLinkPage ...
+type deepLinkPage struct {
+	DeepLink string
+	Fallback string
+}
+
+// renderDeepLinkPage writes an html page which opens an application deep link
+// and redirects to a web fallback url if the application is not installed
+func renderDeepLinkPage(w http.ResponseWriter, page deepLinkPage, logger *log.Logger) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := deepLinkPageTemplate.Execute(w, &page); err != nil {
+		logError(logger, err)
+	}
+}
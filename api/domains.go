@@ -0,0 +1,209 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+
+	"shortly/app/domains"
+	"shortly/app/rbac"
+)
+
+// DomainsRoutes ...
+func DomainsRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.HandlerFunc, repo *domains.Repository, logger *log.Logger) {
+
+	r.Get("/api/v1/domains", auth(
+		rbac.NewPermission("/api/v1/domains", "read_domains", "GET"),
+		GetDomains(repo, logger),
+	))
+
+	r.Post("/api/v1/domains/create", auth(
+		rbac.NewPermission("/api/v1/domains/create", "create_domain", "POST"),
+		CreateDomain(repo, logger),
+	))
+
+	r.Post("/api/v1/domains/{id}/verify", auth(
+		rbac.NewPermission("/api/v1/domains/{id}/verify", "verify_domain", "POST"),
+		VerifyDomain(repo, logger),
+	))
+
+	r.Delete("/api/v1/domains/{id}", auth(
+		rbac.NewPermission("/api/v1/domains/{id}", "delete_domain", "DELETE"),
+		DeleteDomain(repo, logger),
+	))
+}
+
+// DomainVerificationResponse describes a dns record required for domain verification
+type DomainVerificationResponse struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DomainResponse ...
+type DomainResponse struct {
+	ID           int64                      `json:"id"`
+	Host         string                     `json:"host"`
+	Verified     bool                       `json:"verified"`
+	VerifiedAt   *time.Time                 `json:"verifiedAt,omitempty"`
+	Verification DomainVerificationResponse `json:"verification"`
+}
+
+func newDomainResponse(d domains.Domain) DomainResponse {
+	return DomainResponse{
+		ID:         d.ID,
+		Host:       d.Host,
+		Verified:   d.Verified,
+		VerifiedAt: d.VerifiedAt,
+		Verification: DomainVerificationResponse{
+			Type:  "TXT",
+			Name:  d.VerificationRecord(),
+			Value: d.VerificationValue(),
+		},
+	}
+}
+
+// domainID reads domain id from url path
+func domainID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+
+	idArg := chi.URLParam(r, "id")
+	if idArg == "" {
+		response.Error(w, "id parameter is required", http.StatusBadRequest)
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(idArg, 0, 64)
+	if err != nil {
+		response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// GetDomains ...
+func GetDomains(repo *domains.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		rows, err := repo.GetDomains(claims.AccountID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]DomainResponse, 0)
+		for _, d := range rows {
+			list = append(list, newDomainResponse(d))
+		}
+
+		response.Object(w, list, http.StatusOK)
+	})
+}
+
+// CreateDomainForm ...
+type CreateDomainForm struct {
+	Host string `json:"host"`
+}
+
+// CreateDomain registers a domain, the response contains a TXT record required for verification
+func CreateDomain(repo *domains.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		var form CreateDomainForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		d, err := repo.CreateDomain(claims.AccountID, form.Host)
+		if err == domains.ErrInvalidHost {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err == domains.ErrDomainExists {
+			response.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, newDomainResponse(*d), http.StatusOK)
+	})
+}
+
+// VerifyDomain checks domain TXT record
+func VerifyDomain(repo *domains.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := domainID(w, r)
+		if !ok {
+			return
+		}
+
+		d, err := repo.VerifyDomain(r.Context(), claims.AccountID, id)
+		if err == sql.ErrNoRows {
+			response.Error(w, "domain not found", http.StatusNotFound)
+			return
+		} else if err == domains.ErrVerificationFailed {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err == domains.ErrDomainExists {
+			response.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, newDomainResponse(*d), http.StatusOK)
+	})
+}
+
+// DeleteDomain ...
+func DeleteDomain(repo *domains.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := domainID(w, r)
+		if !ok {
+			return
+		}
+
+		err := repo.DeleteDomain(claims.AccountID, id)
+		if err == sql.ErrNoRows {
+			response.Error(w, "domain not found", http.StatusNotFound)
+			return
+		} else if err == domains.ErrDomainInUse {
+			response.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"shortly/api/response"
+
+	"shortly/app/data"
+	"shortly/app/export"
+	"shortly/app/links"
+	"shortly/utils"
+)
+
+var (
+	exportLinksColumns = []string{"id", "short_url", "long_url", "description", "tags", "groups", "hidden", "created_at", "expires_at", "clicks"}
+	exportStatsColumns = []string{"link_id", "short_url", "date", "clicks", "scans"}
+)
+
+// exportWriter validates a requested format and starts an export file response,
+// rows are written to the response as soon as they are read
+func exportWriter(w http.ResponseWriter, r *http.Request, name string, columns []string) (export.Writer, bool) {
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	if err := export.ValidateFormat(format); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s", name, utils.Now().Format("20060102"), format)
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	w.WriteHeader(http.StatusOK)
+
+	writer, err := export.NewWriter(format, w, columns)
+	if err != nil {
+		return nil, false
+	}
+
+	return writer, true
+}
+
+// ExportLinks streams user links with tags, groups and click totals
+func ExportLinks(repo *links.LinksRepository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+		filters := userLinkFilters(r.URL.Query())
+
+		writer, ok := exportWriter(w, r, "links", exportLinksColumns)
+		if !ok {
+			return
+		}
+
+		// the status is already sent, so an error can only interrupt the file
+		err := repo.StreamUserLinks(claims.AccountID, claims.UserID, func(link links.Link) error {
+			clicks, err := historyDB.GetTotalClicks(link.Key())
+			if err != nil {
+				return err
+			}
+			return writer.WriteRow(link.ID, link.Short, link.Long, link.Description, link.Tags, link.Groups,
+				link.Hidden, link.CreatedAt, link.ExpiresAt, clicks)
+		}, filters...)
+		if err != nil {
+			logError(logger, err)
+			return
+		}
+
+		if err := writer.Close(); err != nil {
+			logError(logger, err)
+		}
+	})
+
+}
+
+// ExportLinksStats streams daily click counters of user links for a requested period
+func ExportLinksStats(repo *links.LinksRepository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+		query := r.URL.Query()
+
+		startTime, err := time.Parse(time.RFC3339, query.Get("start"))
+		if err != nil {
+			response.Error(w, "start parameter must be a valid RFC3339 datetime string", http.StatusBadRequest)
+			return
+		}
+		endTime, err := time.Parse(time.RFC3339, query.Get("end"))
+		if err != nil {
+			response.Error(w, "end parameter must be a valid RFC3339 datetime string", http.StatusBadRequest)
+			return
+		}
+		if endTime.Before(startTime) {
+			response.Error(w, "end parameter must be after start", http.StatusBadRequest)
+			return
+		}
+
+		// the period is checked before streaming since the plan limit can't be reported within a file
+		timedataLimit, err := historyDB.Limiter.GetOptionValue("timedata_limit", claims.AccountID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if int64(endTime.Sub(startTime)/(24*time.Hour)) > timedataLimit.AsInt64() {
+			response.Error(w, "requested period exceeds statistics limit of a billing plan", http.StatusBadRequest)
+			return
+		}
+
+		filters := userLinkFilters(query)
+
+		writer, ok := exportWriter(w, r, "links-stats", exportStatsColumns)
+		if !ok {
+			return
+		}
+
+		err = repo.StreamUserLinks(claims.AccountID, claims.UserID, func(link links.Link) error {
+
+			stat, err := historyDB.GetClicksData(claims.AccountID, link.Key(), startTime, endTime)
+			if err != nil {
+				return err
+			}
+
+			scans := make(map[time.Time]int64)
+			for _, day := range dailyClicks(stat.Scans) {
+				scans[day.Time] = day.Count
+			}
+
+			for _, day := range dailyClicks(stat.Clicks) {
+				if err := writer.WriteRow(link.ID, link.Short, day.Time.Format("2006-01-02"), day.Count, scans[day.Time]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, filters...)
+		if err != nil {
+			logError(logger, err)
+			return
+		}
+
+		if err := writer.Close(); err != nil {
+			logError(logger, err)
+		}
+	})
+
+}
+
+// dailyClicks sums ordered click counters by utc days
+func dailyClicks(counters []data.CounterData) []data.CounterData {
+
+	var days []data.CounterData
+	for _, c := range counters {
+		day := c.Time.UTC().Truncate(24 * time.Hour)
+		if n := len(days); n > 0 && days[n-1].Time.Equal(day) {
+			days[n-1].Count += c.Count
+			continue
+		}
+		days = append(days, data.CounterData{Time: day, Count: c.Count})
+	}
+
+	return days
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"shortly/api/response"
+	"shortly/app/data"
+	"shortly/app/links"
+)
+
+// linkHealthChecksLimit is a number of the latest destination probes returned by api
+const linkHealthChecksLimit = 50
+
+// LinkHealthResponse ...
+type LinkHealthResponse struct {
+	Broken           bool               `json:"broken"`
+	HealthStatusCode int                `json:"healthStatusCode,omitempty"`
+	HealthCheckedAt  *time.Time         `json:"healthCheckedAt,omitempty"`
+	Checks           []data.HealthCheck `json:"checks"`
+}
+
+// GetLinkHealth returns the latest destination probes of a link
+func GetLinkHealth(repo *links.LinksRepository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		checks, err := historyDB.GetHealthChecks(link.Key(), linkHealthChecksLimit)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &LinkHealthResponse{
+			Broken:           link.Broken,
+			HealthStatusCode: link.HealthStatusCode,
+			HealthCheckedAt:  link.HealthCheckedAt,
+			Checks:           checks,
+		}, http.StatusOK)
+
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"shortly/api/response"
+
+	"shortly/app/idempotency"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder keeps a copy of a response written to a client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware replays a stored response for requests retried with the same Idempotency-Key header
+// within a window, requests without the header are passed as is, server errors are not stored so they may be retried
+func IdempotencyMiddleware(repo *idempotency.Repository, window time.Duration, logger *log.Logger) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > idempotency.MaxKeyLength {
+				response.Error(w, "idempotency key is too long", http.StatusBadRequest)
+				return
+			}
+
+			claims := r.Context().Value("user").(*JWTClaims)
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				response.Error(w, "read body error", http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			hash.Write(body)
+			requestHash := hex.EncodeToString(hash.Sum(nil))
+
+			record, err := repo.Reserve(claims.AccountID, key, requestHash, window)
+			if err == idempotency.ErrKeyMismatch {
+				response.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			} else if err == idempotency.ErrInProgress {
+				response.Error(w, err.Error(), http.StatusConflict)
+				return
+			} else if err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			if record != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(record.StatusCode)
+				_, _ = w.Write(record.Response)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+				if err := repo.Release(claims.AccountID, key); err != nil {
+					logError(logger, err)
+				}
+				return
+			}
+
+			if err := repo.Complete(claims.AccountID, key, rec.status, rec.body.Bytes()); err != nil {
+				logError(logger, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"shortly/api/response"
+	"shortly/app/bulk"
+	"shortly/app/importer"
+)
+
+const (
+	// importMaxFileSize limits a size of uploaded import files
+	importMaxFileSize = 32 << 20
+	// importMaxRows limits a number of rows of a single import
+	importMaxRows = 50000
+)
+
+// ImportReportResponse is a dry-run result of an import
+type ImportReportResponse struct {
+	Total   int               `json:"total"`
+	Valid   int               `json:"valid"`
+	Invalid int               `json:"invalid"`
+	Rows    []bulk.ItemResult `json:"rows"`
+}
+
+// UploadLinksInBulk imports links from a csv or xlsx file with a header row naming columns url, slug,
+// description, tags, group and expires_at, files without a header are read as a list of urls.
+// With dryRun=true rows are only validated and a per row report is returned, destination redirects are not
+// followed by the dry run, otherwise an import job is started and its progress and report are available with bulk job api
+func UploadLinksInBulk(im *importer.Importer, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+		accountID := claims.AccountID
+
+		r.Body = http.MaxBytesReader(w, r.Body, importMaxFileSize)
+		if err := r.ParseMultipartForm(importMaxFileSize); err != nil {
+			response.Error(w, "parse form error", http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			response.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		content, err := ioutil.ReadAll(file)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		records, err := importer.ReadRecords(header.Filename, content)
+		if err != nil {
+			response.Error(w, "read file error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows, err := importer.ParseRows(records)
+		if err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(rows) > importMaxRows {
+			response.Error(w, fmt.Sprintf("import is limited to %d rows", importMaxRows), http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Query().Get("dryRun") == "true" {
+			if _, err := im.Validate(accountID, rows); err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			report := ImportReportResponse{Total: len(rows), Rows: importer.Report(rows)}
+			for _, row := range report.Rows {
+				if row.OK {
+					report.Valid++
+				} else {
+					report.Invalid++
+				}
+			}
+
+			response.Object(w, &report, http.StatusOK)
+			return
+		}
+
+		job, err := im.Start(accountID, claims.UserID, rows)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		jobResponse := bulkJobResponse(*job)
+		response.Object(w, &jobResponse, http.StatusAccepted)
+
+	})
+}
@@ -1,34 +1,33 @@
 package api
 
 import (
-	"bufio"
-	"encoding/csv"
+	"database/sql"
 	"encoding/json"
-	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
-	qrcode "github.com/skip2/go-qrcode"
 
 	"shortly/cache"
 	"shortly/utils"
 
 	"shortly/api/response"
 
+	"shortly/app/accounts"
 	"shortly/app/billing"
 	"shortly/app/data"
+	"shortly/app/domains"
 	"shortly/app/links"
 	"shortly/app/rbac"
+	"shortly/app/safety"
+	"shortly/app/urls"
 )
 
 // LinksRoutes ...
-func LinksRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.HandlerFunc, linksRepository links.ILinksRepository, logger *log.Logger, historyDB *data.HistoryDB) {
+func LinksRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.HandlerFunc, linksRepository *links.LinksRepository, logger *log.Logger, historyDB *data.HistoryDB, urlCache cache.UrlCache, checker *safety.Checker) {
 	r.Get("/api/v1/users/links", auth(
 		rbac.NewPermission("/api/v1/users/links", "read_links", "GET"),
 		GetUserURLList(linksRepository, logger),
@@ -54,6 +53,96 @@ func LinksRoutes(r chi.Router, auth func(rbac.Permission, http.Handler) http.Han
 		GetTotalLinks(linksRepository, logger),
 	))
 
+	r.Put("/api/v1/users/links/update", auth(
+		rbac.NewPermission("/api/v1/users/links/update", "update_link", "PUT"),
+		UpdateLink(linksRepository, historyDB, urlCache, checker, logger),
+	))
+
+	r.Get("/api/v1/users/links/reserved", auth(
+		rbac.NewPermission("/api/v1/users/links/reserved", "read_reserved_slugs", "GET"),
+		GetReservedSlugs(linksRepository, logger),
+	))
+
+	r.Post("/api/v1/users/links/reserved", auth(
+		rbac.NewPermission("/api/v1/users/links/reserved", "create_reserved_slug", "POST"),
+		AddReservedSlug(linksRepository, logger),
+	))
+
+	r.Delete("/api/v1/users/links/reserved/{id}", auth(
+		rbac.NewPermission("/api/v1/users/links/reserved/{id}", "delete_reserved_slug", "DELETE"),
+		DeleteReservedSlug(linksRepository, logger),
+	))
+
+	r.Get("/api/v1/users/links/codes", auth(
+		rbac.NewPermission("/api/v1/users/links/codes", "read_code_settings", "GET"),
+		GetCodeSettings(linksRepository, logger),
+	))
+
+	r.Put("/api/v1/users/links/codes", auth(
+		rbac.NewPermission("/api/v1/users/links/codes", "update_code_settings", "PUT"),
+		UpdateCodeSettings(linksRepository, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/rules", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/rules", "read_link_rules", "GET"),
+		GetLinkRules(linksRepository, logger),
+	))
+
+	r.Post("/api/v1/users/links/{id}/rules", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/rules", "create_link_rule", "POST"),
+		CreateLinkRule(linksRepository, urlCache, checker, logger),
+	))
+
+	r.Put("/api/v1/users/links/{id}/rules/{ruleID}", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/rules/{ruleID}", "update_link_rule", "PUT"),
+		UpdateLinkRule(linksRepository, urlCache, checker, logger),
+	))
+
+	r.Delete("/api/v1/users/links/{id}/rules/{ruleID}", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/rules/{ruleID}", "delete_link_rule", "DELETE"),
+		DeleteLinkRule(linksRepository, urlCache, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/versions", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/versions", "read_link_versions", "GET"),
+		GetLinkVersions(linksRepository, logger),
+	))
+
+	r.Post("/api/v1/users/links/{id}/versions/{version}/rollback", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/versions/{version}/rollback", "rollback_link", "POST"),
+		RollbackLink(linksRepository, urlCache, checker, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/health", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/health", "read_link_health", "GET"),
+		GetLinkHealth(linksRepository, historyDB, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/variants", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/variants", "read_link_variants", "GET"),
+		GetLinkVariants(linksRepository, logger),
+	))
+
+	r.Post("/api/v1/users/links/{id}/variants", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/variants", "create_link_variant", "POST"),
+		CreateLinkVariant(linksRepository, urlCache, checker, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/variants/stat", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/variants/stat", "read_link_variants_stat", "GET"),
+		GetLinkVariantsStat(linksRepository, historyDB, logger),
+	))
+
+	r.Put("/api/v1/users/links/{id}/variants/{variantID}", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/variants/{variantID}", "update_link_variant", "PUT"),
+		UpdateLinkVariant(linksRepository, urlCache, checker, logger),
+	))
+
+	r.Delete("/api/v1/users/links/{id}/variants/{variantID}", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/variants/{variantID}", "delete_link_variant", "DELETE"),
+		DeleteLinkVariant(linksRepository, urlCache, logger),
+	))
+
 }
 
 // GetAccountID extract account id from http context
@@ -64,16 +153,30 @@ func GetAccountID(r *http.Request) int64 {
 
 // Public API
 
-// TODO - auto expired links
-
 // LinkResponse ...
 type LinkResponse struct {
-	ID          int64    `json:"id,omitempty"`
-	Short       string   `json:"short"`
-	Long        string   `json:"long"`
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-	Active      bool     `json:"is_active"`
+	ID             int64      `json:"id,omitempty"`
+	Short          string     `json:"short"`
+	Long           string     `json:"long"`
+	Description    string     `json:"description"`
+	Tags           []string   `json:"tags"`
+	Active         bool       `json:"is_active"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks      int64      `json:"maxClicks,omitempty"`
+	FallbackURL    string     `json:"fallbackUrl,omitempty"`
+	Protected      bool       `json:"protected"`
+	Domain         string     `json:"domain,omitempty"`
+	RedirectType   int        `json:"redirectType"`
+	StickyVariants bool       `json:"stickyVariants"`
+	SafetyScore    int        `json:"safetyScore"`
+	SafetyStatus   string     `json:"safetyStatus,omitempty"`
+	SafetyReasons  []string   `json:"safetyReasons,omitempty"`
+	// Broken is set by destination health monitor
+	Broken           bool       `json:"broken"`
+	HealthStatusCode int        `json:"healthStatusCode,omitempty"`
+	HealthCheckedAt  *time.Time `json:"healthCheckedAt,omitempty"`
+	// Reused is set when an existing link is returned by create request
+	Reused bool `json:"reused,omitempty"`
 }
 
 // TODO refactor to top links
@@ -111,6 +214,40 @@ type LinkListResponse struct {
 	Total int64          `json:"total"`
 }
 
+// userLinkFilters reads link list filters from query parameters
+func userLinkFilters(query url.Values) []links.LinkFilter {
+
+	tagsFilter := query["tags"]
+	shortUrlFilter := query["shortUrl"]
+	longUrlFilter := query["longUrl"]
+	fullTextFilter := query["fullText"]
+	linkIDFilter := query["linkID"]
+
+	var filters []links.LinkFilter
+	if len(tagsFilter) > 0 {
+		filters = append(filters, links.LinkFilter{Tags: tagsFilter})
+	}
+
+	if len(shortUrlFilter) > 0 {
+		filters = append(filters, links.LinkFilter{ShortUrl: shortUrlFilter})
+	}
+
+	if len(longUrlFilter) > 0 {
+		filters = append(filters, links.LinkFilter{LongUrl: longUrlFilter})
+	}
+
+	if len(fullTextFilter) > 0 {
+		filters = append(filters, links.LinkFilter{FullText: fullTextFilter[0]})
+	}
+
+	if len(linkIDFilter) > 0 {
+		linkID, _ := strconv.ParseInt(linkIDFilter[0], 0, 64)
+		filters = append(filters, links.LinkFilter{LinkID: linkID})
+	}
+
+	return filters
+}
+
 // GetUserURLList ...
 func GetUserURLList(repo links.ILinksRepository, logger *log.Logger) http.HandlerFunc {
 
@@ -119,11 +256,6 @@ func GetUserURLList(repo links.ILinksRepository, logger *log.Logger) http.Handle
 		claims := r.Context().Value("user").(*JWTClaims)
 
 		query := r.URL.Query()
-		tagsFilter := query["tags"]
-		shortUrlFilter := query["shortUrl"]
-		longUrlFilter := query["longUrl"]
-		fullTextFilter := query["fullText"]
-		linkIDFilter := query["linkID"]
 
 		var limit, offset int64
 		limitArg := query["limit"]
@@ -135,27 +267,7 @@ func GetUserURLList(repo links.ILinksRepository, logger *log.Logger) http.Handle
 			offset, _ = strconv.ParseInt(offsetArg[0], 0, 64)
 		}
 
-		var filters []links.LinkFilter
-		if len(tagsFilter) > 0 {
-			filters = append(filters, links.LinkFilter{Tags: tagsFilter})
-		}
-
-		if len(shortUrlFilter) > 0 {
-			filters = append(filters, links.LinkFilter{ShortUrl: shortUrlFilter})
-		}
-
-		if len(longUrlFilter) > 0 {
-			filters = append(filters, links.LinkFilter{LongUrl: longUrlFilter})
-		}
-
-		if len(fullTextFilter) > 0 {
-			filters = append(filters, links.LinkFilter{FullText: fullTextFilter[0]})
-		}
-
-		if len(linkIDFilter) > 0 {
-			linkID, _ := strconv.ParseInt(linkIDFilter[0], 0, 64)
-			filters = append(filters, links.LinkFilter{LinkID: linkID})
-		}
+		filters := userLinkFilters(query)
 
 		result, err := repo.GetUserLinks(claims.AccountID, claims.UserID, limit, offset, filters...)
 		if err != nil {
@@ -167,12 +279,25 @@ func GetUserURLList(repo links.ILinksRepository, logger *log.Logger) http.Handle
 		var list []LinkResponse
 		for _, r := range result.Rows {
 			list = append(list, LinkResponse{
-				ID:          r.ID,
-				Short:       r.Short,
-				Long:        r.Long,
-				Description: r.Description,
-				Tags:        r.Tags,
-				Active:      !r.Hidden,
+				ID:               r.ID,
+				Short:            r.Short,
+				Long:             r.Long,
+				Description:      r.Description,
+				Tags:             r.Tags,
+				Active:           !r.Hidden,
+				ExpiresAt:        r.ExpiresAt,
+				MaxClicks:        r.MaxClicks,
+				FallbackURL:      r.FallbackURL,
+				Protected:        r.Protected(),
+				Domain:           r.Domain,
+				RedirectType:     r.RedirectType,
+				StickyVariants:   r.StickyVariants,
+				SafetyScore:      r.SafetyScore,
+				SafetyStatus:     r.SafetyStatus,
+				SafetyReasons:    r.SafetyReasons,
+				Broken:           r.Broken,
+				HealthStatusCode: r.HealthStatusCode,
+				HealthCheckedAt:  r.HealthCheckedAt,
 			})
 		}
 
@@ -184,10 +309,69 @@ func GetUserURLList(repo links.ILinksRepository, logger *log.Logger) http.Handle
 
 }
 
+// LinkLifetimeForm is a set of optional link expiration parameters
+type LinkLifetimeForm struct {
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	MaxClicks   int64      `json:"maxClicks"`
+	FallbackURL string     `json:"fallbackUrl"`
+}
+
+// validateLinkLifetime checks lifetime parameters and canonicalizes a fallback url
+func validateLinkLifetime(form *LinkLifetimeForm) (string, bool) {
+
+	if form.MaxClicks < 0 {
+		return "maxClicks parameter must be a positive number", false
+	}
+
+	if form.FallbackURL != "" {
+		fallbackURL, err := urls.Canonicalize(form.FallbackURL, urls.Web)
+		if err != nil {
+			return "fallback url: " + err.Error(), false
+		}
+		form.FallbackURL = fallbackURL
+	}
+
+	return "", true
+}
+
 // CreateLinkForm ...
 type CreateLinkForm struct {
+	LinkLifetimeForm
 	Url         string `json:"url"`
+	Short       string `json:"short"`
 	Description string `json:"description"`
+	Password    string `json:"password"`
+	DomainID    int64  `json:"domainId"`
+	// RedirectType is a redirect http status code, zero means account default
+	RedirectType   int  `json:"redirectType"`
+	StickyVariants bool `json:"stickyVariants"`
+	// ReuseExisting returns an active link of the account to the same destination instead of creating a new one
+	ReuseExisting bool `json:"reuseExisting"`
+}
+
+// shortLinkURL builds a full short url, links of the default domain use request host
+func shortLinkURL(r *http.Request, link links.Link) string {
+
+	urlScheme := "http"
+	if r.URL.Scheme != "" {
+		urlScheme = r.URL.Scheme
+	}
+
+	host := r.Host
+	if link.Domain != "" {
+		host = link.Domain
+	}
+
+	return urlScheme + "://" + host + "/" + link.Short
+}
+
+// slugError writes an error response for short url validation errors
+func slugError(w http.ResponseWriter, err error) {
+	if err == links.ErrSlugConflict {
+		response.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	response.Error(w, err.Error(), http.StatusBadRequest)
 }
 
 // CreateLink http handler creates a short link for a long url provided via POST form
@@ -198,7 +382,7 @@ type CreateLinkForm struct {
 // @Failure 400
 // @Failure 500
 // @Router /api/v1/links [post]
-func CreateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+func CreateLink(repo links.ILinksRepository, checker *safety.Checker, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -214,20 +398,21 @@ func CreateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *lo
 			return
 		}
 
-		longURL := form.Url
-
-		validLongURL, err := url.Parse(longURL)
+		longURL, err := urls.Canonicalize(form.Url, urls.Destination)
 		if err != nil {
-			response.Error(w, "url has incorrect format", http.StatusBadRequest)
+			response.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		link := &links.Link{
-			Short:       repo.GenerateLink(),
-			Long:        validLongURL.String(),
+			Long:        longURL,
 			Description: form.Description,
 		}
 
+		if !checkLinkSafety(w, r, checker, link) {
+			return
+		}
+
 		err = repo.CreateLink(link)
 		if err != nil {
 			logError(logger, err)
@@ -235,7 +420,7 @@ func CreateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *lo
 			return
 		}
 
-		urlCache.StoreExp(link.Short, validLongURL.String(), 86400*60)
+		urlCache.StoreExp(link.Key(), link.Cached().Encode(), 86400*60)
 
 		urlScheme := "http"
 		if r.URL.Scheme != "" {
@@ -245,9 +430,12 @@ func CreateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *lo
 		shortVersion := url.URL{Scheme: urlScheme, Host: r.Host, Path: link.Short}
 
 		linkResponse := &LinkResponse{
-			Short:       shortVersion.String(),
-			Long:        link.Long,
-			Description: link.Description,
+			Short:         shortVersion.String(),
+			Long:          link.Long,
+			Description:   link.Description,
+			SafetyScore:   link.SafetyScore,
+			SafetyStatus:  link.SafetyStatus,
+			SafetyReasons: link.SafetyReasons,
 		}
 		response.Object(w, linkResponse, http.StatusCreated)
 
@@ -255,15 +443,27 @@ func CreateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *lo
 
 }
 
-// UpdateLinkForm ...
+// UpdateLinkForm is a partial update of a link, fields omitted in a request keep stored values
 type UpdateLinkForm struct {
-	LinkID      int64  `json:"linkId"`
-	Url         string `json:"url"`
-	Description string `json:"description"`
+	LinkID      int64      `json:"linkId"`
+	Url         string     `json:"url"`
+	Short       string     `json:"short"`
+	Description *string    `json:"description"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	// RemoveExpiry clears a link expiration date
+	RemoveExpiry bool `json:"removeExpiry"`
+	// MaxClicks and FallbackURL are removed by zero values
+	MaxClicks      *int64  `json:"maxClicks"`
+	FallbackURL    *string `json:"fallbackUrl"`
+	Password       string  `json:"password"`
+	RemovePassword bool    `json:"removePassword"`
+	// RedirectType zero value resets a link to account default redirect type
+	RedirectType   *int  `json:"redirectType"`
+	StickyVariants *bool `json:"stickyVariants"`
 }
 
 // UpdateLink ...
-func UpdateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+func UpdateLink(repo *links.LinksRepository, historyDB *data.HistoryDB, urlCache cache.UrlCache, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -282,49 +482,141 @@ func UpdateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *lo
 			return
 		}
 
+		link, err := repo.GetLinkByID(form.LinkID)
+		if err == sql.ErrNoRows || (err == nil && link.AccountID != accountID) {
+			response.Error(w, "link not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "get link error", http.StatusInternalServerError)
+			return
+		}
+
 		longURL := form.Url
+		if longURL == "" {
+			longURL = link.Long
+		}
 
-		validLongURL, err := url.Parse(longURL)
+		longURL, err = urls.Canonicalize(longURL, urls.Destination)
 		if err != nil {
-			response.Error(w, "url has incorrect format", http.StatusBadRequest)
+			response.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		link, err := repo.GetLinkByID(form.LinkID)
-		if err != nil {
-			logError(logger, err)
-			response.Error(w, "get link error", http.StatusInternalServerError)
+		oldKey := link.Key()
+		if form.Short != "" && form.Short != link.Short {
+			if err := repo.CheckSlug(accountID, link.DomainID, form.Short); links.IsSlugError(err) {
+				slugError(w, err)
+				return
+			} else if err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			link.Short = form.Short
+		}
+
+		lifetime := LinkLifetimeForm{ExpiresAt: link.ExpiresAt, MaxClicks: link.MaxClicks, FallbackURL: link.FallbackURL}
+		if form.ExpiresAt != nil {
+			if !form.ExpiresAt.After(utils.Now()) {
+				response.Error(w, "expiresAt parameter must be a future date", http.StatusBadRequest)
+				return
+			}
+			lifetime.ExpiresAt = form.ExpiresAt
+		}
+		if form.RemoveExpiry {
+			lifetime.ExpiresAt = nil
+		}
+		if form.MaxClicks != nil {
+			lifetime.MaxClicks = *form.MaxClicks
+		}
+		if form.FallbackURL != nil {
+			lifetime.FallbackURL = *form.FallbackURL
+		}
+
+		if message, ok := validateLinkLifetime(&lifetime); !ok {
+			response.Error(w, message, http.StatusBadRequest)
 			return
 		}
 
+		if form.RedirectType != nil {
+			if err := links.ValidateRedirectType(*form.RedirectType); err != nil {
+				response.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			link.RedirectType = *form.RedirectType
+		}
+
+		if form.StickyVariants != nil {
+			link.StickyVariants = *form.StickyVariants
+		}
+
 		link.Long = longURL
-		link.Description = form.Description
+		if form.Description != nil {
+			link.Description = *form.Description
+		}
+		link.ExpiresAt = lifetime.ExpiresAt
+		link.MaxClicks = lifetime.MaxClicks
+		link.FallbackURL = lifetime.FallbackURL
+
+		if form.RemovePassword {
+			link.Password = ""
+		} else if form.Password != "" {
+			link.Password, err = links.HashPassword(form.Password)
+			if err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !checkLinkSafety(w, r, checker, &link) {
+			return
+		}
 
-		tx, err := repo.UpdateUserLink(accountID, form.LinkID, &link)
+		tx, err := repo.UpdateUserLink(accountID, claims.UserID, form.LinkID, &link)
 		if err != nil {
 			_ = tx.Rollback()
+			if err == links.ErrSlugConflict {
+				slugError(w, err)
+				return
+			}
 			logError(logger, err)
 			response.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
-		urlCache.Store(link.Short, validLongURL.String())
-
 		if err := tx.Commit(); err != nil {
 			logError(logger, err)
 			response.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
-		urlScheme := "http"
-		if r.URL.Scheme != "" {
-			urlScheme = r.URL.Scheme
+		// history and cache follow the committed short url only
+		if oldKey != link.Key() {
+			if err := historyDB.RenameLink(oldKey, link.Key()); err != nil {
+				logError(logger, err)
+			}
+			urlCache.Delete(oldKey)
 		}
 
+		links.StoreCache(urlCache, link)
+
 		response.Object(w, &LinkResponse{
-			Short:       urlScheme + "://" + r.Host + "/" + link.Short,
-			Long:        link.Long,
-			Description: link.Description,
+			ID:             link.ID,
+			Short:          shortLinkURL(r, link),
+			Long:           link.Long,
+			Description:    link.Description,
+			ExpiresAt:      link.ExpiresAt,
+			MaxClicks:      link.MaxClicks,
+			FallbackURL:    link.FallbackURL,
+			Protected:      link.Protected(),
+			Domain:         link.Domain,
+			RedirectType:   link.RedirectType,
+			StickyVariants: link.StickyVariants,
+			SafetyScore:    link.SafetyScore,
+			SafetyStatus:   link.SafetyStatus,
+			SafetyReasons:  link.SafetyReasons,
 		}, http.StatusOK)
 
 	})
@@ -332,7 +624,7 @@ func UpdateLink(repo links.ILinksRepository, urlCache cache.UrlCache, logger *lo
 }
 
 // CreateUserLink ...
-func CreateUserLink(repo *links.LinksRepository, historyDB *data.HistoryDB, urlCache cache.UrlCache, billingLimiter *billing.BillingLimiter, logger *log.Logger) http.HandlerFunc {
+func CreateUserLink(repo *links.LinksRepository, accountsRepo *accounts.UsersRepository, domainsRepo *domains.Repository, historyDB *data.HistoryDB, urlCache cache.UrlCache, billingLimiter *billing.BillingLimiter, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -356,23 +648,115 @@ func CreateUserLink(repo *links.LinksRepository, historyDB *data.HistoryDB, urlC
 			return
 		}
 
-		validLongURL, err := url.Parse(form.Url)
+		longURL, err := urls.Canonicalize(form.Url, urls.Destination)
+		if err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var domainHost string
+		if form.DomainID > 0 {
+			domain, err := domainsRepo.GetDomain(accountID, form.DomainID)
+			if err == sql.ErrNoRows {
+				response.Error(w, "domain not found", http.StatusBadRequest)
+				return
+			} else if err != nil {
+				logError(logger, err)
+				response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
+				return
+			}
+			if !domain.Verified {
+				response.Error(w, "domain is not verified", http.StatusBadRequest)
+				return
+			}
+			domainHost = domain.Host
+		}
+
+		// an empty short url is generated on insert
+		shortURL := form.Short
+		if shortURL != "" {
+			if err := repo.CheckSlug(accountID, form.DomainID, shortURL); links.IsSlugError(err) {
+				slugError(w, err)
+				return
+			} else if err != nil {
+				logError(logger, err)
+				response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if message, ok := validateLinkLifetime(&form.LinkLifetimeForm); !ok {
+			response.Error(w, message, http.StatusBadRequest)
+			return
+		}
+
+		if form.ExpiresAt != nil && !form.ExpiresAt.After(utils.Now()) {
+			response.Error(w, "expiresAt parameter must be a future date", http.StatusBadRequest)
+			return
+		}
+
+		if err := links.ValidateRedirectType(form.RedirectType); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		account, err := accountsRepo.GetAccount(accountID)
 		if err != nil {
-			response.Error(w, "long url has incorrect format", http.StatusBadRequest)
+			logError(logger, err)
+			response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
 			return
 		}
 
 		link := &links.Link{
-			Short:       utils.RandomString(5),
-			Long:        validLongURL.String(),
-			Description: form.Description,
+			Short:               shortURL,
+			Long:                longURL,
+			Description:         form.Description,
+			ExpiresAt:           form.ExpiresAt,
+			MaxClicks:           form.MaxClicks,
+			FallbackURL:         form.FallbackURL,
+			DomainID:            form.DomainID,
+			Domain:              domainHost,
+			RedirectType:        form.RedirectType,
+			AccountRedirectType: account.DefaultRedirectType,
+			StickyVariants:      form.StickyVariants,
+			CreatedAt:           utils.Now(),
+		}
+
+		if form.Password != "" {
+			link.Password, err = links.HashPassword(form.Password)
+			if err != nil {
+				logError(logger, err)
+				response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !checkLinkSafety(w, r, checker, link) {
+			return
 		}
 
 		l := billingLimiter.Lock(accountID)
 		defer l.Unlock()
 
+		// the lookup is made under the account lock so concurrent requests don't create duplicates,
+		// only links with the same options are reused
+		if form.ReuseExisting && form.Short == "" {
+			existing, err := repo.FindActiveLink(accountID, *link)
+			if err == nil {
+				response.Object(w, createdLinkResponse(r, existing.ID, existing, true), http.StatusOK)
+				return
+			} else if err != sql.ErrNoRows {
+				logError(logger, err)
+				response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
 		tx, linkID, err := repo.CreateUserLink(accountID, link)
-		if err != nil {
+		if err == links.ErrSlugConflict {
+			slugError(w, err)
+			return
+		} else if err != nil {
 			logError(logger, err)
 			response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
 			return
@@ -385,7 +769,7 @@ func CreateUserLink(repo *links.LinksRepository, historyDB *data.HistoryDB, urlC
 			return
 		}
 
-		if err := historyDB.InsertDetail(link.Short, accountID); err != nil {
+		if err := historyDB.InsertDetail(link.Key(), accountID); err != nil {
 			_ = tx.Rollback()
 			logError(logger, err)
 			response.Error(w, "(create link) - internal error", http.StatusInternalServerError)
@@ -399,25 +783,36 @@ func CreateUserLink(repo *links.LinksRepository, historyDB *data.HistoryDB, urlC
 			return
 		}
 
-		urlCache.Store(link.Short, link.Long)
-
-		urlScheme := "http"
-		if r.URL.Scheme != "" {
-			urlScheme = r.URL.Scheme
-		}
+		links.StoreCache(urlCache, *link)
 
-		response.Object(w, &LinkResponse{
-			ID:          linkID,
-			Short:       urlScheme + "://" + r.Host + "/" + link.Short,
-			Long:        link.Long,
-			Description: link.Description,
-		}, http.StatusOK)
+		response.Object(w, createdLinkResponse(r, linkID, *link, false), http.StatusOK)
 	})
 
 }
 
+func createdLinkResponse(r *http.Request, linkID int64, link links.Link, reused bool) *LinkResponse {
+	return &LinkResponse{
+		ID:             linkID,
+		Short:          shortLinkURL(r, link),
+		Long:           link.Long,
+		Description:    link.Description,
+		Active:         !link.Hidden,
+		ExpiresAt:      link.ExpiresAt,
+		MaxClicks:      link.MaxClicks,
+		FallbackURL:    link.FallbackURL,
+		Protected:      link.Protected(),
+		Domain:         link.Domain,
+		RedirectType:   link.RedirectType,
+		StickyVariants: link.StickyVariants,
+		SafetyScore:    link.SafetyScore,
+		SafetyStatus:   link.SafetyStatus,
+		SafetyReasons:  link.SafetyReasons,
+		Reused:         reused,
+	}
+}
+
 // DeleteUserLink ...
-func DeleteUserLink(repo *links.LinksRepository, urlCache cache.UrlCache, billingLimiter *billing.BillingLimiter, logger *log.Logger) http.HandlerFunc {
+func DeleteUserLink(repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -444,30 +839,27 @@ func DeleteUserLink(repo *links.LinksRepository, urlCache cache.UrlCache, billin
 			return
 		}
 
-		link := links.Rows[0]
+		if len(links.Rows) == 0 {
+			response.Error(w, "link not found", http.StatusNotFound)
+			return
+		}
 
-		lock := billingLimiter.Lock(accountID)
-		defer lock.Unlock()
+		link := links.Rows[0]
 
+		// billing counter is freed when the link is purged from trash
 		tx, _, err := repo.DeleteUserLink(accountID, linkID)
 		if err != nil {
-			_ = tx.Rollback()
-			logError(logger, err)
-			response.Error(w, "internal error", http.StatusInternalServerError)
-			return
-		}
-
-		if err := billingLimiter.Increase("url_limit", accountID); err != nil {
-			_ = tx.Rollback()
+			if tx != nil {
+				_ = tx.Rollback()
+			}
 			logError(logger, err)
 			response.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
-		urlCache.Delete(link.Short)
+		urlCache.Delete(link.Key())
 
 		if err := tx.Commit(); err != nil {
-			_ = billingLimiter.Reset("url_limit", accountID)
 			logError(logger, err)
 			response.Error(w, "internal error", http.StatusInternalServerError)
 			return
@@ -549,6 +941,8 @@ func DeleteUrlFromGroup(repo links.ILinksRepository, logger *log.Logger) http.Ha
 type ClickDataResponse struct {
 	Time  time.Time
 	Count int64
+	// Scans is a part of Count made by scanning qr codes
+	Scans int64
 }
 
 // GetClicksData ...
@@ -600,9 +994,14 @@ func GetClicksData(historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFu
 			return
 		}
 
+		scans := make(map[int64]int64)
+		for _, r := range data.Scans {
+			scans[r.Time.Unix()] += r.Count
+		}
+
 		var list []ClickDataResponse
 		for _, r := range data.Clicks {
-			list = append(list, ClickDataResponse{Time: r.Time, Count: r.Count})
+			list = append(list, ClickDataResponse{Time: r.Time, Count: r.Count, Scans: scans[r.Time.Unix()]})
 		}
 
 		response.Object(w, &list, http.StatusOK)
@@ -610,90 +1009,6 @@ func GetClicksData(historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFu
 
 }
 
-// UploadLinksInBulk ...
-func UploadLinksInBulk(limiter *billing.BillingLimiter, repo *links.LinksRepository, historyDB *data.HistoryDB, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		claims := r.Context().Value("user").(*JWTClaims)
-		accountID := claims.AccountID
-
-		if err := r.ParseMultipartForm(10 << 20); err != nil {
-			logError(logger, err)
-			fmt.Fprintf(w, "parse form error")
-			return
-		}
-
-		file, _, err := r.FormFile("file")
-		if err != nil {
-			logError(logger, err)
-			fmt.Fprintf(w, "internal server error")
-			return
-		}
-		defer file.Close()
-
-		reader := csv.NewReader(bufio.NewReader(file))
-
-		var links []string
-
-		for {
-			line, err := reader.Read()
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				logError(logger, err)
-				fmt.Fprintf(w, "error")
-				return
-			}
-			links = append(links, line[0])
-		}
-
-		option, err := limiter.GetOptionValue("url_limit", accountID)
-
-		if err != nil {
-			logError(logger, err)
-			fmt.Fprintf(w, "error")
-			return
-		}
-
-		maxLinks, _ := strconv.ParseInt(option.Value, 0, 64)
-
-		if len(links) >= int(maxLinks) {
-			fmt.Fprintf(w, "plan limit exceeded")
-			return
-		}
-
-		linksCreated, err := repo.BulkCreateLinks(accountID, links)
-		if err != nil {
-			logError(logger, err)
-			fmt.Fprintf(w, "error")
-			return
-		}
-
-		for _, l := range linksCreated {
-
-			// TODO - make one method for creating links
-
-			urlCache.Store(l.Short, l.Long)
-
-			if err := limiter.Reduce("url_limit", accountID); err != nil {
-				logError(logger, err)
-				fmt.Fprintf(w, "error")
-				return
-			}
-
-			if err := historyDB.InsertDetail(l.Short, accountID); err != nil {
-				logError(logger, err)
-				fmt.Fprintf(w, "error")
-				return
-			}
-
-		}
-
-		fmt.Fprintf(w, "ok")
-	})
-}
-
 // HideUserLink ...
 func HideUserLink(repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
 
@@ -728,7 +1043,7 @@ func HideUserLink(repo *links.LinksRepository, urlCache cache.UrlCache, logger *
 			return
 		}
 
-		urlCache.Delete(link.Short)
+		urlCache.Delete(link.Key())
 
 		if err := tx.Commit(); err != nil {
 			logError(logger, err)
@@ -775,7 +1090,7 @@ func ActivateUserLink(repo *links.LinksRepository, urlCache cache.UrlCache, logg
 			return
 		}
 
-		urlCache.Store(link.Short, link.Long)
+		links.StoreCache(urlCache, link)
 
 		if err := tx.Commit(); err != nil {
 			logError(logger, err)
@@ -807,34 +1122,110 @@ func GetTotalLinks(repo links.ILinksRepository, logger *log.Logger) http.Handler
 	})
 }
 
-// QrCodeHandler http handler generation qr code for a provided link
-func QrCodeHandler(repo links.ILinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+// ReservedSlugResponse ...
+type ReservedSlugResponse struct {
+	ID       int64  `json:"id"`
+	Slug     string `json:"slug"`
+	IsGlobal bool   `json:"isGlobal"`
+}
 
-	return func(w http.ResponseWriter, r *http.Request) {
+// GetReservedSlugs ...
+func GetReservedSlugs(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
 
-		shortURL := strings.TrimPrefix(r.URL.Path, "/qr/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		_, ok := urlCache.Load(shortURL)
-		if !ok {
-			response.Text(w, "url not found", http.StatusBadRequest)
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		rows, err := repo.GetReservedSlugs(claims.AccountID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
-		urlScheme := "http"
-		if r.URL.Scheme != "" {
-			urlScheme = r.URL.Scheme
+		list := make([]ReservedSlugResponse, 0)
+		for _, slug := range links.ReservedSlugs {
+			list = append(list, ReservedSlugResponse{Slug: slug, IsGlobal: true})
+		}
+		for _, r := range rows {
+			list = append(list, ReservedSlugResponse{
+				ID:       r.ID,
+				Slug:     r.Slug,
+				IsGlobal: r.AccountID == 0,
+			})
 		}
 
-		qrURL := strings.Replace(urlScheme+"://"+r.Host+r.URL.String(), "/qr", "", -1)
-		fmt.Println(qrURL)
-		png, err := qrcode.Encode(qrURL, qrcode.Medium, 256)
+		response.Object(w, list, http.StatusOK)
+	})
+}
+
+// AddReservedSlugForm ...
+type AddReservedSlugForm struct {
+	Slug string `json:"slug"`
+}
+
+// AddReservedSlug ...
+func AddReservedSlug(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		var form AddReservedSlugForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			logError(logger, err)
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		if err := links.ValidateSlug(form.Slug); err != nil {
+			slugError(w, err)
+			return
+		}
+
+		rowID, err := repo.AddReservedSlug(claims.AccountID, form.Slug)
+		if err == links.ErrSlugReserved {
+			slugError(w, err)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &ReservedSlugResponse{
+			ID:   rowID,
+			Slug: form.Slug,
+		}, http.StatusOK)
+	})
+}
+
+// DeleteReservedSlug ...
+func DeleteReservedSlug(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		idArg := chi.URLParam(r, "id")
+		if idArg == "" {
+			response.Error(w, "id parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.ParseInt(idArg, 0, 64)
 		if err != nil {
-			response.Text(w, "qr code generate error", http.StatusBadRequest)
+			response.Error(w, "id parameter is not a number", http.StatusBadRequest)
 			return
 		}
 
-		w.Header().Set("Content-Type", "image/png")
-		w.Header().Set("Content-Length", fmt.Sprintf("%v", len(png)))
-		_, _ = w.Write(png)
-	}
+		if err := repo.DeleteReservedSlug(claims.AccountID, id); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
 }
@@ -0,0 +1,490 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+
+	"shortly/app/data"
+	"shortly/app/links"
+	"shortly/app/pages"
+)
+
+// PageItemResponse ...
+type PageItemResponse struct {
+	ID       int64  `json:"id"`
+	LinkID   int64  `json:"linkId"`
+	Position int    `json:"position"`
+	Title    string `json:"title"`
+	Icon     string `json:"icon,omitempty"`
+}
+
+// PageResponse ...
+type PageResponse struct {
+	ID          int64              `json:"id"`
+	Handle      string             `json:"handle"`
+	URL         string             `json:"url"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	AvatarURL   string             `json:"avatarUrl,omitempty"`
+	Theme       string             `json:"theme"`
+	Published   bool               `json:"published"`
+	Items       []PageItemResponse `json:"items,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+func pageResponse(r *http.Request, p pages.Page) PageResponse {
+
+	urlScheme := "http"
+	if r.URL.Scheme != "" {
+		urlScheme = r.URL.Scheme
+	}
+
+	result := PageResponse{
+		ID:          p.ID,
+		Handle:      p.Handle,
+		URL:         urlScheme + "://" + r.Host + "/p/" + p.Handle,
+		Title:       p.Title,
+		Description: p.Description,
+		AvatarURL:   p.AvatarURL,
+		Theme:       p.Theme,
+		Published:   p.Published,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+	for _, item := range p.Items {
+		result.Items = append(result.Items, PageItemResponse{
+			ID:       item.ID,
+			LinkID:   item.LinkID,
+			Position: item.Position,
+			Title:    item.Title,
+			Icon:     item.Icon,
+		})
+	}
+	return result
+}
+
+// PageForm ...
+type PageForm struct {
+	Handle      string `json:"handle"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	AvatarURL   string `json:"avatarUrl"`
+	Theme       string `json:"theme"`
+	Published   bool   `json:"published"`
+}
+
+// PageItemForm ...
+type PageItemForm struct {
+	LinkID int64  `json:"linkId"`
+	Title  string `json:"title"`
+	Icon   string `json:"icon"`
+}
+
+// pageError writes an error response for page validation and storage errors
+func pageError(w http.ResponseWriter, err error, logger *log.Logger) {
+	switch err {
+	case sql.ErrNoRows:
+		response.Error(w, "page not found", http.StatusNotFound)
+	case pages.ErrHandleTaken:
+		response.Error(w, err.Error(), http.StatusConflict)
+	default:
+		logError(logger, err)
+		response.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func pageID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+	if err != nil {
+		response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func decodePageForm(w http.ResponseWriter, r *http.Request) (*pages.Page, bool) {
+
+	var form PageForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		response.Error(w, "decode form error", http.StatusBadRequest)
+		return nil, false
+	}
+
+	page := &pages.Page{
+		Handle:      form.Handle,
+		Title:       form.Title,
+		Description: form.Description,
+		AvatarURL:   form.AvatarURL,
+		Theme:       form.Theme,
+		Published:   form.Published,
+	}
+	if err := page.Validate(); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return page, true
+}
+
+// GetPages returns link-in-bio pages of an account without items
+func GetPages(pagesRepo *pages.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		list, err := pagesRepo.GetPages(claims.AccountID)
+		if err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		result := make([]PageResponse, 0, len(list))
+		for _, p := range list {
+			result = append(result, pageResponse(r, p))
+		}
+
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// GetPage returns a page with its items
+func GetPage(pagesRepo *pages.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pageID(w, r)
+		if !ok {
+			return
+		}
+
+		page, err := pagesRepo.GetPage(claims.AccountID, id)
+		if err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		result := pageResponse(r, *page)
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// CreatePage creates an empty page, links are added with SetPageItems
+func CreatePage(pagesRepo *pages.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		page, ok := decodePageForm(w, r)
+		if !ok {
+			return
+		}
+		page.AccountID = claims.AccountID
+
+		if err := pagesRepo.CreatePage(page); err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		result := pageResponse(r, *page)
+		response.Object(w, &result, http.StatusCreated)
+	})
+}
+
+// UpdatePage updates page fields, items are kept
+func UpdatePage(pagesRepo *pages.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pageID(w, r)
+		if !ok {
+			return
+		}
+
+		page, ok := decodePageForm(w, r)
+		if !ok {
+			return
+		}
+		page.ID = id
+		page.AccountID = claims.AccountID
+
+		if err := pagesRepo.UpdatePage(page); err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		result := pageResponse(r, *page)
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// DeletePage removes a page with its items and view history
+func DeletePage(pagesRepo *pages.Repository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pageID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := pagesRepo.DeletePage(claims.AccountID, id); err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		if err := historyDB.DeletePageViews(id); err != nil {
+			logError(logger, err)
+		}
+
+		response.Ok(w)
+	})
+}
+
+// SetPageItems replaces ordered links of a page, links must belong to the page account
+func SetPageItems(pagesRepo *pages.Repository, repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pageID(w, r)
+		if !ok {
+			return
+		}
+
+		page, err := pagesRepo.GetPage(claims.AccountID, id)
+		if err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		var form []PageItemForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		items := make([]pages.Item, 0, len(form))
+		linkIDs := make([]int64, 0, len(form))
+		for _, f := range form {
+			items = append(items, pages.Item{LinkID: f.LinkID, Title: f.Title, Icon: f.Icon})
+			linkIDs = append(linkIDs, f.LinkID)
+		}
+
+		if err := pages.ValidateItems(items); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		accountLinks, err := repo.GetAccountLinksByIDs(claims.AccountID, linkIDs)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if len(accountLinks) != len(linkIDs) {
+			response.Error(w, "link not found", http.StatusBadRequest)
+			return
+		}
+
+		if err := pagesRepo.SetItems(page.ID, items); err != nil {
+			pageError(w, err, logger)
+			return
+		}
+		page.Items = items
+
+		result := pageResponse(r, *page)
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// GetPageViews returns daily views of a page between start and end dates
+func GetPageViews(pagesRepo *pages.Repository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pageID(w, r)
+		if !ok {
+			return
+		}
+
+		query := r.URL.Query()
+		startTime, err := time.Parse(time.RFC3339, query.Get("start"))
+		if err != nil {
+			response.Error(w, "start parameter must be a valid RFC3339 datetime string", http.StatusBadRequest)
+			return
+		}
+		endTime, err := time.Parse(time.RFC3339, query.Get("end"))
+		if err != nil {
+			response.Error(w, "end parameter must be a valid RFC3339 datetime string", http.StatusBadRequest)
+			return
+		}
+		if endTime.Before(startTime) {
+			response.Error(w, "end parameter must be after start", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := pagesRepo.GetPage(claims.AccountID, id); err != nil {
+			pageError(w, err, logger)
+			return
+		}
+
+		views, err := historyDB.GetPageViews(id, startTime, endTime)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &views, http.StatusOK)
+	})
+}
+
+var bioPageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <meta name="referrer" content="no-referrer-when-downgrade">
+    <title>{{.Title}}</title>
+    <style>
+        body { font-family: sans-serif; margin: 0; padding: 32px 16px; background: {{.Theme.Background}}; color: {{.Theme.Text}}; }
+        main { max-width: 560px; margin: 0 auto; text-align: center; }
+        .avatar { width: 96px; height: 96px; border-radius: 50%; object-fit: cover; }
+        a.item { display: flex; align-items: center; gap: 12px; margin: 12px 0; padding: 14px 16px; border-radius: 8px;
+            background: {{.Theme.Button}}; color: {{.Theme.ButtonText}}; text-decoration: none; box-shadow: 0 1px 3px rgba(0,0,0,.2); }
+        a.item img { width: 24px; height: 24px; }
+        a.item span { flex: 1; }
+    </style>
+</head>
+<body>
+    <main>
+        {{if .AvatarURL}}<img class="avatar" src="{{.AvatarURL}}" alt="">{{end}}
+        <h2>{{.Title}}</h2>
+        {{if .Description}}<p>{{.Description}}</p>{{end}}
+        {{range .Items}}
+        <a class="item" href="{{.URL}}" rel="noopener">{{if .Icon}}<img src="{{.Icon}}" alt="">{{end}}<span>{{.Title}}</span></a>
+        {{end}}
+    </main>
+</body>
+</html>
+`))
+
+// bioPage ...
+type bioPage struct {
+	Title       string
+	Description string
+	AvatarURL   string
+	Theme       pages.Theme
+	Items       []bioPageItem
+}
+
+type bioPageItem struct {
+	URL   string
+	Title string
+	Icon  string
+}
+
+// ShowPage renders a published link-in-bio page, items point to short urls so clicks pass the normal redirect
+// with the page url as a referrer, page views are counted separately from link clicks
+func ShowPage(pagesRepo *pages.Repository, repo *links.LinksRepository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		page, err := pagesRepo.GetPublishedPage(chi.URLParam(r, "handle"))
+		if err == sql.ErrNoRows {
+			response.Text(w, "page not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		linkIDs := make([]int64, 0, len(page.Items))
+		for _, item := range page.Items {
+			linkIDs = append(linkIDs, item.LinkID)
+		}
+
+		accountLinks, err := repo.GetAccountLinksByIDs(page.AccountID, linkIDs)
+		if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		linksByID := make(map[int64]links.Link, len(accountLinks))
+		for _, link := range accountLinks {
+			linksByID[link.ID] = link
+		}
+
+		view := bioPage{
+			Title:       page.Title,
+			Description: page.Description,
+			AvatarURL:   page.AvatarURL,
+			Theme:       pages.Themes[page.Theme],
+		}
+		if view.Title == "" {
+			view.Title = "@" + page.Handle
+		}
+
+		for _, item := range page.Items {
+			link, ok := linksByID[item.LinkID]
+			if !ok || link.Hidden || link.SafetyStatus == links.SafetyBlocked {
+				continue
+			}
+
+			// expired links are left out instead of leading visitors to a not found page
+			cached := link.Cached()
+			if expired, err := isLinkExpired(historyDB, &cached, link.Key()); err != nil {
+				logError(logger, err)
+				continue
+			} else if expired {
+				continue
+			}
+
+			title := item.Title
+			if title == "" {
+				title = link.Description
+			}
+			if title == "" {
+				title = link.Short
+			}
+
+			view.Items = append(view.Items, bioPageItem{URL: shortLinkURL(r, link), Title: title, Icon: item.Icon})
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Referrer-Policy", "no-referrer-when-downgrade")
+		w.WriteHeader(http.StatusOK)
+		if err := bioPageTemplate.Execute(w, &view); err != nil {
+			logError(logger, err)
+			return
+		}
+
+		if err := historyDB.InsertPageView(page.ID); err != nil {
+			logError(logger, err)
+		}
+	})
+}
@@ -0,0 +1,503 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/cache"
+
+	"shortly/app/links"
+	"shortly/app/pixels"
+)
+
+// PixelResponse ...
+type PixelResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	URL       string    `json:"url"`
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func pixelResponse(p pixels.Pixel) PixelResponse {
+	return PixelResponse{
+		ID:        p.ID,
+		Name:      p.Name,
+		Kind:      p.Kind,
+		URL:       p.URL,
+		Host:      p.Host,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+// PixelForm ...
+type PixelForm struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+// PixelHostResponse ...
+type PixelHostResponse struct {
+	ID        int64     `json:"id"`
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PixelHostForm ...
+type PixelHostForm struct {
+	Host string `json:"host"`
+}
+
+// LinkPixelsForm ...
+type LinkPixelsForm struct {
+	PixelIDs []int64 `json:"pixelIds"`
+	// Delay of the interstitial page in milliseconds, zero means the default delay
+	Delay int `json:"delay"`
+}
+
+// LinkPixelsResponse ...
+type LinkPixelsResponse struct {
+	PixelIDs []int64 `json:"pixelIds"`
+	Delay    int     `json:"delay"`
+}
+
+// pixelError writes an error response for pixel validation and storage errors
+func pixelError(w http.ResponseWriter, err error, notFound string, logger *log.Logger) {
+	switch err {
+	case sql.ErrNoRows:
+		response.Error(w, notFound, http.StatusNotFound)
+	case pixels.ErrHostExists, pixels.ErrHostInUse:
+		response.Error(w, err.Error(), http.StatusConflict)
+	default:
+		logError(logger, err)
+		response.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func pixelID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+	if err != nil {
+		response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodePixelForm reads a pixel and checks its url against the account host allowlist
+func decodePixelForm(w http.ResponseWriter, r *http.Request, pixelsRepo *pixels.Repository, logger *log.Logger) (*pixels.Pixel, bool) {
+
+	claims := r.Context().Value("user").(*JWTClaims)
+
+	var form PixelForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		response.Error(w, "decode form error", http.StatusBadRequest)
+		return nil, false
+	}
+
+	hosts, err := pixelsRepo.GetHosts(claims.AccountID)
+	if err != nil {
+		logError(logger, err)
+		response.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	pixel := &pixels.Pixel{
+		AccountID: claims.AccountID,
+		Name:      form.Name,
+		Kind:      form.Kind,
+		URL:       form.URL,
+	}
+	if err := pixel.Validate(hosts); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return pixel, true
+}
+
+// refreshPixelLinks stores links of a pixel with actual pixels into url cache
+func refreshPixelLinks(repo *links.LinksRepository, urlCache cache.UrlCache, linkIDs []int64, logger *log.Logger) {
+	for _, linkID := range linkIDs {
+		if err := refreshLinkCache(repo, urlCache, linkID); err != nil && err != sql.ErrNoRows {
+			logError(logger, err)
+		}
+	}
+}
+
+// GetPixels returns retargeting pixels of an account
+func GetPixels(pixelsRepo *pixels.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		list, err := pixelsRepo.GetPixels(claims.AccountID)
+		if err != nil {
+			pixelError(w, err, "pixel not found", logger)
+			return
+		}
+
+		result := make([]PixelResponse, 0, len(list))
+		for _, p := range list {
+			result = append(result, pixelResponse(p))
+		}
+
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// CreatePixel creates a pixel, its url host must be in the account allowlist
+func CreatePixel(pixelsRepo *pixels.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		pixel, ok := decodePixelForm(w, r, pixelsRepo, logger)
+		if !ok {
+			return
+		}
+
+		if err := pixelsRepo.CreatePixel(pixel); err != nil {
+			pixelError(w, err, "pixel not found", logger)
+			return
+		}
+
+		result := pixelResponse(*pixel)
+		response.Object(w, &result, http.StatusCreated)
+	})
+}
+
+// UpdatePixel updates a pixel and links it is attached to
+func UpdatePixel(pixelsRepo *pixels.Repository, repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id, ok := pixelID(w, r)
+		if !ok {
+			return
+		}
+
+		pixel, ok := decodePixelForm(w, r, pixelsRepo, logger)
+		if !ok {
+			return
+		}
+		pixel.ID = id
+
+		if err := pixelsRepo.UpdatePixel(pixel); err != nil {
+			pixelError(w, err, "pixel not found", logger)
+			return
+		}
+
+		linkIDs, err := pixelsRepo.GetPixelLinkIDs(pixel.ID)
+		if err != nil {
+			logError(logger, err)
+		}
+		refreshPixelLinks(repo, urlCache, linkIDs, logger)
+
+		result := pixelResponse(*pixel)
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// DeletePixel removes a pixel from all links
+func DeletePixel(pixelsRepo *pixels.Repository, repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pixelID(w, r)
+		if !ok {
+			return
+		}
+
+		linkIDs, err := pixelsRepo.GetPixelLinkIDs(id)
+		if err != nil {
+			pixelError(w, err, "pixel not found", logger)
+			return
+		}
+
+		if err := pixelsRepo.DeletePixel(claims.AccountID, id); err != nil {
+			pixelError(w, err, "pixel not found", logger)
+			return
+		}
+
+		refreshPixelLinks(repo, urlCache, linkIDs, logger)
+
+		response.Ok(w)
+	})
+}
+
+// GetPixelHosts returns the pixel host allowlist of an account
+func GetPixelHosts(pixelsRepo *pixels.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		list, err := pixelsRepo.GetHosts(claims.AccountID)
+		if err != nil {
+			pixelError(w, err, "host not found", logger)
+			return
+		}
+
+		result := make([]PixelHostResponse, 0, len(list))
+		for _, h := range list {
+			result = append(result, PixelHostResponse{ID: h.ID, Host: h.Host, CreatedAt: h.CreatedAt})
+		}
+
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// CreatePixelHost adds a host to the account allowlist
+func CreatePixelHost(pixelsRepo *pixels.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		var form PixelHostForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		host, err := pixels.NormalizeHost(form.Host)
+		if err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h := pixels.Host{AccountID: claims.AccountID, Host: host}
+		if err := pixelsRepo.CreateHost(&h); err != nil {
+			pixelError(w, err, "host not found", logger)
+			return
+		}
+
+		response.Object(w, &PixelHostResponse{ID: h.ID, Host: h.Host, CreatedAt: h.CreatedAt}, http.StatusCreated)
+	})
+}
+
+// DeletePixelHost removes a host from the account allowlist, hosts of existing pixels are kept
+func DeletePixelHost(pixelsRepo *pixels.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, ok := pixelID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := pixelsRepo.DeleteHost(claims.AccountID, id); err != nil {
+			pixelError(w, err, "host not found", logger)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
+
+// GetLinkPixels returns pixels attached to a link
+func GetLinkPixels(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		result := LinkPixelsResponse{PixelIDs: make([]int64, 0, len(link.Pixels)), Delay: link.PixelDelay}
+		for _, p := range link.Pixels {
+			result.PixelIDs = append(result.PixelIDs, p.ID)
+		}
+
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// SetLinkPixels replaces pixels of a link, pixels must belong to the link account
+func SetLinkPixels(repo *links.LinksRepository, pixelsRepo *pixels.Repository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		var form LinkPixelsForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		if form.Delay < 0 || time.Duration(form.Delay)*time.Millisecond > pixels.MaxDelay {
+			response.Error(w, pixels.ErrInvalidDelay.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pixelIDs := make([]int64, 0, len(form.PixelIDs))
+		seen := make(map[int64]bool)
+		for _, id := range form.PixelIDs {
+			if !seen[id] {
+				seen[id] = true
+				pixelIDs = append(pixelIDs, id)
+			}
+		}
+		if len(pixelIDs) > pixels.MaxLinkPixels {
+			response.Error(w, pixels.ErrTooManyPixels.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(pixelIDs) > 0 {
+			accountPixels, err := pixelsRepo.GetPixelsByIDs(link.AccountID, pixelIDs)
+			if err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if len(accountPixels) != len(pixelIDs) {
+				response.Error(w, pixels.ErrPixelNotFound.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := repo.SetLinkPixels(link.ID, pixelIDs, form.Delay); err != nil {
+			pixelError(w, err, "link not found", logger)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+		}
+
+		response.Object(w, &LinkPixelsResponse{PixelIDs: pixelIDs, Delay: form.Delay}, http.StatusOK)
+	})
+}
+
+var pixelPageTemplate = template.Must(template.New("pixels").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="robots" content="noindex, nofollow">
+    <meta http-equiv="refresh" content="{{.Refresh}}; url={{.Destination}}">
+    <title>Redirecting</title>
+    {{range .Scripts}}<script async src="{{.}}" nonce="{{$.Nonce}}"></script>
+    {{end}}
+</head>
+<body>
+    {{range .Images}}<img src="{{.}}" width="1" height="1" alt="" hidden>
+    {{end}}
+    <p>Redirecting... <a href="{{.Destination}}">Continue</a></p>
+    <script nonce="{{.Nonce}}">
+        setTimeout(function () { window.location.replace({{.Destination}}); }, {{.Delay}});
+    </script>
+</body>
+</html>
+`))
+
+// pixelPage ...
+type pixelPage struct {
+	// Destination is a parsed link destination without unsafe schemes, it is passed as a trusted url
+	// since html/template replaces tel:, sms: and application urls of links
+	Destination template.URL
+	Scripts     []string
+	Images      []string
+	Nonce       string
+	// Delay is a redirect delay in milliseconds, Refresh is a meta refresh fallback in seconds
+	Delay   int64
+	Refresh int64
+}
+
+// newPixelPage expands pixel urls of a link for a redirect to a destination
+func newPixelPage(link *links.CachedLink, destination string, delay time.Duration, vars pixels.Vars) pixelPage {
+
+	if link.PixelDelay > 0 {
+		delay = time.Duration(link.PixelDelay) * time.Millisecond
+	}
+	if delay > pixels.MaxDelay {
+		delay = pixels.MaxDelay
+	}
+
+	vars.Destination = destination
+	vars.CacheBuster = strconv.FormatInt(mathrand.Int63(), 10)
+
+	page := pixelPage{
+		Destination: template.URL(destination),
+		Delay:       delay.Milliseconds(),
+		Refresh:     int64((delay + time.Second - 1) / time.Second),
+	}
+	for _, p := range link.Pixels {
+		pixelURL := pixels.Expand(p.URL, vars)
+		if p.Kind == pixels.KindScript {
+			page.Scripts = append(page.Scripts, pixelURL)
+		} else {
+			page.Images = append(page.Images, pixelURL)
+		}
+	}
+	return page
+}
+
+// pixelPolicy is a content security policy of the pixel page, pixels may load resources from their hosts only
+func pixelPolicy(page pixelPage) string {
+
+	var sources []string
+	seen := make(map[string]bool)
+	for _, pixelURL := range append(append([]string{}, page.Scripts...), page.Images...) {
+		u, err := url.Parse(pixelURL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		source := "https://" + u.Host
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+
+	hosts := "'none'"
+	if len(sources) > 0 {
+		hosts = strings.Join(sources, " ")
+	}
+
+	return "default-src 'none'; script-src " + strings.Join(append([]string{"'nonce-" + page.Nonce + "'"}, sources...), " ") +
+		"; img-src " + hosts + "; connect-src " + hosts + "; frame-src " + hosts + "; base-uri 'none'; form-action 'none'"
+}
+
+// renderPixelPage writes an interstitial page which fires link pixels and redirects to a destination
+func renderPixelPage(w http.ResponseWriter, page pixelPage, logger *log.Logger) {
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		logError(logger, err)
+	}
+	page.Nonce = base64.RawURLEncoding.EncodeToString(nonce)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", pixelPolicy(page))
+	w.WriteHeader(http.StatusOK)
+	if err := pixelPageTemplate.Execute(w, &page); err != nil {
+		logError(logger, err)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shortly/app/links"
+	"shortly/app/pixels"
+)
+
+func TestRenderPixelPage(t *testing.T) {
+
+	link := &links.CachedLink{
+		Long: "https://example.com/",
+		Pixels: []links.Pixel{
+			{ID: 1, Kind: pixels.KindScript, URL: "https://tags.example.net/t.js?id=1"},
+			{ID: 2, Kind: pixels.KindImage, URL: "https://px.example.com/p?u={short_url}&d={destination}"},
+		},
+	}
+
+	page := newPixelPage(link, "https://example.com/?a=1&b=</script>", 1500*time.Millisecond,
+		pixels.Vars{ShortURL: "https://sho.rt/abc"})
+	if page.Delay != 1500 || page.Refresh != 2 {
+		t.Errorf("unexpected delay %v, refresh %v", page.Delay, page.Refresh)
+	}
+
+	w := httptest.NewRecorder()
+	renderPixelPage(w, page, log.New(&strings.Builder{}, "", 0))
+
+	policy := w.Header().Get("Content-Security-Policy")
+	for _, directive := range []string{"default-src 'none'", "img-src https://tags.example.net https://px.example.com",
+		"script-src 'nonce-"} {
+		if !strings.Contains(policy, directive) {
+			t.Errorf("policy %q must contain %q", policy, directive)
+		}
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("pixel page must not be cached")
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `src="https://tags.example.net/t.js?id=1"`) {
+		t.Errorf("script pixel is missing: %s", body)
+	}
+	if !strings.Contains(body, "u=https%3a%2f%2fsho.rt%2fabc") && !strings.Contains(body, "u=https%3A%2F%2Fsho.rt%2Fabc") {
+		t.Errorf("image pixel is not expanded: %s", body)
+	}
+	if strings.Contains(body, "b=</script>") {
+		t.Errorf("destination must be escaped: %s", body)
+	}
+
+	// contact and application destinations keep a working continue link
+	w = httptest.NewRecorder()
+	renderPixelPage(w, newPixelPage(link, "tel:+15551234567", time.Second, pixels.Vars{}), log.New(&strings.Builder{}, "", 0))
+	if body := w.Body.String(); !strings.Contains(body, `href="tel:&#43;15551234567"`) && !strings.Contains(body, `href="tel:+15551234567"`) {
+		t.Errorf("continue link must keep a tel destination: %s", body)
+	}
+
+	link.PixelDelay = 60000
+	if page := newPixelPage(link, link.Long, time.Second, pixels.Vars{}); page.Delay != pixels.MaxDelay.Milliseconds() {
+		t.Errorf("delay must be limited, got %v", page.Delay)
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/app/data"
+	"shortly/app/domains"
+	"shortly/app/links"
+	"shortly/cache"
+	"shortly/utils"
+)
+
+// previewSuffix appended to a short url opens the link preview instead of redirect
+const previewSuffix = "+"
+
+var previewPageTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <meta name="robots" content="noindex, nofollow">
+    <title>Link preview</title>
+    <style>
+        body { font-family: sans-serif; background: #f4f6f9; display: flex; justify-content: center; padding-top: 10vh; }
+        .preview { background: #fff; padding: 24px; border-radius: 4px; box-shadow: 0 1px 3px rgba(0,0,0,.2); max-width: 480px; word-break: break-all; }
+        .muted { color: #6c757d; font-size: 14px; }
+        .warning { color: #b94a48; }
+    </style>
+</head>
+<body>
+    <div class="preview">
+        <h3>{{.ShortURL}}</h3>
+        {{if .Disabled}}
+        <p class="warning">This link is disabled, its destination looks unsafe</p>
+        {{else if .Protected}}
+        <p class="muted">Destination of this link is password protected</p>
+        {{else}}
+        <p>Leads to <a href="{{.Destination}}" rel="nofollow noopener">{{.Destination}}</a></p>
+        {{end}}
+        {{if .Targeted}}<p class="muted">Destination may differ depending on your location or device</p>{{end}}
+        {{if .Description}}<p>{{.Description}}</p>{{end}}
+        {{if .CreatedAt}}<p class="muted">Created {{.CreatedAt}}</p>{{end}}
+        {{if .Expired}}<p class="warning">This link has expired</p>{{end}}
+        <img src="{{.QrURL}}" width="128" height="128" alt="QR code">
+        {{if not (or .Expired .Disabled)}}<p><a href="{{.ShortURL}}">Continue</a></p>{{end}}
+    </div>
+</body>
+</html>
+`))
+
+// previewPage ...
+type previewPage struct {
+	ShortURL    string
+	QrURL       string
+	Destination string
+	Description string
+	CreatedAt   string
+	Protected   bool
+	Targeted    bool
+	Expired     bool
+	Disabled    bool
+}
+
+// renderPreviewPage writes an html page describing a link destination
+func renderPreviewPage(w http.ResponseWriter, page previewPage, logger *log.Logger) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := previewPageTemplate.Execute(w, &page); err != nil {
+		logError(logger, err)
+	}
+}
+
+// isLinkExpired checks link lifetime, click counts are read from history only for links with clicks limit
+func isLinkExpired(historyDB *data.HistoryDB, link *links.CachedLink, linkKey string) (bool, error) {
+	var totalClicks int64
+	if link.MaxClicks > 0 {
+		var err error
+		totalClicks, err = historyDB.GetTotalClicks(linkKey)
+		if err != nil {
+			return false, err
+		}
+	}
+	return link.IsExpired(utils.Now(), totalClicks), nil
+}
+
+// Preview ...
+// @Summary Show link destination and details without redirect, clicks are not counted
+// @Tags Links
+// @ID preview-short-link
+// @Produce html
+// @Success 200
+// @Failure 404
+// @Failure 500
+// @Router /{code}+ [get]
+func Preview(repo links.ILinksRepository, domainsRepo *domains.Repository, historyDB *data.HistoryDB, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		shortURL := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), previewSuffix)
+
+		domainID, linkKey, err := resolveLinkKey(domainsRepo, r.Host, shortURL)
+		if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		link := loadLink(repo, urlCache, domainID, linkKey, shortURL, logger)
+
+		if link == nil || link.Long == "" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+			return
+		}
+
+		expired, err := isLinkExpired(historyDB, link, linkKey)
+		if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		scheme := "http"
+		if r.URL.Scheme != "" {
+			scheme = r.URL.Scheme
+		}
+
+		page := previewPage{
+			ShortURL:    scheme + "://" + r.Host + "/" + shortURL,
+			QrURL:       "/qr/" + shortURL,
+			Description: link.Description,
+			Protected:   link.Protected,
+			Targeted:    len(link.Rules) > 0 || len(link.Variants) > 0,
+			Expired:     expired,
+			Disabled:    !link.Safe(),
+		}
+
+		if !link.Protected && link.Safe() {
+			destination, err := parseDestinationURL(link.Long)
+			if err == nil {
+				page.Destination = destination.String()
+			}
+		}
+
+		if link.CreatedAt != nil {
+			page.CreatedAt = link.CreatedAt.Format("January 2, 2006")
+		}
+
+		renderPreviewPage(w, page, logger)
+
+	})
+}
+
+// ExpandResponse ...
+type ExpandResponse struct {
+	Short        string     `json:"short"`
+	Long         string     `json:"long,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	RedirectType int        `json:"redirectType"`
+	Protected    bool       `json:"protected"`
+	Targeted     bool       `json:"targeted"`
+	Expired      bool       `json:"expired"`
+	SafetyStatus string     `json:"safetyStatus,omitempty"`
+}
+
+// ExpandLink ...
+// @Summary Get destination and details of a short link without redirect, clicks are not counted
+// @Tags Links
+// @ID expand-short-link
+// @Produce json
+// @Param code path string true "short url"
+// @Param domain query string false "custom domain of a link"
+// @Success 200 {object} ExpandResponse
+// @Failure 404 {object} response.ApiResponse
+// @Failure 500 {object} response.ApiResponse
+// @Router /api/v1/expand/{code} [get]
+func ExpandLink(repo links.ILinksRepository, domainsRepo *domains.Repository, historyDB *data.HistoryDB, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		shortURL := chi.URLParam(r, "code")
+
+		var domainID int64
+		var domainHost string
+		if host := r.URL.Query().Get("domain"); host != "" {
+			var ok bool
+			var err error
+			domainID, ok, err = domainsRepo.LookupHost(host)
+			if err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				response.Error(w, "link not found", http.StatusNotFound)
+				return
+			}
+			domainHost = domains.NormalizeHost(host)
+		}
+		linkKey := links.LinkKey(domainHost, shortURL)
+
+		link := loadLink(repo, urlCache, domainID, linkKey, shortURL, logger)
+		if link == nil || link.Long == "" {
+			response.Error(w, "link not found", http.StatusNotFound)
+			return
+		}
+
+		expired, err := isLinkExpired(historyDB, link, linkKey)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		expanded := ExpandResponse{
+			Short:        linkKey,
+			Description:  link.Description,
+			CreatedAt:    link.CreatedAt,
+			ExpiresAt:    link.ExpiresAt,
+			RedirectType: link.StatusCode(),
+			Protected:    link.Protected,
+			Targeted:     len(link.Rules) > 0 || len(link.Variants) > 0,
+			Expired:      expired,
+			SafetyStatus: link.SafetyStatus,
+		}
+		if !link.Protected && link.Safe() {
+			expanded.Long = link.Long
+		}
+
+		response.Object(w, &expanded, http.StatusOK)
+
+	})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+var unlockPageTemplate = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <meta name="robots" content="noindex, nofollow">
+    <title>Protected link</title>
+    <style>
+        body { font-family: sans-serif; background: #f4f6f9; display: flex; justify-content: center; padding-top: 10vh; }
+        form { background: #fff; padding: 24px; border-radius: 4px; box-shadow: 0 1px 3px rgba(0,0,0,.2); width: 320px; }
+        input { width: 100%; box-sizing: border-box; padding: 8px; margin: 12px 0; }
+        button { width: 100%; padding: 8px; }
+        .error { color: #dc3545; }
+    </style>
+</head>
+<body>
+    <form method="POST" action="{{.Action}}">
+        <h3>This link is password protected</h3>
+        {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+        <input type="password" name="password" placeholder="Password" autofocus required>
+        <button type="submit">Unlock</button>
+    </form>
+</body>
+</html>
+`))
+
+// unlockPage ...
+type unlockPage struct {
+	Action string
+	Error  string
+}
+
+// renderUnlockPage writes an html form asking for a password of a protected link
+func renderUnlockPage(w http.ResponseWriter, page unlockPage, statusCode int, logger *log.Logger) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := unlockPageTemplate.Execute(w, &page); err != nil {
+		logError(logger, err)
+	}
+}
@@ -0,0 +1,462 @@
+package api
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shortly/api/response"
+	"shortly/cache"
+
+	"shortly/app/data"
+	"shortly/app/domains"
+	"shortly/app/links"
+	"shortly/app/qr"
+)
+
+// qrExportMaxLinks limits a number of qr codes in a single zip archive
+const qrExportMaxLinks = 1000
+
+var errTooManyLinks = errors.New("too many links")
+
+// QrCodeHandler http handler generation qr code for a provided link,
+// links of custom domains are resolved by request host.
+// The endpoint is public, so only a saved link style or the default style is rendered,
+// styles are overridden by owners through LinkQRCode
+func QrCodeHandler(domainsRepo *domains.Repository, qrRepo *qr.Repository, renderer *qr.Renderer, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		shortURL := strings.TrimPrefix(r.URL.Path, "/qr/")
+
+		domainID, ok, err := domainsRepo.LookupHost(r.Host)
+		if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		var domainHost string
+		if ok {
+			domainHost = domains.NormalizeHost(r.Host)
+		}
+
+		if _, ok := urlCache.Load(links.LinkKey(domainHost, shortURL)); !ok {
+			response.Text(w, "url not found", http.StatusBadRequest)
+			return
+		}
+
+		accountID, saved, err := qrRepo.FindLinkStyle(domainID, shortURL)
+		if err == sql.ErrNoRows {
+			response.Text(w, "url not found", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		style := qr.DefaultStyle()
+		if saved != nil {
+			style = *saved
+		}
+
+		urlScheme := "http"
+		if r.URL.Scheme != "" {
+			urlScheme = r.URL.Scheme
+		}
+
+		// scans are told apart from direct clicks by a source marker
+		img, err := renderer.Render(accountID, sourceURL(urlScheme+"://"+r.Host+"/"+shortURL, data.SourceQR), style)
+		if err == qr.ErrNoLogo {
+			response.Text(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Text(w, "qr code generate error", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", style.ContentType())
+		w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(img)
+	}
+}
+
+// LinkQRCode renders a qr code of an account link for its owners,
+// query parameters size, level, fg, bg, margin, format and logo override a saved link style
+func LinkQRCode(repo *links.LinksRepository, qrRepo *qr.Repository, renderer *qr.Renderer, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		style, err := qrRepo.GetLinkStyle(claims.AccountID, link.ID)
+		if err == sql.ErrNoRows {
+			defaultStyle := qr.DefaultStyle()
+			style = &defaultStyle
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := style.Override(r.URL.Query()); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		img, err := renderer.Render(claims.AccountID, sourceURL(shortLinkURL(r, *link), data.SourceQR), *style)
+		if err == qr.ErrNoLogo {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "qr code generate error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", style.ContentType())
+		w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		_, _ = w.Write(img)
+	})
+}
+
+// QRStyleResponse ...
+type QRStyleResponse struct {
+	Size       int        `json:"size"`
+	Level      string     `json:"level"`
+	Foreground string     `json:"foreground"`
+	Background string     `json:"background"`
+	QuietZone  int        `json:"quietZone"`
+	Format     string     `json:"format"`
+	Logo       bool       `json:"logo"`
+	Saved      bool       `json:"saved"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+func qrStyleResponse(s qr.Style, saved bool) QRStyleResponse {
+	result := QRStyleResponse{
+		Size:       s.Size,
+		Level:      s.Level,
+		Foreground: s.Foreground,
+		Background: s.Background,
+		QuietZone:  *s.QuietZone,
+		Format:     s.Format,
+		Logo:       s.Logo,
+		Saved:      saved,
+	}
+	if saved {
+		result.UpdatedAt = &s.UpdatedAt
+	}
+	return result
+}
+
+// QRStyleForm ...
+type QRStyleForm struct {
+	Size       int    `json:"size"`
+	Level      string `json:"level"`
+	Foreground string `json:"foreground"`
+	Background string `json:"background"`
+	QuietZone  *int   `json:"quietZone"`
+	Format     string `json:"format"`
+	Logo       bool   `json:"logo"`
+}
+
+// GetLinkQRStyle returns a saved qr style of a link or the default style
+func GetLinkQRStyle(repo *links.LinksRepository, qrRepo *qr.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		style, err := qrRepo.GetLinkStyle(claims.AccountID, link.ID)
+		if err == sql.ErrNoRows {
+			result := qrStyleResponse(qr.DefaultStyle(), false)
+			response.Object(w, &result, http.StatusOK)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result := qrStyleResponse(*style, true)
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// SaveLinkQRStyle saves a qr style used for qr codes of a link
+func SaveLinkQRStyle(repo *links.LinksRepository, qrRepo *qr.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		var form QRStyleForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		style := qr.Style{
+			Size:       form.Size,
+			Level:      form.Level,
+			Foreground: form.Foreground,
+			Background: form.Background,
+			QuietZone:  form.QuietZone,
+			Format:     form.Format,
+			Logo:       form.Logo,
+		}
+		if err := style.Validate(); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if style.Logo {
+			if _, err := qrRepo.GetLogoVersion(claims.AccountID); err == sql.ErrNoRows {
+				response.Error(w, qr.ErrNoLogo.Error(), http.StatusBadRequest)
+				return
+			} else if err != nil {
+				logError(logger, err)
+				response.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := qrRepo.SaveLinkStyle(claims.AccountID, link.ID, &style); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result := qrStyleResponse(style, true)
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// DeleteLinkQRStyle removes a saved qr style of a link, the default style is used afterwards
+func DeleteLinkQRStyle(repo *links.LinksRepository, qrRepo *qr.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		if err := qrRepo.DeleteLinkStyle(claims.AccountID, link.ID); err == sql.ErrNoRows {
+			response.Error(w, "qr style not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
+
+// UploadQRLogo replaces the account logo placed in the center of qr codes, png, jpeg and gif images are accepted
+func UploadQRLogo(qrRepo *qr.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		r.Body = http.MaxBytesReader(w, r.Body, qr.MaxLogoBytes+1<<10)
+		if err := r.ParseMultipartForm(qr.MaxLogoBytes); err != nil {
+			response.Error(w, "parse form error", http.StatusBadRequest)
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			response.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		content, err := ioutil.ReadAll(file)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		logo, err := qr.NormalizeLogo(content)
+		if err == qr.ErrInvalidLogo || err == qr.ErrLogoTooLarge {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := qrRepo.SaveLogo(claims.AccountID, logo); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
+
+// GetQRLogo returns the account logo as a png image
+func GetQRLogo(qrRepo *qr.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		logo, err := qrRepo.GetLogo(claims.AccountID)
+		if err == sql.ErrNoRows {
+			response.Error(w, qr.ErrNoLogo.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Length", strconv.Itoa(len(logo)))
+		_, _ = w.Write(logo)
+	})
+}
+
+// DeleteQRLogo removes the account logo, styles with a logo fail to render until a new logo is uploaded
+func DeleteQRLogo(qrRepo *qr.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		if err := qrRepo.DeleteLogo(claims.AccountID); err == sql.ErrNoRows {
+			response.Error(w, qr.ErrNoLogo.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
+
+// ExportQRCodes returns a zip archive of qr codes of links matched by link list filters.
+// Each code uses a saved style of its link, query parameters size, level, fg, bg, margin, format and logo
+// override styles of all codes
+func ExportQRCodes(repo *links.LinksRepository, qrRepo *qr.Repository, renderer *qr.Renderer, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		query := r.URL.Query()
+
+		// styles are checked before the archive is started since errors can't be reported within it
+		override := qr.DefaultStyle()
+		if err := override.Override(query); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var list []links.Link
+		err := repo.StreamUserLinks(claims.AccountID, claims.UserID, func(link links.Link) error {
+			list = append(list, link)
+			if len(list) > qrExportMaxLinks {
+				return errTooManyLinks
+			}
+			return nil
+		}, userLinkFilters(query)...)
+		if err == errTooManyLinks {
+			response.Error(w, "too many links, narrow filters to at most "+strconv.Itoa(qrExportMaxLinks)+" links", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		linkIDs := make([]int64, 0, len(list))
+		for _, link := range list {
+			linkIDs = append(linkIDs, link.ID)
+		}
+		styles, err := qrRepo.GetLinkStyles(claims.AccountID, linkIDs)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="qr-codes.zip"`)
+		w.WriteHeader(http.StatusOK)
+
+		archive := zip.NewWriter(w)
+		for _, link := range list {
+
+			style := qr.DefaultStyle()
+			if saved, ok := styles[link.ID]; ok {
+				style = saved
+			}
+			if err := style.Override(query); err != nil {
+				logger.Printf("qr export link(%v) style error: %v\n", link.ID, err)
+				continue
+			}
+
+			img, err := renderer.Render(claims.AccountID, sourceURL(shortLinkURL(r, link), data.SourceQR), style)
+			if err != nil {
+				logger.Printf("qr export link(%v) render error: %v\n", link.ID, err)
+				continue
+			}
+
+			name := link.Short + "." + style.Format
+			if link.Domain != "" {
+				name = link.Domain + "/" + name
+			}
+			file, err := archive.Create(name)
+			if err != nil {
+				logError(logger, err)
+				return
+			}
+			if _, err := file.Write(img); err != nil {
+				logError(logger, err)
+				return
+			}
+		}
+
+		if err := archive.Close(); err != nil {
+			logError(logger, err)
+		}
+	})
+}
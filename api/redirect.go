@@ -7,45 +7,92 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/oschwald/geoip2-golang"
+	"golang.org/x/time/rate"
 
 	"shortly/app/data"
+	"shortly/app/domains"
 	"shortly/cache"
 	"shortly/utils"
 
 	"shortly/api/response"
 
 	"shortly/app/links"
+	"shortly/app/pixels"
+	"shortly/app/urls"
 )
 
 type LinkRedirect struct {
-	ShortUrl string
-	LongUrl  string
-	Headers  http.Header
-	IPAddr   string
-	Country  string
-	Referer  string
+	ShortUrl  string
+	LongUrl   string
+	Headers   http.Header
+	IPAddr    string
+	Country   string
+	Referer   string
+	RuleID    int64
+	VariantID int64
+	// Source is data.SourceQR for qr code scans, empty for direct clicks
+	Source string
+}
+
+// sourceParam marks short urls encoded in qr codes, e.g. /abc?src=qr, the marker is removed before a redirect
+const sourceParam = "src"
+
+// clickSource reads a source marker of a short url and removes it from the request url
+func clickSource(r *http.Request) string {
+	query := r.URL.Query()
+	if query.Get(sourceParam) != data.SourceQR {
+		return ""
+	}
+	query.Del(sourceParam)
+	r.URL.RawQuery = query.Encode()
+	return data.SourceQR
+}
+
+// sourceURL adds a source marker to a short url
+func sourceURL(shortURL, source string) string {
+	if source == "" {
+		return shortURL
+	}
+	return shortURL + "?" + sourceParam + "=" + url.QueryEscape(source)
 }
 
 // Redirect ...
 // @Summary Redirect from short link to associated long url
 // @Tags Links
 // @ID redirect-short-link
+// @Success 301
+// @Success 302
+// @Success 303
 // @Success 307
 // @Success 308
 // @Failure 400
 // @Failure 500
 // @Router / [get]
-func Redirect(repo links.ILinksRepository, redirectLogger utils.DbLogger, historyDB *data.HistoryDB, urlCache cache.UrlCache, logger *log.Logger, geoipDbPath string) http.HandlerFunc {
+func Redirect(repo links.ILinksRepository, domainsRepo *domains.Repository, redirectLogger utils.DbLogger, historyDB *data.HistoryDB, urlCache cache.UrlCache, logger *log.Logger, geoipDbPath string, pixelDelay time.Duration) http.HandlerFunc {
 
 	var geoipDB *geoip2.Reader
 
+	// failed password attempts for protected links
+	passwordLimiter := utils.NewKeyRateLimiter(rate.Every(time.Minute), 5)
+
+	preview := Preview(repo, domainsRepo, historyDB, urlCache, logger)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, previewSuffix) {
+			preview(w, r)
+			return
+		}
+
+		source := clickSource(r)
+
 		ipAddr := utils.GetIPAdress(r)
-		var country string
+		var country, countryCode string
 		var err error
 
 		if geoipDB == nil {
@@ -61,6 +108,7 @@ func Redirect(repo links.ILinksRepository, redirectLogger utils.DbLogger, histor
 			countryObject, err := geoipDB.Country(validIP)
 			if err == nil {
 				country = countryObject.Country.Names["en"]
+				countryCode = countryObject.Country.IsoCode
 			} else {
 				logger.Printf("geoip parse error: %v", country)
 			}
@@ -85,40 +133,121 @@ func Redirect(repo links.ILinksRepository, redirectLogger utils.DbLogger, histor
 			return
 		}
 
-		cacheURLValue, ok := urlCache.Load(shortURL)
+		domainID, linkKey, err := resolveLinkKey(domainsRepo, r.Host, shortURL)
+		if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		link := loadLink(repo, urlCache, domainID, linkKey, shortURL, logger)
+
+		if link == nil || link.Long == "" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+			return
+		}
+
+		if !link.Safe() {
+			renderUnsafePage(w, link.SafetyStatus, logger)
+			return
+		}
 
-		var longURL string
+		expired, err := isLinkExpired(historyDB, link, linkKey)
+		if err != nil {
+			logError(logger, err)
+			response.Text(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
 
-		if ok {
-			longURL, ok = cacheURLValue.(string)
-			if !ok {
-				response.Text(w, "url is not a string", http.StatusBadRequest)
+		if expired {
+			if link.FallbackURL == "" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte("not found"))
 				return
 			}
-		} else {
-			longURL, err = repo.UnshortenURL(shortURL)
-			if err == nil {
-				logger.Printf("cache miss, short=%v, long=%v\n", shortURL, longURL)
-				urlCache.Store(shortURL, longURL)
+			fallbackURL, err := parseDestinationURL(link.FallbackURL)
+			if err != nil {
+				response.Text(w, "url has incorrect format", http.StatusBadRequest)
+				return
 			}
+			http.Redirect(w, r, fallbackURL.String(), redirectStatus(r, link))
+			return
 		}
 
-		if longURL == "" {
-			w.WriteHeader(http.StatusNotFound)
-			_, _ = w.Write([]byte("not found"))
-			return
+		if link.Protected {
+
+			// the source marker is kept by the unlock form
+			action := sourceURL(r.URL.Path, source)
+
+			if r.Method != http.MethodPost {
+				renderUnlockPage(w, unlockPage{Action: action}, http.StatusOK, logger)
+				return
+			}
+
+			limiterKey := ipAddr
+			if limiterKey == "" {
+				limiterKey, _, _ = net.SplitHostPort(r.RemoteAddr)
+			}
+
+			if passwordLimiter.Exceeded(limiterKey) {
+				renderUnlockPage(w, unlockPage{
+					Action: action,
+					Error:  "Too many attempts, try again later",
+				}, http.StatusTooManyRequests, logger)
+				return
+			}
+
+			valid, err := repo.VerifyLinkPassword(domainID, shortURL, r.PostFormValue("password"))
+			if err != nil {
+				logError(logger, err)
+				response.Text(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			if !valid {
+				passwordLimiter.Hit(limiterKey)
+				renderUnlockPage(w, unlockPage{
+					Action: action,
+					Error:  "Incorrect password",
+				}, http.StatusUnauthorized, logger)
+				return
+			}
 		}
 
-		if !(strings.HasPrefix(longURL, "http") || strings.HasPrefix(longURL, "https")) {
-			longURL = "https://" + longURL
+		destination, rule := link.Destination(links.Visitor{
+			Country:   countryCode,
+			UserAgent: utils.ParseUserAgent(r.UserAgent()),
+		})
+
+		var variant *links.Variant
+		if rule == nil && len(link.Variants) > 0 {
+			variant = pickVariant(w, r, link)
+			if variant != nil {
+				destination = variant.Destination
+			}
+		}
+
+		// deep links are opened by a page which falls back to a web url
+		var deepLink string
+		if rule != nil && links.IsDeepLink(destination) {
+			deepLink = destination
+			destination = link.Long
+			if rule.Fallback != "" {
+				destination = rule.Fallback
+			}
 		}
 
-		validURL, err := url.Parse(longURL)
+		validURL, err := parseDestinationURL(destination)
 		if err != nil {
 			response.Text(w, "url has incorrect format", http.StatusBadRequest)
 			return
 		}
 
+		// links of an active campaign channel carry campaign utm parameters
+		if link.UTM != nil && (validURL.Scheme == "http" || validURL.Scheme == "https") {
+			link.UTM.Apply(validURL)
+		}
+
 		referrers := r.Header[http.CanonicalHeaderKey("Referer")]
 
 		var referer string
@@ -129,21 +258,42 @@ func Redirect(repo links.ILinksRepository, redirectLogger utils.DbLogger, histor
 		requestData := data.LinkRequestData{
 			Location: country,
 			Referrer: referer,
+			Source:   source,
+		}
+
+		var ruleID int64
+		if rule != nil {
+			ruleID = rule.ID
+			requestData.Rule = strconv.FormatInt(rule.ID, 10)
+		}
+
+		var variantID int64
+		if variant != nil {
+			variantID = variant.ID
+			requestData.Variant = strconv.FormatInt(variant.ID, 10)
 		}
 
-		if err := historyDB.Insert(shortURL, requestData, r); err != nil {
+		if err := historyDB.Insert(linkKey, requestData, r); err != nil {
 			logError(logger, err)
 			response.Text(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		longURL := validURL.String()
+		if deepLink != "" {
+			longURL = deepLink
+		}
+
 		body, err := json.Marshal(&LinkRedirect{
-			ShortUrl: shortURL,
-			LongUrl:  validURL.String(),
-			Headers:  r.Header,
-			IPAddr:   ipAddr,
-			Country:  country,
-			Referer:  referer,
+			ShortUrl:  linkKey,
+			LongUrl:   longURL,
+			Headers:   r.Header,
+			IPAddr:    ipAddr,
+			Country:   country,
+			Referer:   referer,
+			RuleID:    ruleID,
+			VariantID: variantID,
+			Source:    source,
 		})
 		if err != nil {
 			logError(logger, err)
@@ -157,11 +307,80 @@ func Redirect(repo links.ILinksRepository, redirectLogger utils.DbLogger, histor
 			return
 		}
 
-		http.Redirect(w, r, validURL.String(), http.StatusSeeOther)
+		if deepLink != "" {
+			renderDeepLinkPage(w, deepLinkPage{DeepLink: deepLink, Fallback: validURL.String()}, logger)
+			return
+		}
+
+		// links with pixels are served by an interstitial page, other links keep a direct redirect
+		if len(link.Pixels) > 0 {
+			renderPixelPage(w, newPixelPage(link, validURL.String(), pixelDelay, pixels.Vars{
+				ShortURL: scheme + r.Host + r.URL.Path,
+				Referrer: referer,
+			}), logger)
+			return
+		}
+
+		http.Redirect(w, r, validURL.String(), redirectStatus(r, link))
 
 	})
 }
 
+// redirectStatus returns a link redirect status code, unlock form posts are answered with 303 See Other
+// since browsers resend a post with the password field to 307 and 308 redirect targets
+func redirectStatus(r *http.Request, link *links.CachedLink) int {
+	if r.Method == http.MethodPost {
+		return http.StatusSeeOther
+	}
+	return link.StatusCode()
+}
+
+// resolveLinkKey finds a custom domain by request host, requests to unknown hosts are served by the default domain
+func resolveLinkKey(domainsRepo *domains.Repository, host, shortURL string) (int64, string, error) {
+	domainID, ok, err := domainsRepo.LookupHost(host)
+	if err != nil {
+		return 0, "", err
+	}
+	if !ok {
+		return 0, links.LinkKey("", shortURL), nil
+	}
+	return domainID, links.LinkKey(domains.NormalizeHost(host), shortURL), nil
+}
+
+// loadLink reads a link from url cache, links missed in cache are loaded from database and cached
+func loadLink(repo links.ILinksRepository, urlCache cache.UrlCache, domainID int64, linkKey, shortURL string, logger *log.Logger) *links.CachedLink {
+
+	if link, ok := links.LoadCache(urlCache, linkKey); ok {
+		return link
+	}
+
+	dbLink, err := repo.UnshortenURL(domainID, shortURL)
+	if err != nil {
+		return nil
+	}
+
+	logger.Printf("cache miss, short=%v, long=%v\n", shortURL, dbLink.Long)
+	links.StoreCache(urlCache, dbLink)
+	link := dbLink.Cached()
+	return &link
+}
+
+// parseDestinationURL parses a stored destination, urls without a scheme are treated as https urls.
+// Destinations are canonicalized before storing, only unsafe schemes are rejected here
+// since links stored before urls were validated must keep working
+func parseDestinationURL(longURL string) (*url.URL, error) {
+	scheme := urls.Scheme(longURL)
+	for _, unsafe := range urls.UnsafeSchemes {
+		if scheme == unsafe {
+			return nil, urls.ErrUnsafeScheme
+		}
+	}
+	if scheme == "" {
+		longURL = "https://" + strings.TrimSpace(longURL)
+	}
+	return url.Parse(longURL)
+}
+
 type IPInfo struct {
 	Country string
 }
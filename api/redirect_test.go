@@ -0,0 +1,175 @@
+package api
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	bolt "go.etcd.io/bbolt"
+
+	"shortly/cache"
+
+	"shortly/app/data"
+	"shortly/app/domains"
+	"shortly/app/links"
+	"shortly/app/urls"
+)
+
+func TestClickSource(t *testing.T) {
+
+	cases := []struct {
+		target   string
+		source   string
+		rawQuery string
+	}{
+		{"/abc", "", ""},
+		{"/abc?src=qr", data.SourceQR, ""},
+		{"/abc?src=qr&utm_source=poster", data.SourceQR, "utm_source=poster"},
+		{"/abc?src=email", "", "src=email"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.target, nil)
+		if source := clickSource(r); source != c.source {
+			t.Errorf("%v: expected source %q, got %q", c.target, c.source, source)
+		}
+		if r.URL.RawQuery != c.rawQuery {
+			t.Errorf("%v: expected query %q, got %q", c.target, c.rawQuery, r.URL.RawQuery)
+		}
+	}
+
+	if u := sourceURL("https://sho.rt/abc", data.SourceQR); u != "https://sho.rt/abc?src=qr" {
+		t.Errorf("unexpected qr url %v", u)
+	}
+	if u := sourceURL("/abc", ""); u != "/abc" {
+		t.Errorf("unexpected direct url %v", u)
+	}
+}
+
+func TestParseDestinationURL(t *testing.T) {
+
+	cases := []struct {
+		longURL  string
+		expected string
+	}{
+		{"example.com/a", "https://example.com/a"},
+		{"https://example.com/a b", "https://example.com/a%20b"},
+		{"https://example.com/search?q=a b", "https://example.com/search?q=a b"},
+		{"https://my_host.example.com/", "https://my_host.example.com/"},
+		{"http://localhost:8080/x", "http://localhost:8080/x"},
+		{"tel:+15551234567", "tel:+15551234567"},
+		{"myapp://open/item", "myapp://open/item"},
+	}
+
+	for _, c := range cases {
+		u, err := parseDestinationURL(c.longURL)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", c.longURL, err)
+			continue
+		}
+		if u.String() != c.expected {
+			t.Errorf("%v: expected %v, got %v", c.longURL, c.expected, u.String())
+		}
+	}
+
+	for _, longURL := range []string{"javascript:alert(1)", " JavaScript:alert(1)", "data:text/html,<b>x</b>"} {
+		if _, err := parseDestinationURL(longURL); err != urls.ErrUnsafeScheme {
+			t.Errorf("%v: expected unsafe scheme error, got %v", longURL, err)
+		}
+	}
+}
+
+type mockRedirectRepository struct {
+	links.ILinksRepository
+	links map[string]links.Link
+}
+
+func (repo *mockRedirectRepository) UnshortenURL(domainID int64, shortURL string) (links.Link, error) {
+	return repo.links[shortURL], nil
+}
+
+func (repo *mockRedirectRepository) VerifyLinkPassword(domainID int64, shortURL, password string) (bool, error) {
+	return password == "secret", nil
+}
+
+type mockRedirectLogger struct{}
+
+func (l mockRedirectLogger) Push([]byte) error {
+	return nil
+}
+
+func TestRedirectStatusCode(t *testing.T) {
+
+	storage, err := bolt.Open(filepath.Join(t.TempDir(), "links.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	err = storage.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("details"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := log.New(ioutil.Discard, "", 0)
+	urlCache := cache.NewMemoryCache()
+	defer urlCache.Close()
+
+	repo := &mockRedirectRepository{links: map[string]links.Link{
+		"open":      {ID: 1, Short: "open", Long: "https://example.com/open", RedirectType: http.StatusTemporaryRedirect},
+		"protected": {ID: 2, Short: "protected", Long: "https://example.com/protected", RedirectType: http.StatusTemporaryRedirect, Password: "hash"},
+	}}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	handler := Redirect(repo, &domains.Repository{DB: db, Logger: logger}, mockRedirectLogger{},
+		&data.HistoryDB{DB: storage, Logger: logger}, urlCache, logger, t.TempDir(), 0)
+
+	cases := []struct {
+		method   string
+		path     string
+		password string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/open", "", http.StatusTemporaryRedirect, "https://example.com/open"},
+		{http.MethodGet, "/protected", "", http.StatusOK, ""},
+		// the password must not be resent to the destination
+		{http.MethodPost, "/protected", "secret", http.StatusSeeOther, "https://example.com/protected"},
+		{http.MethodPost, "/protected", "wrong", http.StatusUnauthorized, ""},
+	}
+
+	for _, c := range cases {
+		mock.ExpectQuery("select id from domains").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var r *http.Request
+		if c.method == http.MethodPost {
+			r = httptest.NewRequest(c.method, c.path, strings.NewReader(url.Values{"password": {c.password}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			r = httptest.NewRequest(c.method, c.path, nil)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != c.status {
+			t.Errorf("%v %v: expected status %v, got %v", c.method, c.path, c.status, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != c.location {
+			t.Errorf("%v %v: expected location %q, got %q", c.method, c.path, c.location, location)
+		}
+	}
+}
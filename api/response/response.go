@@ -45,4 +45,4 @@ func Text(w http.ResponseWriter, str string, statusCode int) {
 	w.Header().Set("Content-Type", textContentType)
 	w.WriteHeader(statusCode)
 	fmt.Fprint(w, str)
-}
\ No newline at end of file
+}
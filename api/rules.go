@@ -0,0 +1,260 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/cache"
+
+	"shortly/app/links"
+	"shortly/app/safety"
+)
+
+// LinkRuleResponse ...
+type LinkRuleResponse struct {
+	ID          int64    `json:"id"`
+	Position    int      `json:"position"`
+	Countries   []string `json:"countries"`
+	Devices     []string `json:"devices"`
+	Destination string   `json:"destination"`
+	Fallback    string   `json:"fallback"`
+}
+
+// LinkRuleForm ...
+type LinkRuleForm struct {
+	Position    int      `json:"position"`
+	Countries   []string `json:"countries"`
+	Devices     []string `json:"devices"`
+	Destination string   `json:"destination"`
+	Fallback    string   `json:"fallback"`
+}
+
+// accountLink reads link id from url path and loads a link owned by the account
+func accountLink(w http.ResponseWriter, r *http.Request, repo *links.LinksRepository, logger *log.Logger) (*links.Link, bool) {
+
+	linkID, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+	if err != nil {
+		response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+		return nil, false
+	}
+
+	link, err := repo.GetLinkByID(linkID)
+	if err == sql.ErrNoRows || (err == nil && link.AccountID != GetAccountID(r)) {
+		response.Error(w, "link not found", http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		logError(logger, err)
+		response.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return &link, true
+}
+
+// refreshLinkCache stores a link with actual rules into url cache, hidden links are removed from cache
+func refreshLinkCache(repo *links.LinksRepository, urlCache cache.UrlCache, linkID int64) error {
+	link, err := repo.GetLinkByID(linkID)
+	if err != nil {
+		return err
+	}
+	links.StoreCache(urlCache, link)
+	return nil
+}
+
+// GetLinkRules ...
+func GetLinkRules(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		rows, err := repo.GetLinkRules(link.ID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]LinkRuleResponse, 0)
+		for _, rule := range rows {
+			list = append(list, LinkRuleResponse{
+				ID:          rule.ID,
+				Position:    rule.Position,
+				Countries:   rule.Countries,
+				Devices:     rule.Devices,
+				Destination: rule.Destination,
+				Fallback:    rule.Fallback,
+			})
+		}
+
+		response.Object(w, list, http.StatusOK)
+	})
+}
+
+// CreateLinkRule ...
+func CreateLinkRule(repo *links.LinksRepository, urlCache cache.UrlCache, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		var form LinkRuleForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		rule := links.Rule{
+			Position:    form.Position,
+			Countries:   form.Countries,
+			Devices:     form.Devices,
+			Destination: form.Destination,
+			Fallback:    form.Fallback,
+		}
+
+		if err := links.ValidateRule(&rule); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !checkDestinationSafety(w, r, checker, rule.Destination, rule.Fallback) {
+			return
+		}
+
+		ruleID, err := repo.CreateLinkRule(link.ID, rule)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &LinkRuleResponse{
+			ID:          ruleID,
+			Position:    rule.Position,
+			Countries:   rule.Countries,
+			Devices:     rule.Devices,
+			Destination: rule.Destination,
+			Fallback:    rule.Fallback,
+		}, http.StatusOK)
+	})
+}
+
+// UpdateLinkRule ...
+func UpdateLinkRule(repo *links.LinksRepository, urlCache cache.UrlCache, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		ruleID, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 0, 64)
+		if err != nil {
+			response.Error(w, "ruleID parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		var form LinkRuleForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		rule := links.Rule{
+			ID:          ruleID,
+			Position:    form.Position,
+			Countries:   form.Countries,
+			Devices:     form.Devices,
+			Destination: form.Destination,
+			Fallback:    form.Fallback,
+		}
+
+		if err := links.ValidateRule(&rule); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// deep link destinations are checked by blocklist patterns only
+		if !checkDestinationSafety(w, r, checker, rule.Destination, rule.Fallback) {
+			return
+		}
+
+		err = repo.UpdateLinkRule(link.ID, rule)
+		if err == sql.ErrNoRows {
+			response.Error(w, "rule not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &LinkRuleResponse{
+			ID:          rule.ID,
+			Position:    rule.Position,
+			Countries:   rule.Countries,
+			Devices:     rule.Devices,
+			Destination: rule.Destination,
+			Fallback:    rule.Fallback,
+		}, http.StatusOK)
+	})
+}
+
+// DeleteLinkRule ...
+func DeleteLinkRule(repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		ruleID, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 0, 64)
+		if err != nil {
+			response.Error(w, "ruleID parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		if err := repo.DeleteLinkRule(link.ID, ruleID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
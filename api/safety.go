@@ -0,0 +1,206 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/app/links"
+	"shortly/app/safety"
+)
+
+// checkLinkSafety scores destinations of a link before it is stored,
+// links with blocked destinations are rejected, suspicious links are stored as quarantined
+func checkLinkSafety(w http.ResponseWriter, r *http.Request, checker *safety.Checker, link *links.Link) bool {
+
+	result := checker.CheckLink(r.Context(), *link)
+	if result.Status == links.SafetyBlocked {
+		response.Error(w, "destination url is blocked: "+strings.Join(result.Reasons, "; "), http.StatusBadRequest)
+		return false
+	}
+
+	link.SafetyScore = result.Score
+	link.SafetyStatus = result.Status
+	link.SafetyReasons = result.Reasons
+	return true
+}
+
+// checkDestinationSafety rejects suspicious destinations of link rules and variants
+func checkDestinationSafety(w http.ResponseWriter, r *http.Request, checker *safety.Checker, destinations ...string) bool {
+
+	for _, destination := range destinations {
+		if destination == "" {
+			continue
+		}
+		result := checker.Check(r.Context(), destination)
+		if result.Status != links.SafetyOK {
+			response.Error(w, "destination url is "+result.Status+": "+strings.Join(result.Reasons, "; "), http.StatusBadRequest)
+			return false
+		}
+	}
+
+	return true
+}
+
+var unsafePageTemplate = template.Must(template.New("unsafe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <meta name="robots" content="noindex, nofollow">
+    <title>Link is disabled</title>
+    <style>
+        body { font-family: sans-serif; background: #f4f6f9; display: flex; justify-content: center; padding-top: 10vh; }
+        .notice { background: #fff; padding: 24px; border-radius: 4px; box-shadow: 0 1px 3px rgba(0,0,0,.2); max-width: 480px; }
+    </style>
+</head>
+<body>
+    <div class="notice">
+        {{if .Quarantined}}
+        <h3>This link is under review</h3>
+        <p>The destination of this link looks suspicious and is being checked.</p>
+        {{else}}
+        <h3>This link has been disabled</h3>
+        <p>The destination of this link was reported as harmful.</p>
+        {{end}}
+    </div>
+</body>
+</html>
+`))
+
+// renderUnsafePage writes an html page shown instead of redirect for quarantined and blocked links
+func renderUnsafePage(w http.ResponseWriter, status string, logger *log.Logger) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+	page := struct{ Quarantined bool }{Quarantined: status == links.SafetyQuarantined}
+	if err := unsafePageTemplate.Execute(w, &page); err != nil {
+		logError(logger, err)
+	}
+}
+
+// BlocklistEntryResponse ...
+type BlocklistEntryResponse struct {
+	ID     int64  `json:"id"`
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// GetBlocklist ...
+func GetBlocklist(repo *safety.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		entries, err := repo.GetEntries()
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]BlocklistEntryResponse, 0, len(entries))
+		for _, entry := range entries {
+			list = append(list, BlocklistEntryResponse{
+				ID:     entry.ID,
+				Kind:   entry.Kind,
+				Value:  entry.Value,
+				Reason: entry.Reason,
+			})
+		}
+
+		response.Object(w, &list, http.StatusOK)
+
+	})
+}
+
+// BlocklistEntryForm ...
+type BlocklistEntryForm struct {
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// CreateBlocklistEntry adds a domain, an ip network or an url pattern to blocklist,
+// new entries are applied to link checks immediately and to existing links on next recheck
+func CreateBlocklistEntry(repo *safety.Repository, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var form BlocklistEntryForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		entry := safety.Entry{
+			Kind:   form.Kind,
+			Value:  form.Value,
+			Reason: form.Reason,
+		}
+
+		if err := safety.ValidateEntry(&entry); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id, err := repo.CreateEntry(&entry)
+		if err == safety.ErrEntryExists {
+			response.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := repo.LoadBlocklist(checker); err != nil {
+			logError(logger, err)
+		}
+
+		response.Object(w, &BlocklistEntryResponse{
+			ID:     id,
+			Kind:   entry.Kind,
+			Value:  entry.Value,
+			Reason: entry.Reason,
+		}, http.StatusCreated)
+
+	})
+}
+
+// DeleteBlocklistEntry ...
+func DeleteBlocklistEntry(repo *safety.Repository, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+		if err != nil || id <= 0 {
+			response.Error(w, "id is not a number", http.StatusBadRequest)
+			return
+		}
+
+		if err := repo.DeleteEntry(id); err == sql.ErrNoRows {
+			response.Error(w, "blocklist entry not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := repo.LoadBlocklist(checker); err != nil {
+			logError(logger, err)
+		}
+
+		response.Ok(w)
+
+	})
+}
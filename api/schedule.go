@@ -0,0 +1,155 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+
+	"shortly/app/links"
+	"shortly/app/safety"
+	"shortly/app/schedule"
+	"shortly/utils"
+)
+
+// ScheduleEntryResponse ...
+type ScheduleEntryResponse struct {
+	ID          int64      `json:"id"`
+	LinkID      int64      `json:"linkId"`
+	UserID      int64      `json:"userId"`
+	Action      string     `json:"action"`
+	Destination string     `json:"destination,omitempty"`
+	RunAt       time.Time  `json:"runAt"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
+
+func scheduleEntryResponse(e schedule.Entry) ScheduleEntryResponse {
+	return ScheduleEntryResponse{
+		ID:          e.ID,
+		LinkID:      e.LinkID,
+		UserID:      e.UserID,
+		Action:      e.Action,
+		Destination: e.Destination,
+		RunAt:       e.RunAt,
+		Status:      e.Status,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt,
+		AppliedAt:   e.AppliedAt,
+	}
+}
+
+// ScheduleEntryForm ...
+type ScheduleEntryForm struct {
+	Action      string    `json:"action"`
+	Destination string    `json:"destination"`
+	RunAt       time.Time `json:"runAt"`
+}
+
+// CreateScheduleEntry schedules activation, deactivation or a destination swap of a link
+func CreateScheduleEntry(repo *links.LinksRepository, scheduleRepo *schedule.Repository, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		var form ScheduleEntryForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		entry := &schedule.Entry{
+			AccountID:   claims.AccountID,
+			UserID:      claims.UserID,
+			LinkID:      link.ID,
+			Action:      form.Action,
+			Destination: form.Destination,
+			RunAt:       form.RunAt,
+		}
+
+		if err := entry.Validate(utils.Now()); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// a swapped destination is checked again when the entry is applied
+		if !checkDestinationSafety(w, r, checker, entry.Destination) {
+			return
+		}
+
+		if err := scheduleRepo.CreateEntry(entry); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result := scheduleEntryResponse(*entry)
+		response.Object(w, &result, http.StatusCreated)
+	})
+}
+
+// GetScheduleEntries returns schedule entries of account links, optionally filtered by link id and status
+func GetScheduleEntries(scheduleRepo *schedule.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		query := r.URL.Query()
+		linkID, _ := strconv.ParseInt(query.Get("linkId"), 0, 64)
+
+		list, err := scheduleRepo.GetEntries(claims.AccountID, linkID, query.Get("status"))
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result := make([]ScheduleEntryResponse, 0, len(list))
+		for _, e := range list {
+			result = append(result, scheduleEntryResponse(e))
+		}
+
+		response.Object(w, &result, http.StatusOK)
+	})
+}
+
+// CancelScheduleEntry cancels a pending schedule entry
+func CancelScheduleEntry(scheduleRepo *schedule.Repository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+		if err != nil {
+			response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		if err := scheduleRepo.CancelEntry(claims.AccountID, id); err == sql.ErrNoRows {
+			response.Error(w, "pending schedule entry not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/app/links"
+	"shortly/app/trash"
+	"shortly/cache"
+)
+
+// TrashLinkResponse ...
+type TrashLinkResponse struct {
+	ID          int64      `json:"id"`
+	Short       string     `json:"short"`
+	Long        string     `json:"long"`
+	Description string     `json:"description"`
+	Domain      string     `json:"domain,omitempty"`
+	DeletedAt   *time.Time `json:"deletedAt"`
+	PurgeAt     *time.Time `json:"purgeAt"`
+}
+
+// TrashListResponse ...
+type TrashListResponse struct {
+	Links []TrashLinkResponse `json:"links"`
+	Total int64               `json:"total"`
+}
+
+// trashLinkID ...
+func trashLinkID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	linkID, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 64)
+	if err != nil {
+		response.Error(w, "id parameter is not a number", http.StatusBadRequest)
+		return 0, false
+	}
+	return linkID, true
+}
+
+// GetTrashLinks returns deleted links of account with time of their purge
+func GetTrashLinks(repo *links.LinksRepository, retention time.Duration, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		query := r.URL.Query()
+		limit, _ := strconv.ParseInt(query.Get("limit"), 0, 64)
+		offset, _ := strconv.ParseInt(query.Get("offset"), 0, 64)
+
+		result, err := repo.GetUserLinks(claims.AccountID, claims.UserID, limit, offset, links.LinkFilter{Trash: true})
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]TrashLinkResponse, 0, len(result.Rows))
+		for _, r := range result.Rows {
+			item := TrashLinkResponse{
+				ID:          r.ID,
+				Short:       r.Short,
+				Long:        r.Long,
+				Description: r.Description,
+				Domain:      r.Domain,
+				DeletedAt:   r.DeletedAt,
+			}
+			if r.DeletedAt != nil {
+				purgeAt := r.DeletedAt.Add(retention)
+				item.PurgeAt = &purgeAt
+			}
+			list = append(list, item)
+		}
+
+		response.Object(w, &TrashListResponse{Links: list, Total: result.Total}, http.StatusOK)
+
+	})
+}
+
+// RestoreLink moves a link out of trash, its click history is kept while the link is in trash
+func RestoreLink(repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		linkID, ok := trashLinkID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := repo.RestoreUserLink(GetAccountID(r), linkID); err == sql.ErrNoRows {
+			response.Error(w, "link not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, linkID); err != nil {
+			logError(logger, err)
+		}
+
+		response.Ok(w)
+
+	})
+}
+
+// PurgeTrashLink removes a link from trash permanently without waiting for retention
+func PurgeTrashLink(repo *links.LinksRepository, purger *trash.Purger, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		linkID, ok := trashLinkID(w, r)
+		if !ok {
+			return
+		}
+
+		link, err := repo.GetTrashedLink(GetAccountID(r), linkID)
+		if err == sql.ErrNoRows {
+			response.Error(w, "link not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := purger.Purge(link); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+
+	})
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/cache"
+
+	"shortly/app/data"
+	"shortly/app/links"
+	"shortly/app/safety"
+)
+
+// variantCookieName is a cookie keeping sticky split test variant, the cookie is scoped to the link path
+const variantCookieName = "shortly_variant"
+
+// variantCookieMaxAge ...
+const variantCookieMaxAge = 30 * 24 * 3600
+
+// pickVariant chooses a split test variant, sticky links reuse a variant stored in the visitor cookie
+func pickVariant(w http.ResponseWriter, r *http.Request, link *links.CachedLink) *links.Variant {
+
+	if link.StickyVariants {
+		if cookie, err := r.Cookie(variantCookieName); err == nil {
+			if id, err := strconv.ParseInt(cookie.Value, 10, 64); err == nil {
+				if variant := link.VariantByID(id); variant != nil {
+					return variant
+				}
+			}
+		}
+	}
+
+	variant := link.PickVariant(rand.Intn)
+
+	if variant != nil && link.StickyVariants {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookieName,
+			Value:    strconv.FormatInt(variant.ID, 10),
+			Path:     r.URL.Path,
+			MaxAge:   variantCookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return variant
+}
+
+// LinkVariantResponse ...
+type LinkVariantResponse struct {
+	ID          int64  `json:"id"`
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// LinkVariantForm ...
+type LinkVariantForm struct {
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// GetLinkVariants ...
+func GetLinkVariants(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		rows, err := repo.GetLinkVariants(link.ID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]LinkVariantResponse, 0)
+		for _, v := range rows {
+			list = append(list, LinkVariantResponse{
+				ID:          v.ID,
+				Destination: v.Destination,
+				Weight:      v.Weight,
+			})
+		}
+
+		response.Object(w, list, http.StatusOK)
+	})
+}
+
+// CreateLinkVariant ...
+func CreateLinkVariant(repo *links.LinksRepository, urlCache cache.UrlCache, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		var form LinkVariantForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		variant := links.Variant{
+			Destination: form.Destination,
+			Weight:      form.Weight,
+		}
+
+		if err := links.ValidateVariant(&variant); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !checkDestinationSafety(w, r, checker, variant.Destination) {
+			return
+		}
+
+		variantID, err := repo.CreateLinkVariant(link.ID, variant)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &LinkVariantResponse{
+			ID:          variantID,
+			Destination: variant.Destination,
+			Weight:      variant.Weight,
+		}, http.StatusOK)
+	})
+}
+
+// UpdateLinkVariant ...
+func UpdateLinkVariant(repo *links.LinksRepository, urlCache cache.UrlCache, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		variantID, err := strconv.ParseInt(chi.URLParam(r, "variantID"), 0, 64)
+		if err != nil {
+			response.Error(w, "variantID parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		var form LinkVariantForm
+
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			response.Error(w, "decode form error", http.StatusBadRequest)
+			return
+		}
+
+		variant := links.Variant{
+			ID:          variantID,
+			Destination: form.Destination,
+			Weight:      form.Weight,
+		}
+
+		if err := links.ValidateVariant(&variant); err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !checkDestinationSafety(w, r, checker, variant.Destination) {
+			return
+		}
+
+		err = repo.UpdateLinkVariant(link.ID, variant)
+		if err == sql.ErrNoRows {
+			response.Error(w, "variant not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Object(w, &LinkVariantResponse{
+			ID:          variant.ID,
+			Destination: variant.Destination,
+			Weight:      variant.Weight,
+		}, http.StatusOK)
+	})
+}
+
+// DeleteLinkVariant ...
+func DeleteLinkVariant(repo *links.LinksRepository, urlCache cache.UrlCache, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		variantID, err := strconv.ParseInt(chi.URLParam(r, "variantID"), 0, 64)
+		if err != nil {
+			response.Error(w, "variantID parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		if err := repo.DeleteLinkVariant(link.ID, variantID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := refreshLinkCache(repo, urlCache, link.ID); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		response.Ok(w)
+	})
+}
+
+// VariantStatResponse ...
+type VariantStatResponse struct {
+	ID          int64               `json:"id"`
+	Destination string              `json:"destination"`
+	Weight      int                 `json:"weight"`
+	Clicks      int64               `json:"clicks"`
+	Days        []ClickDataResponse `json:"days"`
+}
+
+// GetLinkVariantsStat returns clicks of each split test variant
+func GetLinkVariantsStat(repo *links.LinksRepository, historyDB *data.HistoryDB, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		clicks, err := historyDB.GetVariantClicks(link.Key())
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]VariantStatResponse, 0)
+		for _, v := range link.Variants {
+			stat := VariantStatResponse{
+				ID:          v.ID,
+				Destination: v.Destination,
+				Weight:      v.Weight,
+				Days:        make([]ClickDataResponse, 0),
+			}
+			for _, c := range clicks[strconv.FormatInt(v.ID, 10)] {
+				stat.Clicks += c.Count
+				stat.Days = append(stat.Days, ClickDataResponse{Time: c.Time, Count: c.Count})
+			}
+			list = append(list, stat)
+		}
+
+		response.Object(w, list, http.StatusOK)
+	})
+}
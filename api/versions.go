@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"shortly/api/response"
+	"shortly/app/links"
+	"shortly/app/safety"
+	"shortly/cache"
+)
+
+// LinkChangeResponse ...
+type LinkChangeResponse struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// LinkVersionResponse ...
+type LinkVersionResponse struct {
+	ID       int64                `json:"id"`
+	Action   string               `json:"action"`
+	Time     time.Time            `json:"time"`
+	UserID   int64                `json:"userId"`
+	Username string               `json:"username"`
+	Changes  []LinkChangeResponse `json:"changes"`
+}
+
+// GetLinkVersions ...
+func GetLinkVersions(repo *links.LinksRepository, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		versions, err := repo.GetLinkVersions(link.ID)
+		if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]LinkVersionResponse, 0, len(versions))
+		for _, version := range versions {
+			changes := make([]LinkChangeResponse, 0)
+			for _, change := range version.Changes() {
+				changes = append(changes, LinkChangeResponse{
+					Field: change.Field,
+					Old:   change.Old,
+					New:   change.New,
+				})
+			}
+			list = append(list, LinkVersionResponse{
+				ID:       version.ID,
+				Action:   version.Action,
+				Time:     version.Time,
+				UserID:   version.UserID,
+				Username: version.Username,
+				Changes:  changes,
+			})
+		}
+
+		response.Object(w, &list, http.StatusOK)
+
+	})
+}
+
+// RollbackLink restores a link state before a version, the rollback is stored as a new version
+func RollbackLink(repo *links.LinksRepository, urlCache cache.UrlCache, checker *safety.Checker, logger *log.Logger) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		link, ok := accountLink(w, r, repo, logger)
+		if !ok {
+			return
+		}
+
+		versionID, err := strconv.ParseInt(chi.URLParam(r, "version"), 0, 64)
+		if err != nil {
+			response.Error(w, "version parameter is not a number", http.StatusBadRequest)
+			return
+		}
+
+		version, err := repo.GetLinkVersion(link.ID, versionID)
+		if err == sql.ErrNoRows {
+			response.Error(w, "version not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		state, err := version.RollbackState()
+		if err != nil {
+			response.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		state.Apply(link)
+
+		if !checkLinkSafety(w, r, checker, link) {
+			return
+		}
+
+		claims := r.Context().Value("user").(*JWTClaims)
+
+		tx, err := repo.UpdateUserLink(link.AccountID, claims.UserID, link.ID, link)
+		if err != nil {
+			if tx != nil {
+				_ = tx.Rollback()
+			}
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			logError(logger, err)
+			response.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		// hidden links stay out of url cache, see links.StoreCache
+		links.StoreCache(urlCache, *link)
+
+		response.Object(w, &LinkResponse{
+			ID:             link.ID,
+			Short:          shortLinkURL(r, *link),
+			Long:           link.Long,
+			Description:    link.Description,
+			ExpiresAt:      link.ExpiresAt,
+			MaxClicks:      link.MaxClicks,
+			FallbackURL:    link.FallbackURL,
+			Protected:      link.Protected(),
+			Domain:         link.Domain,
+			RedirectType:   link.RedirectType,
+			StickyVariants: link.StickyVariants,
+			SafetyScore:    link.SafetyScore,
+			SafetyStatus:   link.SafetyStatus,
+			SafetyReasons:  link.SafetyReasons,
+		}, http.StatusOK)
+
+	})
+}
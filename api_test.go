@@ -29,12 +29,8 @@ func (repo *MockLinksRepository) GetAllLinks() ([]links.Link, error) {
 	}, nil
 }
 
-func (repo *MockLinksRepository) GenerateLink() string {
-	return "ABCDE"
-}
-
-func (repo *MockLinksRepository) UnshortenURL(shortURL string) (string, error) {
-	return "", nil
+func (repo *MockLinksRepository) UnshortenURL(domainID int64, shortURL string) (links.Link, error) {
+	return links.Link{}, nil
 }
 
 func (repo *MockLinksRepository) CreateLink(*links.Link) error {
@@ -45,6 +41,10 @@ func (repo *MockLinksRepository) GetLinkByID(_ int64) (links.Link, error) {
 	return links.Link{}, nil
 }
 
+func (repo *MockLinksRepository) VerifyLinkPassword(_ int64, _, _ string) (bool, error) {
+	return true, nil
+}
+
 func (repo *MockLinksRepository) GetUserLinks(_, _, _, _ int64, filters ...links.LinkFilter) (*links.LinkResult, error) {
 	rows := []links.Link{
 		{Short: "12345", Long: "www.facebook.com"},
@@ -53,7 +53,7 @@ func (repo *MockLinksRepository) GetUserLinks(_, _, _, _ int64, filters ...links
 	return &links.LinkResult{Total: 2, Rows: rows}, nil
 }
 
-func (repo *MockLinksRepository) UpdateUserLink(_, _ int64, _ *links.Link) (*sql.Tx, error) {
+func (repo *MockLinksRepository) UpdateUserLink(_, _, _ int64, _ *links.Link) (*sql.Tx, error) {
 	return nil, nil
 }
 
@@ -10,6 +10,8 @@ type Account struct {
 	Name      string
 	CreatedAt time.Time
 	Verified  bool
+	// DefaultRedirectType is a redirect http status code used by links without own redirect type
+	DefaultRedirectType int
 }
 
 // User ...
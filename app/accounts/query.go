@@ -95,12 +95,13 @@ func (r *UsersRepository) GetAccount(accountID int64) (*Account, error) {
 
 	var account Account
 	err := r.DB.QueryRow(
-		"select name, created_at, verified from accounts where id = $1",
+		"select name, created_at, verified, default_redirect_type from accounts where id = $1",
 		accountID,
 	).Scan(
 		&account.Name,
 		&account.CreatedAt,
 		&account.Verified,
+		&account.DefaultRedirectType,
 	)
 
 	if err != nil {
@@ -110,6 +111,15 @@ func (r *UsersRepository) GetAccount(accountID int64) (*Account, error) {
 	return &account, nil
 }
 
+// UpdateDefaultRedirectType ...
+func (r *UsersRepository) UpdateDefaultRedirectType(accountID int64, redirectType int) error {
+	_, err := r.DB.Exec(
+		"update accounts set default_redirect_type = $1 where id = $2",
+		redirectType, accountID,
+	)
+	return err
+}
+
 // GetAccountUsers ...
 func (r *UsersRepository) GetAccountUsers(accountID int64) ([]User, error) {
 
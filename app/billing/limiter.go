@@ -422,3 +422,19 @@ func (l *BillingLimiter) Increase(optionName string, accountID int64) error {
 		})
 	})
 }
+
+// ReleaseLink frees url_limit counter for a removed link, only links created within a current plan period are counted
+func (l *BillingLimiter) ReleaseLink(accountID int64, createdAt time.Time) error {
+	return l.DB.Update(func(tx *bolt.Tx) error {
+		account, err := l.getAccount(tx, accountID)
+		if err != nil {
+			return err
+		}
+		if createdAt.Before(account.Start) || createdAt.After(account.End) {
+			return nil
+		}
+		return l.UpdateOption(tx, "url_limit", accountID, func(v int64) int64 {
+			return v + 1
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package bulk
+
+import (
+	"errors"
+	"time"
+
+	"shortly/app/campaigns"
+	"shortly/app/links"
+)
+
+// bulk actions
+const (
+	ActionHide     = "hide"
+	ActionActivate = "activate"
+	ActionDelete   = "delete"
+	ActionTag      = "tag"
+	ActionUntag    = "untag"
+	ActionGroup    = "group"
+	ActionCampaign = "campaign"
+	// ActionImport is an action of link import jobs, it is not accepted by bulk requests
+	ActionImport = "import"
+)
+
+// job statuses
+const (
+	StatusRunning  = "running"
+	StatusFinished = "finished"
+	StatusFailed   = "failed"
+)
+
+var (
+	// ErrInvalidAction ...
+	ErrInvalidAction = errors.New("action must be one of hide, activate, delete, tag, untag, group, campaign")
+	// ErrNoLinks ...
+	ErrNoLinks = errors.New("link ids or filter are required")
+	// ErrTagRequired ...
+	ErrTagRequired = errors.New("tag is required")
+	// ErrGroupRequired ...
+	ErrGroupRequired = errors.New("group id is required")
+	// ErrChannelRequired ...
+	ErrChannelRequired = errors.New("campaign and channel ids are required")
+)
+
+// Request describes a set of links and an action applied to each of them,
+// links are selected by ids or by a filter when no ids are provided
+type Request struct {
+	Action  string
+	LinkIDs []int64
+	Filter  *links.LinkFilter
+	// Tag is used by tag and untag actions
+	Tag string
+	// GroupID is used by group action
+	GroupID int64
+	// CampaignID, ChannelID and UTM are used by campaign action
+	CampaignID int64
+	ChannelID  int64
+	UTM        campaigns.UTMSetting
+}
+
+// Validate ...
+func (r Request) Validate() error {
+
+	if len(r.LinkIDs) == 0 && r.Filter == nil {
+		return ErrNoLinks
+	}
+
+	switch r.Action {
+	case ActionHide, ActionActivate, ActionDelete:
+	case ActionTag, ActionUntag:
+		if r.Tag == "" {
+			return ErrTagRequired
+		}
+	case ActionGroup:
+		if r.GroupID <= 0 {
+			return ErrGroupRequired
+		}
+	case ActionCampaign:
+		if r.CampaignID <= 0 || r.ChannelID <= 0 {
+			return ErrChannelRequired
+		}
+		if err := links.ValidateUTMMode(r.UTM.Mode); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidAction
+	}
+
+	return nil
+}
+
+// ItemResult is a result of an action applied to a single link
+type ItemResult struct {
+	// Row is a line of an import file
+	Row    int    `json:"row,omitempty"`
+	LinkID int64  `json:"linkId,omitempty"`
+	Short  string `json:"short,omitempty"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Job is a tracked bulk action, Results are reported per link
+type Job struct {
+	ID         int64
+	AccountID  int64
+	UserID     int64
+	Action     string
+	Status     string
+	Total      int
+	Processed  int
+	Failed     int
+	Results    []ItemResult
+	CreatedAt  time.Time
+	FinishedAt *time.Time
+}
@@ -0,0 +1,33 @@
+package bulk
+
+import (
+	"testing"
+
+	"shortly/app/links"
+)
+
+func TestRequestValidate(t *testing.T) {
+
+	ids := []int64{1, 2}
+
+	tests := []struct {
+		req Request
+		err error
+	}{
+		{Request{Action: ActionHide, LinkIDs: ids}, nil},
+		{Request{Action: ActionDelete, Filter: &links.LinkFilter{Tags: []string{"sale"}}}, nil},
+		{Request{Action: ActionHide}, ErrNoLinks},
+		{Request{Action: "archive", LinkIDs: ids}, ErrInvalidAction},
+		{Request{Action: ActionTag, LinkIDs: ids}, ErrTagRequired},
+		{Request{Action: ActionUntag, LinkIDs: ids, Tag: "sale"}, nil},
+		{Request{Action: ActionGroup, LinkIDs: ids}, ErrGroupRequired},
+		{Request{Action: ActionCampaign, LinkIDs: ids, CampaignID: 1}, ErrChannelRequired},
+		{Request{Action: ActionCampaign, LinkIDs: ids, CampaignID: 1, ChannelID: 2}, nil},
+	}
+
+	for _, tt := range tests {
+		if err := tt.req.Validate(); err != tt.err {
+			t.Errorf("%+v: expected error %v, got %v", tt.req, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package bulk
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+)
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+const jobFields = `id, account_id, user_id, action, status, total, processed, failed, coalesce(results, '[]'), created_at, finished_at`
+
+// jobListFields skips per link results
+const jobListFields = `id, account_id, user_id, action, status, total, processed, failed, '[]'::jsonb, created_at, finished_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner, job *Job) error {
+	var results []byte
+	err := row.Scan(&job.ID, &job.AccountID, &job.UserID, &job.Action, &job.Status, &job.Total, &job.Processed, &job.Failed,
+		&results, &job.CreatedAt, &job.FinishedAt)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(results, &job.Results)
+}
+
+// CreateJob ...
+func (r *Repository) CreateJob(job *Job) error {
+	return r.DB.QueryRow(`
+		insert into bulk_jobs (account_id, user_id, action, status, total, created_at)
+		values ($1, $2, $3, $4, $5, now()) returning id, created_at`,
+		job.AccountID, job.UserID, job.Action, job.Status, job.Total,
+	).Scan(&job.ID, &job.CreatedAt)
+}
+
+// FailInterruptedJobs marks jobs left running by a stopped server as failed, jobs run in server goroutines
+// and are not resumed, it returns a number of failed jobs
+func (r *Repository) FailInterruptedJobs() (int64, error) {
+	results, err := json.Marshal([]ItemResult{{Error: "job was interrupted by a server restart"}})
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.DB.Exec(`update bulk_jobs set status = $1, results = coalesce(results, $2), finished_at = now() where status = $3`,
+		StatusFailed, results, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UpdateProgress ...
+func (r *Repository) UpdateProgress(jobID int64, processed, failed int) error {
+	_, err := r.DB.Exec(`update bulk_jobs set processed = $1, failed = $2 where id = $3`, processed, failed, jobID)
+	return err
+}
+
+// FinishJob stores a per link report of a job
+func (r *Repository) FinishJob(job *Job) error {
+	results, err := json.Marshal(job.Results)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(`
+		update bulk_jobs set status = $1, processed = $2, failed = $3, results = $4, finished_at = now() where id = $5`,
+		job.Status, job.Processed, job.Failed, results, job.ID,
+	)
+	return err
+}
+
+// GetJob returns sql.ErrNoRows for jobs of other accounts
+func (r *Repository) GetJob(accountID, jobID int64) (Job, error) {
+	var job Job
+	err := scanJob(r.DB.QueryRow(`select `+jobFields+` from bulk_jobs where id = $1 and account_id = $2`, jobID, accountID), &job)
+	return job, err
+}
+
+// GetJobs returns the latest jobs of account without per link results
+func (r *Repository) GetJobs(accountID int64, limit int) ([]Job, error) {
+
+	rows, err := r.DB.Query(`select `+jobListFields+` from bulk_jobs where account_id = $1 order by id desc limit $2`, accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Job
+	for rows.Next() {
+		var job Job
+		if err := scanJob(rows, &job); err != nil {
+			return nil, err
+		}
+		list = append(list, job)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,145 @@
+package bulk
+
+import (
+	"log"
+
+	"shortly/app/campaigns"
+	"shortly/app/links"
+	"shortly/app/tags"
+	"shortly/cache"
+	"shortly/utils"
+)
+
+// progressStep is a number of processed links between job progress updates
+const progressStep = 100
+
+// Runner applies bulk actions in background, every link is changed with the same repository
+// methods as single link endpoints, so repository callbacks and webhooks are fired per link
+type Runner struct {
+	Jobs      *Repository
+	Links     *links.LinksRepository
+	Tags      *tags.TagsRepository
+	Campaigns *campaigns.Repository
+	URLCache  cache.UrlCache
+	Logger    *log.Logger
+}
+
+// Start creates a job and applies an action to links in background
+func (r *Runner) Start(accountID, userID int64, req Request, list []links.Link, missing []int64) (*Job, error) {
+
+	job := &Job{
+		AccountID: accountID,
+		UserID:    userID,
+		Action:    req.Action,
+		Status:    StatusRunning,
+		Total:     len(list) + len(missing),
+	}
+
+	if err := r.Jobs.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	go r.run(*job, req, list, missing)
+
+	return job, nil
+}
+
+func (r *Runner) run(job Job, req Request, list []links.Link, missing []int64) {
+
+	for _, linkID := range missing {
+		job.Results = append(job.Results, ItemResult{LinkID: linkID, Error: "link not found"})
+		job.Processed++
+		job.Failed++
+	}
+
+	for _, link := range list {
+		result := ItemResult{LinkID: link.ID, Short: link.Key(), OK: true}
+		if err := r.apply(job.AccountID, req, link); err != nil {
+			r.Logger.Printf("bulk job(%v) link(%v) error: %v\n", job.ID, link.ID, err)
+			result.OK = false
+			result.Error = err.Error()
+			job.Failed++
+		}
+		job.Results = append(job.Results, result)
+		job.Processed++
+
+		if job.Processed%progressStep == 0 {
+			if err := r.Jobs.UpdateProgress(job.ID, job.Processed, job.Failed); err != nil {
+				r.Logger.Println("bulk job progress error", err)
+			}
+		}
+	}
+
+	job.Status = StatusFinished
+	if job.Failed > 0 && job.Failed == job.Total {
+		job.Status = StatusFailed
+	}
+	finishedAt := utils.Now()
+	job.FinishedAt = &finishedAt
+
+	if err := r.Jobs.FinishJob(&job); err != nil {
+		r.Logger.Println("bulk job finish error", err)
+	}
+}
+
+// apply changes a single link, cached links are removed to be reloaded on next redirect
+func (r *Runner) apply(accountID int64, req Request, link links.Link) error {
+
+	switch req.Action {
+	case ActionHide:
+		tx, err := r.Links.HideUserLink(accountID, link.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		r.URLCache.Delete(link.Key())
+		return nil
+
+	case ActionActivate:
+		tx, err := r.Links.ActivateUserLink(accountID, link.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		r.URLCache.Delete(link.Key())
+		return nil
+
+	case ActionDelete:
+		tx, _, err := r.Links.DeleteUserLink(accountID, link.ID)
+		if err != nil {
+			if tx != nil {
+				_ = tx.Rollback()
+			}
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		r.URLCache.Delete(link.Key())
+		return nil
+
+	case ActionTag:
+		_, err := r.Tags.AddTagToLink(link.ID, req.Tag)
+		return err
+
+	case ActionUntag:
+		_, err := r.Tags.DeleteTagFromLink(link.ID, req.Tag)
+		return err
+
+	case ActionGroup:
+		return r.Links.AddUrlToGroup(req.GroupID, link.ID)
+
+	case ActionCampaign:
+		if err := r.Campaigns.MoveLinkToCampaignChannel(req.CampaignID, req.ChannelID, link.ID, req.UTM); err != nil {
+			return err
+		}
+		r.URLCache.Delete(link.Key())
+		return nil
+	}
+
+	return ErrInvalidAction
+}
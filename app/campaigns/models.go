@@ -15,6 +15,8 @@ type UTMSetting struct {
 	Medium  string
 	Term    string
 	Content string
+	// Mode is a links.UTMPreserve or links.UTMOverride
+	Mode string
 }
 
 // Channel ...
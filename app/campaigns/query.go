@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"shortly/app/data"
+	"shortly/app/links"
 )
 
 // CampaignLink ...
@@ -229,13 +230,74 @@ func (r *Repository) DeleteChannelFromCampaign(cmpID int64, chID int64) (int64,
 func (r *Repository) AddLinkToCampaignChannel(cmpID, channelID, linkID int64, utm UTMSetting) (int64, error) {
 	var rowID int64
 	err := r.DB.QueryRow(`
-		insert into "campaigns_channels_links" (chan_campaign_id, link_id, utm_source, utm_medium, utm_term, utm_content) 
-		values ((select id from campaigns_channels where campaign_id = $1 and channel_id = $2 limit 1), $3, $4, $5, $6, $7)
+		insert into "campaigns_channels_links" (chan_campaign_id, link_id, utm_source, utm_medium, utm_term, utm_content, utm_mode) 
+		values ((select id from campaigns_channels where campaign_id = $1 and channel_id = $2 limit 1), $3, $4, $5, $6, $7, $8)
 		returning id
-	`, cmpID, channelID, linkID, utm.Source, utm.Medium, utm.Term, utm.Content).Scan(&rowID)
+	`, cmpID, channelID, linkID, utm.Source, utm.Medium, utm.Term, utm.Content, utm.Mode).Scan(&rowID)
 	return rowID, err
 }
 
+// MoveLinkToCampaignChannel removes a link from other channels of a campaign and adds it to a channel
+func (r *Repository) MoveLinkToCampaignChannel(cmpID, channelID, linkID int64, utm UTMSetting) error {
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+	delete from "campaigns_channels_links"
+	where chan_campaign_id in (select id from campaigns_channels where campaign_id = $1) and link_id = $2
+	`, cmpID, linkID)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`
+		insert into "campaigns_channels_links" (chan_campaign_id, link_id, utm_source, utm_medium, utm_term, utm_content, utm_mode)
+		values ((select id from campaigns_channels where campaign_id = $1 and channel_id = $2 limit 1), $3, $4, $5, $6, $7, $8)
+	`, cmpID, channelID, linkID, utm.Source, utm.Medium, utm.Term, utm.Content, utm.Mode)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCampaignLinkKeys returns url cache keys of links added to campaign channels
+func (r *Repository) GetCampaignLinkKeys(cmpID int64) ([]string, error) {
+
+	rows, err := r.DB.Query(`
+		select l.short_url, coalesce(d.host, '') from campaigns_channels ch
+		inner join campaigns_channels_links cl on cl.chan_campaign_id = ch.id
+		inner join links l on l.id = cl.link_id
+		left join domains d on d.id = l.domain_id
+		where ch.campaign_id = $1
+	`, cmpID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var shortURL, host string
+		if err := rows.Scan(&shortURL, &host); err != nil {
+			return nil, err
+		}
+		keys = append(keys, links.LinkKey(host, shortURL))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
 // DeleteLinkFromCampaignChannel ...
 func (r *Repository) DeleteLinkFromCampaignChannel(cmpID, channelID, linkID int64) error {
 	_, err := r.DB.Exec(`
@@ -8,9 +8,19 @@ type ClickData struct {
 	Count int64
 }
 
+// VariantClickData ...
+type VariantClickData struct {
+	Time      time.Time
+	VariantID int64
+	Count     int64
+}
+
 type LinkData struct {
 	Time     time.Time
 	Referer  string
 	Location string
-	Count    int64
+	RuleID   int64
+	// Source is empty for direct clicks
+	Source string
+	Count  int64
 }
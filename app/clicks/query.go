@@ -3,6 +3,8 @@ package clicks
 import (
 	"database/sql"
 	"log"
+
+	"shortly/app/data"
 )
 
 // Repository ...
@@ -101,8 +103,8 @@ func (r *Repository) GetLinkInfoByDay(shortURL string) ([]LinkData, error) {
 
 	rows, err := r.DB.Query(`
 	select date_trunc('day', timestamp at time zone 'utc') t, 
-	country, referer, count(*) from redirect_log where short_url = $1
-	group by t, country, referer
+	country, referer, coalesce(rule_id, 0) rule, source, count(*) from redirect_log where short_url = $1
+	group by t, country, referer, rule, source
 	`, shortURL)
 
 	if err != nil {
@@ -112,7 +114,7 @@ func (r *Repository) GetLinkInfoByDay(shortURL string) ([]LinkData, error) {
 	var list []LinkData
 	for rows.Next() {
 		var u LinkData
-		err := rows.Scan(&u.Time, &u.Location, &u.Referer, &u.Count)
+		err := rows.Scan(&u.Time, &u.Location, &u.Referer, &u.RuleID, &u.Source, &u.Count)
 		if err != nil {
 			return nil, err
 		}
@@ -127,3 +129,65 @@ func (r *Repository) GetLinkInfoByDay(shortURL string) ([]LinkData, error) {
 
 	return list, nil
 }
+
+// GetScansByDay returns daily numbers of qr code scans of a link
+func (r *Repository) GetScansByDay(shortURL string) ([]ClickData, error) {
+
+	rows, err := r.DB.Query(`
+	select date_trunc('day', timestamp at time zone 'utc') t, count(*) from redirect_log
+	where short_url = $1 and source = $2
+	group by t
+	`, shortURL, data.SourceQR)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []ClickData
+	for rows.Next() {
+		var u ClickData
+		if err := rows.Scan(&u.Time, &u.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetVariantClicksByDay ...
+func (r *Repository) GetVariantClicksByDay(shortURL string) ([]VariantClickData, error) {
+
+	rows, err := r.DB.Query(`
+	select date_trunc('day', timestamp at time zone 'utc') t, variant_id, count(*) from redirect_log
+	where short_url = $1 and variant_id is not null
+	group by t, variant_id
+	`, shortURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []VariantClickData
+	for rows.Next() {
+		var u VariantClickData
+		if err := rows.Scan(&u.Time, &u.VariantID, &u.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
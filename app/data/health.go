@@ -0,0 +1,93 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// healthChecksLimit is a number of destination checks kept per link
+const healthChecksLimit = 100
+
+// healthCheckKey is a sortable time format of health check keys
+const healthCheckKey = "2006-01-02T15:04:05.000000000Z"
+
+// HealthCheck is a result of a link destination probe
+type HealthCheck struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	LatencyMs  int64     `json:"latencyMs"`
+	FinalURL   string    `json:"finalUrl,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Broken destinations are unreachable or respond with 4xx and 5xx codes
+func (c HealthCheck) Broken() bool {
+	return c.Error != "" || c.StatusCode >= 400
+}
+
+// InsertHealthCheck stores a destination check, the oldest checks over the limit are removed
+func (d *HistoryDB) InsertHealthCheck(link string, check HealthCheck) error {
+	return d.Update(func(tx *bolt.Tx) error {
+
+		bucket, err := tx.CreateBucketIfNotExists([]byte("health:" + link))
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(&check)
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put([]byte(check.Time.UTC().Format(healthCheckKey)), value); err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		for i := 0; i < len(keys)-healthChecksLimit; i++ {
+			if err := bucket.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetHealthChecks returns destination checks of a link, the latest go first
+func (d *HistoryDB) GetHealthChecks(link string, limit int) ([]HealthCheck, error) {
+
+	list := make([]HealthCheck, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket([]byte("health:" + link))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(list) < limit); k, v = c.Prev() {
+			var check HealthCheck
+			if err := json.Unmarshal(v, &check); err != nil {
+				return err
+			}
+			list = append(list, check)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -32,9 +32,21 @@ func incrementTimeSeriesCounter(bucket *bolt.Bucket) error {
 	return bucket.Put([]byte(key), []byte(strconv.Itoa(int(intCounter))))
 }
 
+func incrementCounter(bucket *bolt.Bucket, key string) error {
+
+	counter, _ := strconv.ParseInt(string(bucket.Get([]byte(key))), 0, 64)
+	counter += 1
+
+	return bucket.Put([]byte(key), []byte(strconv.FormatInt(counter, 10)))
+}
+
 type LinkInfo struct {
 	Referrers map[string]int
 	Locations map[string]int
+	// Rules counts redirects by matched destination rule id
+	Rules map[string]int `json:",omitempty"`
+	// Sources counts redirects by click source, direct clicks and qr code scans
+	Sources map[string]int `json:",omitempty"`
 }
 
 func updateLinkInfo(info LinkRequestData, bucket *bolt.Bucket) error {
@@ -56,6 +68,18 @@ func updateLinkInfo(info LinkRequestData, bucket *bolt.Bucket) error {
 	linkInfoData.Locations[info.Location] += 1
 	linkInfoData.Referrers[info.Referrer] += 1
 
+	if info.Rule != "" {
+		if linkInfoData.Rules == nil {
+			linkInfoData.Rules = make(map[string]int)
+		}
+		linkInfoData.Rules[info.Rule] += 1
+	}
+
+	if linkInfoData.Sources == nil {
+		linkInfoData.Sources = make(map[string]int)
+	}
+	linkInfoData.Sources[info.source()] += 1
+
 	bf := bytes.NewBuffer([]byte{})
 	if err := json.NewEncoder(bf).Encode(&linkInfoData); err != nil {
 		return err
@@ -98,6 +122,55 @@ func (d *HistoryDB) InsertClick(link string, t time.Time, counter int) error {
 	})
 }
 
+// DeleteScans ...
+func (d *HistoryDB) DeleteScans(link string) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("scans:" + link))
+	})
+}
+
+// InsertScans stores a daily number of qr code scans, scans are counted in clicks too
+func (d *HistoryDB) InsertScans(link string, t time.Time, counter int) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		scansBucket, err := tx.CreateBucketIfNotExists([]byte("scans:" + link))
+		if err != nil {
+			return err
+		}
+		key := t.Format(time.RFC3339)
+		return scansBucket.Put([]byte(key), []byte(strconv.Itoa(counter)))
+	})
+}
+
+// SetTotalClicks ...
+func (d *HistoryDB) SetTotalClicks(link string, total int64) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		totalsBucket, err := tx.CreateBucketIfNotExists([]byte("totals"))
+		if err != nil {
+			return err
+		}
+		return totalsBucket.Put([]byte(link), []byte(strconv.FormatInt(total, 10)))
+	})
+}
+
+// GetTotalClicks returns a number of clicks made over the whole link lifetime
+func (d *HistoryDB) GetTotalClicks(link string) (int64, error) {
+	var total int64
+	err := d.View(func(tx *bolt.Tx) error {
+		totalsBucket := tx.Bucket([]byte("totals"))
+		if totalsBucket == nil {
+			return nil
+		}
+		v := totalsBucket.Get([]byte(link))
+		if v == nil {
+			return nil
+		}
+		var err error
+		total, err = strconv.ParseInt(string(v), 0, 64)
+		return err
+	})
+	return total, err
+}
+
 // DeleteInfos ...
 func (d *HistoryDB) DeleteInfos(link string) error {
 	return d.Update(func(tx *bolt.Tx) error {
@@ -123,6 +196,19 @@ func (d *HistoryDB) InsertInfo(link string, t time.Time, linkInfo LinkInfo) erro
 type LinkRequestData struct {
 	Location string
 	Referrer string
+	// Rule is an id of a matched destination rule, empty for default destination
+	Rule string
+	// Variant is an id of a split test variant, empty if link has no variants
+	Variant string
+	// Source is SourceQR for qr code scans, empty for direct clicks
+	Source string
+}
+
+func (info LinkRequestData) source() string {
+	if info.Source == "" {
+		return SourceDirect
+	}
+	return info.Source
 }
 
 // Insert ...
@@ -155,6 +241,15 @@ func (d *HistoryDB) Insert(link string, info LinkRequestData, r *http.Request) e
 			return err
 		}
 
+		totalsBucket, err := tx.CreateBucketIfNotExists([]byte("totals"))
+		if err != nil {
+			return err
+		}
+
+		if err := incrementCounter(totalsBucket, link); err != nil {
+			return err
+		}
+
 		uniqueBucket, err := tx.CreateBucketIfNotExists([]byte("unique:" + ipAddr + ":" + link))
 		if err != nil {
 			return err
@@ -173,6 +268,30 @@ func (d *HistoryDB) Insert(link string, info LinkRequestData, r *http.Request) e
 			return err
 		}
 
+		if info.Variant != "" {
+			variantsBucket, err := tx.CreateBucketIfNotExists([]byte("variants:" + link))
+			if err != nil {
+				return err
+			}
+			variantBucket, err := variantsBucket.CreateBucketIfNotExists([]byte(info.Variant))
+			if err != nil {
+				return err
+			}
+			if err := incrementTimeSeriesCounter(variantBucket); err != nil {
+				return err
+			}
+		}
+
+		if info.Source == SourceQR {
+			scansBucket, err := tx.CreateBucketIfNotExists([]byte("scans:" + link))
+			if err != nil {
+				return err
+			}
+			if err := incrementTimeSeriesCounter(scansBucket); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 
@@ -199,6 +318,159 @@ func (db *HistoryDB) InsertDetail(shortURL string, accountID int64) error {
 	})
 }
 
+// linkBucketPrefixes are prefixes of per link history buckets
+var linkBucketPrefixes = []string{"clicks:", "scans:", "info:", "variants:", "health:"}
+
+// RenameLink moves link details and click history to a new short url
+func (db *HistoryDB) RenameLink(oldShortURL, newShortURL string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+
+		details := tx.Bucket([]byte("details"))
+		if v := details.Get([]byte(oldShortURL)); v != nil {
+			if err := details.Put([]byte(newShortURL), v); err != nil {
+				return err
+			}
+			if err := details.Delete([]byte(oldShortURL)); err != nil {
+				return err
+			}
+		}
+
+		if totals := tx.Bucket([]byte("totals")); totals != nil {
+			if v := totals.Get([]byte(oldShortURL)); v != nil {
+				if err := totals.Put([]byte(newShortURL), v); err != nil {
+					return err
+				}
+				if err := totals.Delete([]byte(oldShortURL)); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, prefix := range linkBucketPrefixes {
+			src := tx.Bucket([]byte(prefix + oldShortURL))
+			if src == nil {
+				continue
+			}
+			dst, err := tx.CreateBucketIfNotExists([]byte(prefix + newShortURL))
+			if err != nil {
+				return err
+			}
+			if err := copyBucket(src, dst); err != nil {
+				return err
+			}
+			if err := tx.DeleteBucket([]byte(prefix + oldShortURL)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteLink removes link details and click history
+func (db *HistoryDB) DeleteLink(shortURL string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+
+		if err := tx.Bucket([]byte("details")).Delete([]byte(shortURL)); err != nil {
+			return err
+		}
+
+		if totals := tx.Bucket([]byte("totals")); totals != nil {
+			if err := totals.Delete([]byte(shortURL)); err != nil {
+				return err
+			}
+		}
+
+		for _, prefix := range linkBucketPrefixes {
+			err := tx.DeleteBucket([]byte(prefix + shortURL))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// copyBucket copies keys and nested buckets
+func copyBucket(src, dst *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nestedDst, err := dst.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(src.Bucket(k), nestedDst)
+	})
+}
+
+// DeleteVariants ...
+func (d *HistoryDB) DeleteVariants(link string) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("variants:" + link))
+	})
+}
+
+// InsertVariantClick ...
+func (d *HistoryDB) InsertVariantClick(link, variant string, t time.Time, counter int) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		variantsBucket, err := tx.CreateBucketIfNotExists([]byte("variants:" + link))
+		if err != nil {
+			return err
+		}
+		variantBucket, err := variantsBucket.CreateBucketIfNotExists([]byte(variant))
+		if err != nil {
+			return err
+		}
+		key := t.Format(time.RFC3339)
+		return variantBucket.Put([]byte(key), []byte(strconv.Itoa(counter)))
+	})
+}
+
+// GetVariantClicks returns daily clicks of each split test variant of the link
+func (d *HistoryDB) GetVariantClicks(link string) (map[string][]CounterData, error) {
+
+	result := make(map[string][]CounterData)
+
+	err := d.View(func(tx *bolt.Tx) error {
+
+		variantsBucket := tx.Bucket([]byte("variants:" + link))
+		if variantsBucket == nil {
+			return nil
+		}
+
+		return variantsBucket.ForEach(func(variant, _ []byte) error {
+			variantBucket := variantsBucket.Bucket(variant)
+			if variantBucket == nil {
+				return nil
+			}
+			return variantBucket.ForEach(func(k, v []byte) error {
+				timeK, err := time.Parse(time.RFC3339, string(k))
+				if err != nil {
+					return err
+				}
+				counterValue, err := strconv.ParseInt(string(v), 0, 64)
+				if err != nil {
+					return err
+				}
+				result[string(variant)] = append(result[string(variant)], CounterData{
+					Time:  timeK,
+					Count: counterValue,
+				})
+				return nil
+			})
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // CounterData ...
 type CounterData struct {
 	Time  time.Time
@@ -218,7 +490,9 @@ type HistoryQueryOption struct {
 
 type LinkStatistics struct {
 	Clicks []CounterData
-	Infos  []LinkInfoData
+	// Scans are clicks made by scanning link qr codes
+	Scans []CounterData
+	Infos []LinkInfoData
 }
 
 // Limit ...
@@ -255,6 +529,7 @@ func (db *HistoryDB) GetClicksData(accountID int64, link string, start, end time
 	}
 
 	var counters []CounterData
+	var scans []CounterData
 	var infos []LinkInfoData
 
 	err = db.View(func(tx *bolt.Tx) error {
@@ -296,6 +571,27 @@ func (db *HistoryDB) GetClicksData(accountID int64, link string, start, end time
 
 		db.Logger.Printf("history - fetched interval(%s, %s), found: %v", startKey, endKey, len(counters))
 
+		if scansBucket := tx.Bucket([]byte("scans:" + link)); scansBucket != nil {
+			c := scansBucket.Cursor()
+			for k, v := c.Seek([]byte(startKey)); k != nil && bytes.Compare(k, []byte(endKey)) <= 0; k, v = c.Next() {
+
+				timeK, err := time.Parse(time.RFC3339, string(k))
+				if err != nil {
+					return err
+				}
+
+				counterValue, err := strconv.ParseInt(string(v), 0, 64)
+				if err != nil {
+					return err
+				}
+
+				scans = append(scans, CounterData{
+					Time:  timeK,
+					Count: counterValue,
+				})
+			}
+		}
+
 		linkInfoBucket := tx.Bucket([]byte("info:" + link))
 
 		if linkInfoBucket == nil {
@@ -336,5 +632,5 @@ func (db *HistoryDB) GetClicksData(accountID int64, link string, start, end time
 		return nil, err
 	}
 
-	return &LinkStatistics{Clicks: counters, Infos: infos}, nil
+	return &LinkStatistics{Clicks: counters, Scans: scans, Infos: infos}, nil
 }
@@ -4,6 +4,14 @@ import (
 	"time"
 )
 
+// click sources
+const (
+	// SourceDirect clicks are made by following a short url
+	SourceDirect = "direct"
+	// SourceQR clicks are scans of link qr codes
+	SourceQR = "qr"
+)
+
 // Click ...
 type Click struct {
 	LinkID  int64
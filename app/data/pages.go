@@ -0,0 +1,72 @@
+package data
+
+import (
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func pageViewsBucket(pageID int64) []byte {
+	return []byte("pageviews:" + strconv.FormatInt(pageID, 10))
+}
+
+// InsertPageView increments a daily view counter of a link-in-bio page
+func (d *HistoryDB) InsertPageView(pageID int64) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(pageViewsBucket(pageID))
+		if err != nil {
+			return err
+		}
+		return incrementTimeSeriesCounter(bucket)
+	})
+}
+
+// GetPageViews returns daily views of a page between start and end dates
+func (d *HistoryDB) GetPageViews(pageID int64, start, end time.Time) ([]CounterData, error) {
+
+	list := make([]CounterData, 0)
+
+	err := d.View(func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(pageViewsBucket(pageID))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			day, err := time.Parse(time.RFC3339, string(k))
+			if err != nil {
+				return err
+			}
+			if day.Before(start) || day.After(end) {
+				continue
+			}
+			count, err := strconv.ParseInt(string(v), 0, 64)
+			if err != nil {
+				return err
+			}
+			list = append(list, CounterData{Time: day, Count: count})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// DeletePageViews ...
+func (d *HistoryDB) DeletePageViews(pageID int64) error {
+	return d.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(pageViewsBucket(pageID))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
@@ -0,0 +1,33 @@
+package domains
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidHost ...
+	ErrInvalidHost = errors.New("domain must be a valid host name, e.g. go.example.com")
+)
+
+var hostPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// NormalizeHost converts a request host or user input to the form stored in database:
+// lower case, without port and trailing dot
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// ValidateHost ...
+func ValidateHost(host string) error {
+	if len(host) > 253 || !hostPattern.MatchString(host) {
+		return ErrInvalidHost
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package domains
+
+import (
+	"time"
+)
+
+// Domain is a custom branded host registered by an account
+type Domain struct {
+	ID                int64
+	AccountID         int64
+	Host              string
+	VerificationToken string
+	Verified          bool
+	VerifiedAt        *time.Time
+	CreatedAt         time.Time
+}
@@ -0,0 +1,178 @@
+package domains
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDomainExists ...
+	ErrDomainExists = errors.New("domain is already registered")
+	// ErrDomainInUse ...
+	ErrDomainInUse = errors.New("domain has links and can not be deleted")
+)
+
+// Repository ...
+type Repository struct {
+	DB       *sql.DB
+	Resolver Resolver
+	Logger   *log.Logger
+}
+
+// LookupHost returns an id of a verified domain for request host, hosts are read from database
+// so domains verified or deleted by any instance are resolved at once
+func (r *Repository) LookupHost(host string) (int64, bool, error) {
+	var id int64
+	err := r.DB.QueryRow("select id from domains where host = $1 and verified = true", NormalizeHost(host)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+const domainFields = "id, account_id, host, verification_token, verified, verified_at, created_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDomain(row rowScanner, d *Domain) error {
+	return row.Scan(&d.ID, &d.AccountID, &d.Host, &d.VerificationToken, &d.Verified, &d.VerifiedAt, &d.CreatedAt)
+}
+
+// GetDomains ...
+func (r *Repository) GetDomains(accountID int64) ([]Domain, error) {
+
+	rows, err := r.DB.Query("select "+domainFields+" from domains where account_id = $1 order by host", accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Domain
+	for rows.Next() {
+		var d Domain
+		if err := scanDomain(rows, &d); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetDomain ...
+func (r *Repository) GetDomain(accountID, id int64) (*Domain, error) {
+	var d Domain
+	err := scanDomain(r.DB.QueryRow(
+		"select "+domainFields+" from domains where id = $1 and account_id = $2", id, accountID,
+	), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// CreateDomain registers an unverified domain with a new verification token,
+// a host may be claimed by several accounts until one of them verifies it
+func (r *Repository) CreateDomain(accountID int64, host string) (*Domain, error) {
+
+	host = NormalizeHost(host)
+	if err := ValidateHost(host); err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// hosts verified by any account can't be claimed, claims of one account are kept unique by domains_account_host_unique
+	d := Domain{AccountID: accountID, Host: host, VerificationToken: token}
+	err = r.DB.QueryRow(`
+		insert into domains (account_id, host, verification_token)
+		select $1, $2, $3 where not exists(select 1 from domains where host = $2 and verified = true)
+		returning id, created_at
+	`, accountID, host, token).Scan(&d.ID, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDomainExists
+	} else if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil, ErrDomainExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// VerifyDomain checks domain TXT record and marks domain as verified
+func (r *Repository) VerifyDomain(ctx context.Context, accountID, id int64) (*Domain, error) {
+
+	d, err := r.GetDomain(accountID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Verified {
+		return d, nil
+	}
+
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = NewResolver("")
+	}
+
+	if err := Verify(ctx, resolver, *d); err != nil {
+		return nil, err
+	}
+
+	// a host is verified by one account only, see domains_verified_host_idx
+	err = r.DB.QueryRow(`
+		update domains set verified = true, verified_at = now() where id = $1 and account_id = $2 returning verified_at
+	`, id, accountID).Scan(&d.VerifiedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil, ErrDomainExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	d.Verified = true
+
+	// claims of other accounts can't be verified anymore
+	if _, err := r.DB.Exec("delete from domains where host = $1 and id <> $2 and verified = false", d.Host, d.ID); err != nil {
+		r.Logger.Println("unverified domains cleanup error", err)
+	}
+
+	return d, nil
+}
+
+// DeleteDomain deletes a domain without links
+func (r *Repository) DeleteDomain(accountID, id int64) error {
+
+	var inUse bool
+	err := r.DB.QueryRow(
+		"select exists(select 1 from links where domain_id = $1 and account_id = $2)", id, accountID,
+	).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+
+	if inUse {
+		return ErrDomainInUse
+	}
+
+	return r.DB.QueryRow(
+		"delete from domains where id = $1 and account_id = $2 returning id", id, accountID,
+	).Scan(&id)
+}
@@ -0,0 +1,77 @@
+package domains
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+)
+
+const (
+	verificationRecordPrefix = "_shortly-verification."
+	verificationValuePrefix  = "shortly-verification="
+)
+
+var (
+	// ErrVerificationFailed ...
+	ErrVerificationFailed = errors.New("verification txt record not found")
+)
+
+// Resolver looks up dns TXT records, *net.Resolver satisfies it
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// NewResolver returns the system resolver or a resolver which sends
+// all queries to the provided dns server address (host:port)
+func NewResolver(server string) Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// VerificationRecord is a name of TXT record which proves domain ownership
+func (d Domain) VerificationRecord() string {
+	return verificationRecordPrefix + d.Host
+}
+
+// VerificationValue is an expected value of verification TXT record
+func (d Domain) VerificationValue() string {
+	return verificationValuePrefix + d.VerificationToken
+}
+
+// Verify checks that verification TXT record of domain contains the domain token
+func Verify(ctx context.Context, resolver Resolver, domain Domain) error {
+
+	records, err := resolver.LookupTXT(ctx, domain.VerificationRecord())
+	if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+		return ErrVerificationFailed
+	} else if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == domain.VerificationValue() {
+			return nil
+		}
+	}
+
+	return ErrVerificationFailed
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,183 @@
+package domains
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// fakeResolver is a local dns zone with TXT records only
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestVerify(t *testing.T) {
+
+	resolver := fakeResolver{
+		"_shortly-verification.go.acme.com":  {"v=spf1 -all", "shortly-verification=token1"},
+		"_shortly-verification.go.other.com": {"shortly-verification=token2"},
+	}
+
+	cases := []struct {
+		domain Domain
+		err    error
+	}{
+		{Domain{Host: "go.acme.com", VerificationToken: "token1"}, nil},
+		{Domain{Host: "go.other.com", VerificationToken: "token1"}, ErrVerificationFailed},
+		{Domain{Host: "go.unknown.com", VerificationToken: "token1"}, ErrVerificationFailed},
+	}
+
+	for _, c := range cases {
+		if err := Verify(context.Background(), resolver, c.domain); err != c.err {
+			t.Errorf("domain(%s): expected error %v, got %v", c.domain.Host, c.err, err)
+		}
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+
+	cases := []struct {
+		host     string
+		expected string
+		valid    bool
+	}{
+		{"go.acme.com", "go.acme.com", true},
+		{"Go.Acme.COM:8080", "go.acme.com", true},
+		{"go.acme.com.", "go.acme.com", true},
+		{"localhost", "localhost", false},
+		{"-go.acme.com", "-go.acme.com", false},
+		{"go_acme.com", "go_acme.com", false},
+	}
+
+	for _, c := range cases {
+		host := NormalizeHost(c.host)
+		if host != c.expected {
+			t.Errorf("host(%s): expected %s, got %s", c.host, c.expected, host)
+		}
+		if valid := ValidateHost(host) == nil; valid != c.valid {
+			t.Errorf("host(%s): expected valid = %v", c.host, c.valid)
+		}
+	}
+}
+
+func TestVerifyDomain(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	accountID := int64(10)
+	domainID := int64(3)
+
+	mock.ExpectQuery("select (.+) from domains").WithArgs(domainID, accountID).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "account_id", "host", "verification_token", "verified", "verified_at", "created_at"}).
+			AddRow(domainID, accountID, "go.acme.com", "token1", false, nil, time.Now()))
+	mock.ExpectQuery("update domains set verified = true").WithArgs(domainID, accountID).WillReturnRows(
+		sqlmock.NewRows([]string{"verified_at"}).AddRow(nil))
+	mock.ExpectExec("delete from domains where host = \\$1 and id <> \\$2 and verified = false").
+		WithArgs("go.acme.com", domainID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &Repository{
+		DB:       db,
+		Resolver: fakeResolver{"_shortly-verification.go.acme.com": {"shortly-verification=token1"}},
+	}
+
+	d, err := repo.VerifyDomain(context.Background(), accountID, domainID)
+	if err != nil {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+
+	if !d.Verified {
+		t.Errorf("domain must be verified")
+	}
+
+	// we make sure that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyDomainTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select (.+) from domains").WithArgs(int64(3), int64(10)).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "account_id", "host", "verification_token", "verified", "verified_at", "created_at"}).
+			AddRow(3, 10, "go.acme.com", "token1", false, nil, time.Now()))
+	mock.ExpectQuery("update domains set verified = true").WithArgs(int64(3), int64(10)).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	repo := &Repository{
+		DB:       db,
+		Resolver: fakeResolver{"_shortly-verification.go.acme.com": {"shortly-verification=token1"}},
+	}
+
+	if _, err := repo.VerifyDomain(context.Background(), 10, 3); err != ErrDomainExists {
+		t.Errorf("expected domain exists error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLookupHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select id from domains where host = \\$1 and verified = true").WithArgs("go.acme.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("select id from domains where host = \\$1 and verified = true").WithArgs("go.other.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	repo := &Repository{DB: db}
+
+	if id, ok, err := repo.LookupHost("GO.ACME.COM:443"); err != nil || !ok || id != 3 {
+		t.Errorf("verified host must be resolved to domain id 3, got %d (%v)", id, err)
+	}
+	if _, ok, err := repo.LookupHost("go.other.com"); err != nil || ok {
+		t.Errorf("unverified host must not be resolved (%v)", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateDomainVerifiedHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("insert into domains").WithArgs(int64(10), "go.acme.com", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	repo := &Repository{DB: db}
+
+	if _, err := repo.CreateDomain(10, "Go.Acme.com"); err != ErrDomainExists {
+		t.Errorf("expected domain exists error for a host verified by another account, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"shortly/app/importer"
+)
+
+var testColumns = []string{"id", "short_url", "tags", "hidden", "expires_at"}
+
+func writeTestRows(t *testing.T, format string) []byte {
+
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	var noExpiry *time.Time
+
+	if err := w.WriteRow(int64(1), "a<b&c", []string{"sale", "spring"}, true, &expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(int64(2), "second", []string(nil), false, noExpiry); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+
+	out := string(writeTestRows(t, FormatCSV))
+	expected := "id,short_url,tags,hidden,expires_at\n" +
+		"1,a<b&c,sale;spring,true,2030-01-02T03:04:05Z\n" +
+		"2,second,,false,\n"
+
+	if out != expected {
+		t.Errorf("unexpected csv:\n%s", out)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+
+	lines := strings.Split(strings.TrimSpace(string(writeTestRows(t, FormatNDJSON))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["id"] != float64(1) || first["short_url"] != "a<b&c" || first["hidden"] != true ||
+		first["expires_at"] != "2030-01-02T03:04:05Z" || len(first["tags"].([]interface{})) != 2 {
+		t.Errorf("unexpected object %v", first)
+	}
+
+	var second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if second["expires_at"] != nil || second["tags"] != nil {
+		t.Errorf("unexpected object %v", second)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+
+	records, err := importer.ReadXLSX(writeTestRows(t, FormatXLSX))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	if strings.Join(records[0], ",") != strings.Join(testColumns, ",") {
+		t.Errorf("unexpected header %v", records[0])
+	}
+
+	if got := strings.Join(records[1], ","); got != "1,a<b&c,sale;spring,1,2030-01-02T03:04:05Z" {
+		t.Errorf("unexpected first row %s", got)
+	}
+
+	if got := strings.Join(records[2], ","); got != "2,second,,0" && got != "2,second,,0," {
+		t.Errorf("unexpected second row %s", got)
+	}
+
+	// inline strings are not evaluated, so values starting with a formula character are kept as is
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf, []string{"description"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow("-50% off"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err = importer.ReadXLSX(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || len(records[1]) != 1 || records[1][0] != "-50% off" {
+		t.Errorf("unexpected records %v", records)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+
+	tests := []struct {
+		index    int
+		expected string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, test := range tests {
+		if got := columnName(test.index); got != test.expected {
+			t.Errorf("columnName(%d) = %s, expected %s", test.index, got, test.expected)
+		}
+	}
+}
+
+func TestNewWriterInvalidFormat(t *testing.T) {
+	if _, err := NewWriter("pdf", &bytes.Buffer{}, testColumns); err != ErrInvalidFormat {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
+func TestSafeText(t *testing.T) {
+
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"=HYPERLINK(\"https://evil.example\")", "'=HYPERLINK(\"https://evil.example\")"},
+		{"+cmd|' /C calc'!A0", "'+cmd|' /C calc'!A0"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tvalue", "'\tvalue"},
+		{[]string{"=tag", "sale"}, "'=tag;sale"},
+		{"https://example.com", "https://example.com"},
+		{int64(-5), "-5"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := safeText(test.value); got != test.expected {
+			t.Errorf("safeText(%q) = %q, expected %q", test.value, got, test.expected)
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf, []string{"description"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow("=1+1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "description\n'=1+1\n" {
+		t.Errorf("unexpected csv:\n%s", buf.String())
+	}
+}
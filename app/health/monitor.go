@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"shortly/app/data"
+	"shortly/app/links"
+	"shortly/utils"
+)
+
+const (
+	// monitorBatch is a number of links read for probes at once
+	monitorBatch = 500
+	// monitorPause is a pause between runs over links with stale probes
+	monitorPause = time.Minute
+)
+
+// BrokenLinkPayload is sent with link__broken webhook
+type BrokenLinkPayload struct {
+	ID         int64  `json:"id"`
+	Short      string `json:"short"`
+	Long       string `json:"long"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	FinalURL   string `json:"finalUrl,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Monitor periodically probes link destinations and records results in history
+type Monitor struct {
+	Links   *links.LinksRepository
+	History *data.HistoryDB
+	Prober  *Prober
+	// Interval is a period of probes of each link
+	Interval time.Duration
+	Timeout  time.Duration
+	Workers  int
+	// OnBroken is called when a destination of a link becomes broken
+	OnBroken func(accountID int64, payload interface{})
+	Logger   *log.Logger
+}
+
+// Start ...
+func (m *Monitor) Start() {
+
+	go func() {
+		for {
+			m.Run()
+			time.Sleep(monitorPause)
+		}
+	}()
+}
+
+// Run probes all links with stale probes, links of hosts in backoff wait for next run
+func (m *Monitor) Run() {
+
+	offset := 0
+
+	for {
+		list, err := m.Links.GetLinksToProbe(utils.Now().Add(-m.Interval), monitorBatch, offset)
+		if err != nil {
+			m.Logger.Println("links health fetch error", err)
+			return
+		}
+
+		// postponed links stay in the result of the next fetch and are skipped
+		offset += m.probeLinks(list)
+
+		if len(list) < monitorBatch {
+			return
+		}
+	}
+}
+
+// probeLinks returns a number of postponed links
+func (m *Monitor) probeLinks(list []links.Link) int {
+
+	workers := m.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan links.Link)
+
+	var mu sync.Mutex
+	var postponed int
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				if !m.probeLink(link) {
+					mu.Lock()
+					postponed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, link := range list {
+		jobs <- link
+	}
+	close(jobs)
+
+	wg.Wait()
+	return postponed
+}
+
+// probeLink returns false when a probe is postponed or its result is not stored,
+// such links are skipped by next fetches of the run
+func (m *Monitor) probeLink(link links.Link) bool {
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.Timeout)
+	check, ok := m.Prober.Probe(ctx, link.Long)
+	cancel()
+
+	if !ok {
+		return false
+	}
+
+	if err := m.History.InsertHealthCheck(link.Key(), check); err != nil {
+		m.Logger.Println("link health history error", err)
+	}
+
+	broken := check.Broken()
+
+	if err := m.Links.UpdateLinkHealth(link.ID, check.StatusCode, broken); err != nil {
+		m.Logger.Println("link health update error", err)
+		return false
+	}
+
+	if broken && !link.Broken {
+		m.Logger.Printf("link is broken, short=%v, status=%v, error=%v\n", link.Key(), check.StatusCode, check.Error)
+		if m.OnBroken != nil {
+			m.OnBroken(link.AccountID, &BrokenLinkPayload{
+				ID:         link.ID,
+				Short:      link.Key(),
+				Long:       link.Long,
+				StatusCode: check.StatusCode,
+				FinalURL:   check.FinalURL,
+				Error:      check.Error,
+			})
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,155 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"shortly/app/data"
+	"shortly/app/urls"
+)
+
+// Prober requests link destinations, requests to the same host are limited
+// and hosts which fail or throttle requests are paused with exponential backoff
+type Prober struct {
+	Client *http.Client
+	// HostConcurrency is a number of simultaneous requests to a host
+	HostConcurrency int
+	// Backoff is a pause after the first failure of a host, it doubles up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	once  sync.Once
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState ...
+type hostState struct {
+	slots    chan struct{}
+	failures int
+	until    time.Time
+}
+
+// acquire takes a request slot of a host, hosts in backoff are not available
+func (p *Prober) acquire(host string) (func(failed bool), bool) {
+
+	p.once.Do(func() {
+		p.hosts = make(map[string]*hostState)
+	})
+
+	p.mu.Lock()
+	state, ok := p.hosts[host]
+	if !ok {
+		concurrency := p.HostConcurrency
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+		state = &hostState{slots: make(chan struct{}, concurrency)}
+		p.hosts[host] = state
+	}
+	if time.Now().Before(state.until) {
+		p.mu.Unlock()
+		return nil, false
+	}
+	p.mu.Unlock()
+
+	state.slots <- struct{}{}
+
+	// a host may fail while the probe waits for a slot
+	p.mu.Lock()
+	paused := time.Now().Before(state.until)
+	p.mu.Unlock()
+	if paused {
+		<-state.slots
+		return nil, false
+	}
+
+	return func(failed bool) {
+		p.mu.Lock()
+		if failed {
+			state.failures++
+			state.until = time.Now().Add(p.backoff(state.failures))
+		} else {
+			state.failures = 0
+			state.until = time.Time{}
+		}
+		p.mu.Unlock()
+		<-state.slots
+	}, true
+}
+
+func (p *Prober) backoff(failures int) time.Duration {
+	pause := p.Backoff
+	for i := 1; i < failures && pause < p.MaxBackoff; i++ {
+		pause *= 2
+	}
+	if p.MaxBackoff > 0 && pause > p.MaxBackoff {
+		pause = p.MaxBackoff
+	}
+	return pause
+}
+
+// Probe requests a destination with HEAD and falls back to GET for servers not supporting HEAD,
+// ok is false when the destination host is in backoff and the probe is postponed
+func (p *Prober) Probe(ctx context.Context, destination string) (data.HealthCheck, bool) {
+
+	if urls.Scheme(destination) == "" {
+		destination = "https://" + destination
+	}
+
+	check := data.HealthCheck{Time: time.Now()}
+
+	u, err := url.Parse(destination)
+	if err != nil || u.Host == "" {
+		check.Error = "destination url is not valid"
+		return check, true
+	}
+
+	release, ok := p.acquire(strings.ToLower(u.Host))
+	if !ok {
+		return check, false
+	}
+
+	start := time.Now()
+
+	resp, err := p.request(ctx, http.MethodHead, u.String())
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = p.request(ctx, http.MethodGet, u.String())
+	}
+
+	check.LatencyMs = time.Since(start).Nanoseconds() / int64(time.Millisecond)
+
+	if err != nil {
+		check.Error = err.Error()
+		release(true)
+		return check, true
+	}
+
+	check.StatusCode = resp.StatusCode
+	check.FinalURL = resp.Request.URL.String()
+
+	release(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+	return check, true
+}
+
+func (p *Prober) request(ctx context.Context, method, destination string) (*http.Response, error) {
+
+	req, err := http.NewRequest(method, destination, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "shortly-health-monitor")
+
+	resp, err := p.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// only a status is required, a body is not read
+	_ = resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/get-only":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	prober := &Prober{Client: server.Client(), HostConcurrency: 2}
+
+	tests := []struct {
+		path       string
+		statusCode int
+		finalPath  string
+		broken     bool
+	}{
+		{"/ok", http.StatusOK, "/ok", false},
+		{"/missing", http.StatusNotFound, "/missing", true},
+		{"/get-only", http.StatusOK, "/get-only", false},
+		{"/moved", http.StatusOK, "/ok", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			check, ok := prober.Probe(context.Background(), server.URL+tt.path)
+			if !ok {
+				t.Fatal("probe must not be postponed")
+			}
+			if check.StatusCode != tt.statusCode {
+				t.Errorf("expected status %d, got %d", tt.statusCode, check.StatusCode)
+			}
+			if check.FinalURL != server.URL+tt.finalPath {
+				t.Errorf("expected final url %s, got %s", server.URL+tt.finalPath, check.FinalURL)
+			}
+			if check.Broken() != tt.broken {
+				t.Errorf("expected broken %v, got %v", tt.broken, check.Broken())
+			}
+		})
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	destination := server.URL
+	server.Close()
+
+	prober := &Prober{Client: &http.Client{Timeout: time.Second}, Backoff: time.Minute}
+
+	check, ok := prober.Probe(context.Background(), destination)
+	if !ok || check.Error == "" || !check.Broken() {
+		t.Errorf("unreachable destination must be broken, got %+v", check)
+	}
+
+	if _, ok := prober.Probe(context.Background(), destination); ok {
+		t.Errorf("failed host must be in backoff")
+	}
+}
+
+func TestProbeHostConcurrency(t *testing.T) {
+
+	var active, maxActive int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+	}))
+	defer server.Close()
+
+	prober := &Prober{Client: server.Client(), HostConcurrency: 2}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prober.Probe(context.Background(), server.URL)
+		}()
+	}
+	wg.Wait()
+
+	if maxActive > 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", maxActive)
+	}
+}
+
+func TestProbeBackoff(t *testing.T) {
+
+	var status int32 = http.StatusServiceUnavailable
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	prober := &Prober{Client: server.Client(), Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+
+	check, ok := prober.Probe(context.Background(), server.URL)
+	if !ok || !check.Broken() {
+		t.Fatalf("expected broken destination, got %+v", check)
+	}
+
+	if _, ok := prober.Probe(context.Background(), server.URL); ok {
+		t.Fatal("host must be in backoff after a server error")
+	}
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	time.Sleep(60 * time.Millisecond)
+
+	check, ok = prober.Probe(context.Background(), server.URL)
+	if !ok || check.Broken() {
+		t.Errorf("expected healthy destination after backoff, got %+v", check)
+	}
+
+	if prober.backoff(1) != 50*time.Millisecond || prober.backoff(3) != 200*time.Millisecond || prober.backoff(10) != time.Second {
+		t.Errorf("unexpected backoff durations")
+	}
+}
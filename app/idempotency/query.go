@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"shortly/utils"
+)
+
+var (
+	// ErrInProgress is returned for a key of a request which is still processed
+	ErrInProgress = errors.New("request with the idempotency key is in progress")
+	// ErrKeyMismatch is returned for a key reused with a different request
+	ErrKeyMismatch = errors.New("idempotency key is already used with a different request")
+)
+
+// MaxKeyLength ...
+const MaxKeyLength = 255
+
+// Record is a stored response of a request made with an idempotency key
+type Record struct {
+	StatusCode int
+	Response   []byte
+	CreatedAt  time.Time
+}
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+// Reserve marks a key as taken by a request, nil record is returned for a new key,
+// a stored record is returned for a completed request with the same hash
+func (r *Repository) Reserve(accountID int64, key, requestHash string, window time.Duration) (*Record, error) {
+
+	expiredBefore := utils.Now().Add(-window)
+
+	// keys of an account outside of the window are free to reuse
+	if _, err := r.DB.Exec(
+		"delete from idempotency_keys where account_id = $1 and created_at < $2", accountID, expiredBefore,
+	); err != nil {
+		return nil, err
+	}
+
+	res, err := r.DB.Exec(`
+		insert into idempotency_keys (account_id, key, request_hash, created_at) values ($1, $2, $3, now())
+		on conflict (account_id, key) do nothing`,
+		accountID, key, requestHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, nil
+	}
+
+	var record Record
+	var hash string
+	err = r.DB.QueryRow(`
+		select request_hash, status_code, coalesce(response, ''), created_at from idempotency_keys where account_id = $1 and key = $2`,
+		accountID, key,
+	).Scan(&hash, &record.StatusCode, &record.Response, &record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != requestHash {
+		return nil, ErrKeyMismatch
+	}
+	if record.StatusCode == 0 {
+		return nil, ErrInProgress
+	}
+
+	return &record, nil
+}
+
+// Complete stores a response of a request to replay it for retries
+func (r *Repository) Complete(accountID int64, key string, statusCode int, response []byte) error {
+	_, err := r.DB.Exec(
+		"update idempotency_keys set status_code = $1, response = $2 where account_id = $3 and key = $4",
+		statusCode, response, accountID, key,
+	)
+	return err
+}
+
+// Release removes a key of a failed request so it may be retried
+func (r *Repository) Release(accountID int64, key string) error {
+	_, err := r.DB.Exec("delete from idempotency_keys where account_id = $1 and key = $2", accountID, key)
+	return err
+}
@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReserve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	accountID, key := int64(2), "retry-1"
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expectReserve := func(inserted int64) {
+		mock.ExpectExec("delete from idempotency_keys where account_id = \\$1 and created_at < \\$2").
+			WithArgs(accountID, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("insert into idempotency_keys").WithArgs(accountID, key, "hash").
+			WillReturnResult(sqlmock.NewResult(0, inserted))
+	}
+
+	// new key
+	expectReserve(1)
+
+	// request in progress
+	expectReserve(0)
+	mock.ExpectQuery("select request_hash").WithArgs(accountID, key).WillReturnRows(
+		sqlmock.NewRows([]string{"request_hash", "status_code", "response", "created_at"}).AddRow("hash", 0, []byte{}, created))
+
+	// completed request
+	expectReserve(0)
+	mock.ExpectQuery("select request_hash").WithArgs(accountID, key).WillReturnRows(
+		sqlmock.NewRows([]string{"request_hash", "status_code", "response", "created_at"}).AddRow("hash", 200, []byte(`{"result":1}`), created))
+
+	// different request with the same key
+	expectReserve(0)
+	mock.ExpectQuery("select request_hash").WithArgs(accountID, key).WillReturnRows(
+		sqlmock.NewRows([]string{"request_hash", "status_code", "response", "created_at"}).AddRow("other", 200, []byte{}, created))
+
+	repo := &Repository{DB: db}
+
+	if record, err := repo.Reserve(accountID, key, "hash", time.Hour); record != nil || err != nil {
+		t.Errorf("expected reserved key, got %v, %v", record, err)
+	}
+
+	if _, err := repo.Reserve(accountID, key, "hash", time.Hour); err != ErrInProgress {
+		t.Errorf("expected in progress error, got %v", err)
+	}
+
+	record, err := repo.Reserve(accountID, key, "hash", time.Hour)
+	if err != nil || record == nil || record.StatusCode != 200 || string(record.Response) != `{"result":1}` {
+		t.Errorf("expected stored response, got %+v, %v", record, err)
+	}
+
+	if _, err := repo.Reserve(accountID, key, "hash", time.Hour); err != ErrKeyMismatch {
+		t.Errorf("expected key mismatch error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
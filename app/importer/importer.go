@@ -0,0 +1,352 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shortly/app/accounts"
+	"shortly/app/billing"
+	"shortly/app/bulk"
+	"shortly/app/data"
+	"shortly/app/links"
+	"shortly/app/safety"
+	"shortly/app/tags"
+	"shortly/cache"
+	"shortly/utils"
+)
+
+const (
+	// progressStep is a number of imported rows between job progress updates
+	progressStep = 100
+	// checkTimeout limits a safety check of a single row
+	checkTimeout = 10 * time.Second
+	// checkWorkers is a number of rows checked concurrently
+	checkWorkers = 8
+)
+
+var (
+	// ErrDuplicateSlug ...
+	ErrDuplicateSlug = errors.New("short url is duplicated in file")
+	// ErrGroupNotFound ...
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrPlanLimit ...
+	ErrPlanLimit = errors.New("plan limit exceeded")
+	// ErrExpiryInPast ...
+	ErrExpiryInPast = errors.New("expiry date must be in future")
+	// ErrTagsNotAdded ...
+	ErrTagsNotAdded = errors.New("link is created but its tags are not added")
+	// ErrGroupNotAdded ...
+	ErrGroupNotAdded = errors.New("link is created but it is not added to the group")
+
+	errInternal = errors.New("internal error")
+)
+
+// Importer validates rows of import files and creates links of valid rows in background jobs
+type Importer struct {
+	Jobs     *bulk.Repository
+	Links    *links.LinksRepository
+	Tags     *tags.TagsRepository
+	Users    *accounts.UsersRepository
+	Billing  *billing.BillingLimiter
+	Checker  *safety.Checker
+	History  *data.HistoryDB
+	URLCache cache.UrlCache
+	Logger   *log.Logger
+}
+
+// Validate checks rows against account links, groups, plan limit and destinations blocklist without creating links,
+// Err is set for invalid rows and group ids are returned for valid rows with a group.
+// Destination redirects are followed by the import only, so a redirect to a blocked url is not reported here
+func (im *Importer) Validate(accountID int64, rows []Row) (map[int]int64, error) {
+
+	groups, err := im.Users.GetAccountGroups(accountID)
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := make(map[string]int64, len(groups))
+	for _, group := range groups {
+		groupIDs[strings.ToLower(group.Name)] = group.ID
+	}
+
+	remaining, err := im.remainingLinks(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowGroups := make(map[int]int64)
+	slugs := make(map[string]bool)
+	now := utils.Now()
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Err != nil {
+			continue
+		}
+
+		if row.ExpiresAt != nil && !row.ExpiresAt.After(now) {
+			row.Err = ErrExpiryInPast
+			continue
+		}
+
+		if result := im.Checker.CheckLocal(row.Long); result.Status == links.SafetyBlocked {
+			row.Err = blockedError(result)
+			continue
+		}
+
+		if row.Slug != "" {
+			if slugs[row.Slug] {
+				row.Err = ErrDuplicateSlug
+				continue
+			}
+			slugs[row.Slug] = true
+
+			if err := im.Links.CheckSlug(accountID, 0, row.Slug); links.IsSlugError(err) {
+				row.Err = err
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		if row.Group != "" {
+			groupID, ok := groupIDs[strings.ToLower(row.Group)]
+			if !ok {
+				row.Err = ErrGroupNotFound
+				continue
+			}
+			rowGroups[row.Line] = groupID
+		}
+
+		if remaining <= 0 {
+			row.Err = ErrPlanLimit
+			continue
+		}
+		remaining--
+	}
+
+	return rowGroups, nil
+}
+
+func (im *Importer) remainingLinks(accountID int64) (int64, error) {
+	option, err := im.Billing.GetOptionValue("url_limit", accountID)
+	if err == billing.OptionNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(option.Value, 0, 64)
+}
+
+// Report returns per row results of validated rows
+func Report(rows []Row) []bulk.ItemResult {
+	results := make([]bulk.ItemResult, 0, len(rows))
+	for _, row := range rows {
+		result := bulk.ItemResult{Row: row.Line, Short: row.Slug, OK: row.Err == nil}
+		if row.Err != nil {
+			result.Error = row.Err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Start creates an import job, rows are validated and valid rows are created in background
+func (im *Importer) Start(accountID, userID int64, rows []Row) (*bulk.Job, error) {
+
+	job := &bulk.Job{
+		AccountID: accountID,
+		UserID:    userID,
+		Action:    bulk.ActionImport,
+		Status:    bulk.StatusRunning,
+		Total:     len(rows),
+	}
+
+	if err := im.Jobs.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	go im.run(*job, rows)
+
+	return job, nil
+}
+
+func (im *Importer) run(job bulk.Job, rows []Row) {
+
+	rowGroups, err := im.Validate(job.AccountID, rows)
+	if err != nil {
+		im.Logger.Printf("import job(%v) validation error: %v\n", job.ID, err)
+		job.Status = bulk.StatusFailed
+		job.Results = []bulk.ItemResult{{Error: "internal error"}}
+		if err := im.Jobs.FinishJob(&job); err != nil {
+			im.Logger.Println("import job finish error", err)
+		}
+		return
+	}
+
+	// rows are checked concurrently by chunks and created in file order
+	var checks []safety.Result
+	for i := range rows {
+		row := &rows[i]
+
+		if i%progressStep == 0 {
+			end := i + progressStep
+			if end > len(rows) {
+				end = len(rows)
+			}
+			checks = im.check(rows[i:end])
+		}
+
+		var linkID int64
+		if row.Err == nil {
+			linkID, row.Slug, row.Err = im.create(job.AccountID, *row, rowGroups[row.Line], checks[i%progressStep])
+		}
+
+		result := bulk.ItemResult{Row: row.Line, LinkID: linkID, Short: row.Slug, OK: row.Err == nil}
+		if row.Err != nil {
+			result.Error = row.Err.Error()
+			job.Failed++
+		}
+		job.Results = append(job.Results, result)
+		job.Processed++
+
+		if job.Processed%progressStep == 0 {
+			if err := im.Jobs.UpdateProgress(job.ID, job.Processed, job.Failed); err != nil {
+				im.Logger.Println("import job progress error", err)
+			}
+		}
+	}
+
+	job.Status = bulk.StatusFinished
+	if job.Failed > 0 && job.Failed == job.Total {
+		job.Status = bulk.StatusFailed
+	}
+	finishedAt := utils.Now()
+	job.FinishedAt = &finishedAt
+
+	if err := im.Jobs.FinishJob(&job); err != nil {
+		im.Logger.Println("import job finish error", err)
+	}
+}
+
+// check runs safety checks of valid rows destinations with checkWorkers workers,
+// results are ordered as rows
+func (im *Importer) check(rows []Row) []safety.Result {
+
+	results := make([]safety.Result, len(rows))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < checkWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+				results[i] = im.Checker.CheckLink(ctx, links.Link{Long: rows[i].Long})
+				cancel()
+			}
+		}()
+	}
+
+	for i := range rows {
+		if rows[i].Err == nil {
+			jobs <- i
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+	return results
+}
+
+// create stores a link of a valid row the same way as link create api, row errors are returned for
+// unsafe destinations, slugs taken during import, exhausted plan limit and failed tags or group of a created link
+func (im *Importer) create(accountID int64, row Row, groupID int64, result safety.Result) (int64, string, error) {
+
+	link := &links.Link{
+		AccountID:   accountID,
+		Short:       row.Slug,
+		Long:        row.Long,
+		Description: row.Description,
+		ExpiresAt:   row.ExpiresAt,
+		CreatedAt:   utils.Now(),
+	}
+
+	if result.Status == links.SafetyBlocked {
+		return 0, link.Short, blockedError(result)
+	}
+	link.SafetyScore = result.Score
+	link.SafetyStatus = result.Status
+	link.SafetyReasons = result.Reasons
+
+	linkID, err := im.store(accountID, link)
+	if err == ErrPlanLimit || err == links.ErrSlugConflict {
+		return 0, link.Short, err
+	} else if err != nil {
+		im.Logger.Printf("import link(%v) error: %v\n", link.Short, err)
+		return 0, link.Short, errInternal
+	}
+
+	links.StoreCache(im.URLCache, *link)
+
+	// the link is kept when tags or group fail, its row reports an error with the link id
+	for _, tag := range row.Tags {
+		if _, err := im.Tags.AddTagToLink(linkID, tag); err != nil {
+			im.Logger.Printf("import link(%v) tag error: %v\n", linkID, err)
+			return linkID, link.Short, ErrTagsNotAdded
+		}
+	}
+
+	if groupID > 0 {
+		if err := im.Links.AddUrlToGroup(groupID, linkID); err != nil {
+			im.Logger.Printf("import link(%v) group error: %v\n", linkID, err)
+			return linkID, link.Short, ErrGroupNotAdded
+		}
+	}
+
+	return linkID, link.Short, nil
+}
+
+func blockedError(result safety.Result) error {
+	return errors.New("destination url is blocked: " + strings.Join(result.Reasons, "; "))
+}
+
+func (im *Importer) store(accountID int64, link *links.Link) (int64, error) {
+
+	lock := im.Billing.Lock(accountID)
+	defer lock.Unlock()
+
+	if remaining, err := im.remainingLinks(accountID); err != nil {
+		return 0, err
+	} else if remaining <= 0 {
+		return 0, ErrPlanLimit
+	}
+
+	tx, linkID, err := im.Links.CreateUserLink(accountID, link)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := im.Billing.Reduce("url_limit", accountID); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	if err := im.History.InsertDetail(link.Key(), accountID); err != nil {
+		_ = tx.Rollback()
+		_ = im.Billing.Reset("url_limit", accountID)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = im.Billing.Reset("url_limit", accountID)
+		return 0, err
+	}
+
+	return linkID, nil
+}
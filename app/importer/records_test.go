@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"shortly/app/urls"
+)
+
+func TestParseRowsCSV(t *testing.T) {
+
+	data := []byte("Long URL,Slug,Description,Tags,Group,Expires At\n" +
+		"https://example.com/a,spring-sale,Spring sale,sale;spring,Marketing,2030-01-02\n" +
+		"example.com/b,,,,,\n" +
+		",,,,,\n" +
+		"not a url,,,,,\n" +
+		"https://example.com/c,,,,,tomorrow\n")
+
+	records, err := ReadRecords("links.csv", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ParseRows(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+
+	first := rows[0]
+	if first.Line != 2 || first.Long != "https://example.com/a" || first.Slug != "spring-sale" || first.Group != "Marketing" ||
+		len(first.Tags) != 2 || first.ExpiresAt == nil || first.ExpiresAt.Year() != 2030 || first.Err != nil {
+		t.Errorf("unexpected row %+v", first)
+	}
+
+	if rows[1].Long != "https://example.com/b" || rows[1].Err != nil {
+		t.Errorf("url without scheme must be valid, got %+v", rows[1])
+	}
+
+	if rows[2].Line != 5 || rows[2].Err != urls.ErrInvalid {
+		t.Errorf("expected invalid url on line 5, got %+v", rows[2])
+	}
+
+	if rows[3].Err != ErrInvalidExpiry {
+		t.Errorf("expected invalid expiry, got %+v", rows[3])
+	}
+}
+
+func TestParseRowsWithoutHeader(t *testing.T) {
+
+	records, err := ReadCSV([]byte("https://example.com/a;ignored\nhttps://example.com/b;ignored\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ParseRows(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Line != 1 || rows[1].Long != "https://example.com/b" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+
+	if _, err := ParseRows([][]string{{"slug", "description"}}); err != ErrNoURLColumn {
+		t.Errorf("expected no url column error, got %v", err)
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Links" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>url</t></si><si><t>expires</t></si><si><r><t>https://example.com/</t></r><r><t>a</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+			<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>47119.5</v></c></row>
+			<row r="3"><c r="A3" t="inlineStr"><is><t>https://example.com/b</t></is></c></row>
+			</sheetData></worksheet>`,
+	}
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadRecords("links.XLSX", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ParseRows(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Long != "https://example.com/a" || rows[1].Long != "https://example.com/b" {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+
+	expected := time.Date(2029, 1, 1, 12, 0, 0, 0, time.UTC)
+	if rows[0].ExpiresAt == nil || !rows[0].ExpiresAt.Equal(expected) {
+		t.Errorf("expected expiry %v, got %v", expected, rows[0].ExpiresAt)
+	}
+
+	if _, err := ReadRecords("links.xlsx", []byte("url\n")); err != ErrInvalidXLSX {
+		t.Errorf("expected invalid xlsx error, got %v", err)
+	}
+}
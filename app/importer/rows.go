@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"shortly/app/urls"
+)
+
+// import columns
+const (
+	ColumnURL         = "url"
+	ColumnSlug        = "slug"
+	ColumnDescription = "description"
+	ColumnTags        = "tags"
+	ColumnGroup       = "group"
+	ColumnExpiresAt   = "expires_at"
+)
+
+// columnAliases maps header names to import columns
+var columnAliases = map[string]string{
+	"url":         ColumnURL,
+	"long_url":    ColumnURL,
+	"long":        ColumnURL,
+	"destination": ColumnURL,
+	"slug":        ColumnSlug,
+	"short":       ColumnSlug,
+	"short_url":   ColumnSlug,
+	"custom_slug": ColumnSlug,
+	"description": ColumnDescription,
+	"tags":        ColumnTags,
+	"group":       ColumnGroup,
+	"expires_at":  ColumnExpiresAt,
+	"expires":     ColumnExpiresAt,
+	"expiry":      ColumnExpiresAt,
+}
+
+var (
+	// ErrNoURLColumn ...
+	ErrNoURLColumn = errors.New("header has no url column")
+	// ErrInvalidExpiry ...
+	ErrInvalidExpiry = errors.New("invalid expiry date")
+)
+
+// expiryLayouts are accepted formats of expiry dates
+var expiryLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// Row is a link read from an import file, Err is set for rows which must not be imported
+type Row struct {
+	// Line is a number of the row in a file starting from 1
+	Line        int
+	Long        string
+	Slug        string
+	Description string
+	Tags        []string
+	Group       string
+	ExpiresAt   *time.Time
+	Err         error
+}
+
+// ParseRows maps file columns by a header row, files without a known header are read as a single url column
+func ParseRows(records [][]string) ([]Row, error) {
+
+	if len(records) == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	columns := map[string]int{ColumnURL: 0}
+	start := 0
+
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		key := strings.ToLower(strings.Replace(strings.TrimSpace(name), " ", "_", -1))
+		if column, ok := columnAliases[key]; ok {
+			if _, exists := header[column]; !exists {
+				header[column] = i
+			}
+		}
+	}
+	if len(header) > 0 {
+		if _, ok := header[ColumnURL]; !ok {
+			return nil, ErrNoURLColumn
+		}
+		columns = header
+		start = 1
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []Row
+	for i, record := range records[start:] {
+		row := Row{
+			Line:        start + i + 1,
+			Long:        value(record, ColumnURL),
+			Slug:        value(record, ColumnSlug),
+			Description: value(record, ColumnDescription),
+			Group:       value(record, ColumnGroup),
+			Tags:        splitTags(value(record, ColumnTags)),
+		}
+
+		// blank lines are skipped
+		if row.Long == "" && row.Slug == "" && row.Description == "" {
+			continue
+		}
+
+		row.Long, row.Err = validateURL(row.Long)
+
+		if expiry := value(record, ColumnExpiresAt); expiry != "" && row.Err == nil {
+			row.ExpiresAt, row.Err = parseExpiry(expiry)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyFile
+	}
+	return rows, nil
+}
+
+// splitTags reads tags separated by commas, semicolons or vertical bars
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == '|' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// validateURL canonicalizes a destination the same way as the link create api
+func validateURL(value string) (string, error) {
+	canonical, err := urls.Canonicalize(value, urls.Destination)
+	if err != nil {
+		return value, err
+	}
+	return canonical, nil
+}
+
+// parseExpiry reads dates as text or as xlsx serial numbers
+func parseExpiry(value string) (*time.Time, error) {
+	for _, layout := range expiryLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		days, fraction := math.Modf(serial)
+		t := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).
+			AddDate(0, 0, int(days)).
+			Add(time.Duration(math.Round(fraction*86400)) * time.Second)
+		return &t, nil
+	}
+	return nil, ErrInvalidExpiry
+}
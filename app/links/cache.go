@@ -0,0 +1,146 @@
+package links
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"shortly/cache"
+	"shortly/utils"
+)
+
+// CachedLink is a link representation stored in url cache,
+// it holds everything required to serve a redirect without database queries
+type CachedLink struct {
+	Long        string     `json:"long"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks   int64      `json:"maxClicks,omitempty"`
+	FallbackURL string     `json:"fallbackUrl,omitempty"`
+	Protected   bool       `json:"protected,omitempty"`
+	// RedirectType is resolved with account default before caching
+	RedirectType   int       `json:"redirectType,omitempty"`
+	Rules          []Rule    `json:"rules,omitempty"`
+	Variants       []Variant `json:"variants,omitempty"`
+	StickyVariants bool      `json:"stickyVariants,omitempty"`
+	// Description and CreatedAt are shown by link preview
+	Description string     `json:"description,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UTM         *UTM       `json:"utm,omitempty"`
+	// SafetyStatus of quarantined and blocked links disables redirects
+	SafetyStatus string `json:"safetyStatus,omitempty"`
+	// Pixels are fired by an interstitial page before a redirect
+	Pixels     []Pixel `json:"pixels,omitempty"`
+	PixelDelay int     `json:"pixelDelay,omitempty"`
+}
+
+// LinkKey identifies a link in url cache and click history,
+// links of the default domain are identified by short url only
+func LinkKey(domain, shortURL string) string {
+	if domain == "" {
+		return shortURL
+	}
+	return domain + ":" + shortURL
+}
+
+// Key ...
+func (l Link) Key() string {
+	return LinkKey(l.Domain, l.Short)
+}
+
+// Cached ...
+func (l Link) Cached() CachedLink {
+
+	var createdAt *time.Time
+	if !l.CreatedAt.IsZero() {
+		createdAt = &l.CreatedAt
+	}
+
+	return CachedLink{
+		Long:           l.Long,
+		ExpiresAt:      l.ExpiresAt,
+		MaxClicks:      l.MaxClicks,
+		FallbackURL:    l.FallbackURL,
+		Protected:      l.Protected(),
+		RedirectType:   l.StatusCode(),
+		Rules:          l.Rules,
+		Variants:       l.Variants,
+		StickyVariants: l.StickyVariants,
+		Description:    l.Description,
+		CreatedAt:      createdAt,
+		UTM:            l.UTM,
+		SafetyStatus:   l.SafetyStatus,
+		Pixels:         l.Pixels,
+		PixelDelay:     l.PixelDelay,
+	}
+}
+
+// IsExpired checks link lifetime by date and by clicks count
+func (l CachedLink) IsExpired(now time.Time, clicks int64) bool {
+	if l.ExpiresAt != nil && !now.Before(*l.ExpiresAt) {
+		return true
+	}
+	return l.MaxClicks > 0 && clicks >= l.MaxClicks
+}
+
+// Encode ...
+func (l CachedLink) Encode() string {
+	body, _ := json.Marshal(&l)
+	return string(body)
+}
+
+// DecodeCachedLink reads a cache value, plain string values stored by older versions are treated as long urls
+func DecodeCachedLink(value interface{}) (*CachedLink, bool) {
+
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return nil, false
+	}
+
+	if !strings.HasPrefix(raw, "{") {
+		return &CachedLink{Long: raw}, raw != ""
+	}
+
+	var link CachedLink
+	if err := json.Unmarshal([]byte(raw), &link); err != nil {
+		return nil, false
+	}
+
+	return &link, true
+}
+
+// StoreCache puts link into url cache, links with an expiration date
+// are stored with ttl so the cache entry can not outlive the link.
+// Hidden links are removed from cache instead, they must not be redirected
+func StoreCache(urlCache cache.UrlCache, link Link) {
+
+	if link.Hidden {
+		urlCache.Delete(link.Key())
+		return
+	}
+
+	value := link.Cached().Encode()
+
+	if link.ExpiresAt != nil {
+		ttl := int(link.ExpiresAt.Sub(utils.Now()).Seconds())
+		if ttl > 0 {
+			urlCache.StoreExp(link.Key(), value, ttl)
+			return
+		}
+	}
+
+	urlCache.Store(link.Key(), value)
+}
+
+// LoadCache loads a link by key, see LinkKey
+func LoadCache(urlCache cache.UrlCache, key string) (*CachedLink, bool) {
+	value, ok := urlCache.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return DecodeCachedLink(value)
+}
@@ -0,0 +1,102 @@
+package links
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"shortly/cache"
+)
+
+func TestDecodeCachedLink(t *testing.T) {
+
+	link, ok := DecodeCachedLink("www.google.com")
+	if !ok || link.Long != "www.google.com" {
+		t.Errorf("plain string value is not decoded as long url")
+	}
+
+	expiresAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	value := Link{Long: "https://example.com", ExpiresAt: &expiresAt, MaxClicks: 10}.Cached().Encode()
+
+	link, ok = DecodeCachedLink(value)
+	if !ok {
+		t.Fatalf("encoded value is not decoded")
+	}
+
+	if link.Long != "https://example.com" || link.MaxClicks != 10 || !link.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("decoded value is not equal to encoded, %+v", link)
+	}
+
+	if _, ok := DecodeCachedLink(""); ok {
+		t.Errorf("empty value must be a cache miss")
+	}
+}
+
+func TestCachedLinkIsExpired(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	cases := []struct {
+		link    CachedLink
+		clicks  int64
+		expired bool
+	}{
+		{CachedLink{}, 100, false},
+		{CachedLink{ExpiresAt: &future}, 0, false},
+		{CachedLink{ExpiresAt: &past}, 0, true},
+		{CachedLink{ExpiresAt: &now}, 0, true},
+		{CachedLink{MaxClicks: 5}, 4, false},
+		{CachedLink{MaxClicks: 5}, 5, true},
+		{CachedLink{ExpiresAt: &future, MaxClicks: 5}, 6, true},
+	}
+
+	for i, c := range cases {
+		if c.link.IsExpired(now, c.clicks) != c.expired {
+			t.Errorf("case #%d: expected expired = %v", i, c.expired)
+		}
+	}
+}
+
+func TestLinkStatusCode(t *testing.T) {
+
+	cases := []struct {
+		link     Link
+		expected int
+	}{
+		{Link{}, http.StatusSeeOther},
+		{Link{AccountRedirectType: http.StatusFound}, http.StatusFound},
+		{Link{RedirectType: http.StatusMovedPermanently, AccountRedirectType: http.StatusFound}, http.StatusMovedPermanently},
+	}
+
+	for _, c := range cases {
+		if code := c.link.StatusCode(); code != c.expected {
+			t.Errorf("expected status code %d, got %d", c.expected, code)
+		}
+		if code := c.link.Cached().StatusCode(); code != c.expected {
+			t.Errorf("cached link: expected status code %d, got %d", c.expected, code)
+		}
+	}
+
+	if err := ValidateRedirectType(http.StatusOK); err != ErrInvalidRedirectType {
+		t.Errorf("expected invalid redirect type error, got %v", err)
+	}
+}
+
+func TestStoreCacheHidden(t *testing.T) {
+
+	urlCache := cache.NewMemoryCache()
+
+	link := Link{Short: "abc", Long: "https://example.com"}
+	StoreCache(urlCache, link)
+	if _, ok := LoadCache(urlCache, "abc"); !ok {
+		t.Fatalf("visible link must be cached")
+	}
+
+	link.Hidden = true
+	StoreCache(urlCache, link)
+	if _, ok := LoadCache(urlCache, "abc"); ok {
+		t.Errorf("hidden link must be removed from cache")
+	}
+}
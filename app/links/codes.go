@@ -0,0 +1,356 @@
+package links
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// code generation strategies
+const (
+	CodeStrategyRandom   = "random"
+	CodeStrategySequence = "sequence"
+	CodeStrategyHashids  = "hashids"
+)
+
+const (
+	// DefaultCodeAlphabet ...
+	DefaultCodeAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// DefaultCodeMinLength ...
+	DefaultCodeMinLength = 5
+	// CodeAlphabetMinLength is a minimal number of unique alphabet characters
+	CodeAlphabetMinLength = 16
+	// codeMaxAttempts is a number of generated codes tried before an insert fails with a conflict
+	codeMaxAttempts = 10
+	// codeEscalateAttempts is a number of random codes of the same length tried before the length is increased
+	codeEscalateAttempts = 3
+)
+
+var (
+	// ErrInvalidCodeStrategy ...
+	ErrInvalidCodeStrategy = errors.New("strategy must be one of random, sequence, hashids")
+	// ErrInvalidCodeAlphabet ...
+	ErrInvalidCodeAlphabet = errors.New("alphabet must consist of at least 16 unique latin letters and digits")
+	// ErrInvalidCodeMinLength ...
+	ErrInvalidCodeMinLength = errors.New("minimal length must be between 3 and 10")
+)
+
+// codeMaxMinLength keeps offsets of sequence codes within int64
+const codeMaxMinLength = 10
+
+// CodeGenerator produces short codes of generated links, attempt is a zero based number of
+// the current try, it is increased after a code collides with an existing short url
+type CodeGenerator interface {
+	Generate(attempt int) (string, error)
+}
+
+// CodeSettings is an account configuration of generated short codes
+type CodeSettings struct {
+	AccountID     int64
+	Strategy      string
+	Alphabet      string
+	MinLength     int
+	CaseSensitive bool
+	// Salt shuffles the alphabet of hashids codes, it is set once for an account
+	Salt string
+}
+
+// DefaultCodeSettings ...
+func DefaultCodeSettings(accountID int64) CodeSettings {
+	return CodeSettings{
+		AccountID:     accountID,
+		Strategy:      CodeStrategyRandom,
+		Alphabet:      DefaultCodeAlphabet,
+		MinLength:     DefaultCodeMinLength,
+		CaseSensitive: true,
+	}
+}
+
+// Validate ...
+func (s CodeSettings) Validate() error {
+
+	switch s.Strategy {
+	case CodeStrategyRandom, CodeStrategySequence, CodeStrategyHashids:
+	default:
+		return ErrInvalidCodeStrategy
+	}
+
+	if s.MinLength < SlugMinLength || s.MinLength > codeMaxMinLength {
+		return ErrInvalidCodeMinLength
+	}
+
+	if s.Alphabet != "" {
+		for _, c := range s.Alphabet {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+				return ErrInvalidCodeAlphabet
+			}
+		}
+		if len(s.alphabet()) < CodeAlphabetMinLength {
+			return ErrInvalidCodeAlphabet
+		}
+	}
+
+	return nil
+}
+
+// alphabet returns unique characters of the configured alphabet, lower cased for case insensitive codes
+func (s CodeSettings) alphabet() string {
+
+	alphabet := s.Alphabet
+	if alphabet == "" {
+		alphabet = DefaultCodeAlphabet
+	}
+	if !s.CaseSensitive {
+		alphabet = strings.ToLower(alphabet)
+	}
+
+	var b strings.Builder
+	for _, c := range alphabet {
+		if !strings.ContainsRune(b.String(), c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Generator returns a code generator configured by the settings, sequence based generators read
+// numbers from the database
+func (s CodeSettings) Generator(db *sql.DB) CodeGenerator {
+
+	minLength := s.MinLength
+	if minLength == 0 {
+		minLength = DefaultCodeMinLength
+	}
+
+	switch s.Strategy {
+	case CodeStrategySequence:
+		return &SequenceGenerator{Next: sequenceNext(db), Alphabet: s.alphabet(), MinLength: minLength}
+	case CodeStrategyHashids:
+		return &HashidsGenerator{Next: sequenceNext(db), Alphabet: s.alphabet(), Salt: s.Salt, MinLength: minLength}
+	}
+	return &RandomGenerator{Alphabet: s.alphabet(), MinLength: minLength}
+}
+
+func sequenceNext(db *sql.DB) func() (int64, error) {
+	return func() (int64, error) {
+		var n int64
+		err := db.QueryRow("select nextval('link_codes_seq')").Scan(&n)
+		return n, err
+	}
+}
+
+// RandomGenerator produces crypto random codes, the length grows by one after
+// every few collisions so crowded short lengths don't exhaust attempts
+type RandomGenerator struct {
+	Alphabet  string
+	MinLength int
+}
+
+// Generate ...
+func (g *RandomGenerator) Generate(attempt int) (string, error) {
+
+	length := g.MinLength + attempt/codeEscalateAttempts
+	if length > SlugMaxLength {
+		length = SlugMaxLength
+	}
+
+	max := big.NewInt(int64(len(g.Alphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = g.Alphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// SequenceGenerator encodes numbers of a database sequence in the alphabet base, numbers are offset
+// so the shortest code has the minimal length
+type SequenceGenerator struct {
+	Next      func() (int64, error)
+	Alphabet  string
+	MinLength int
+}
+
+// Generate ...
+func (g *SequenceGenerator) Generate(_ int) (string, error) {
+	n, err := g.Next()
+	if err != nil {
+		return "", err
+	}
+	return encodeNumber(n+codeOffset(len(g.Alphabet), g.MinLength), g.Alphabet), nil
+}
+
+// HashidsGenerator encodes numbers of a database sequence like hashids do: the first character is
+// picked by the number and together with the salt it shuffles the alphabet used for the rest of the code,
+// so sequential numbers don't produce similar codes while codes stay unique and decodable
+type HashidsGenerator struct {
+	Next      func() (int64, error)
+	Alphabet  string
+	Salt      string
+	MinLength int
+}
+
+// Generate ...
+func (g *HashidsGenerator) Generate(_ int) (string, error) {
+	n, err := g.Next()
+	if err != nil {
+		return "", err
+	}
+	return g.Encode(n), nil
+}
+
+// Encode ...
+func (g *HashidsGenerator) Encode(n int64) string {
+	alphabet := shuffle(g.Alphabet, g.Salt)
+	lottery := alphabet[n%int64(len(alphabet))]
+	code := encodeNumber(n+codeOffset(len(alphabet), g.MinLength-1), shuffle(alphabet, string(lottery)+g.Salt))
+	return string(lottery) + code
+}
+
+// Decode returns a number encoded by Encode
+func (g *HashidsGenerator) Decode(code string) (int64, bool) {
+	if code == "" {
+		return 0, false
+	}
+	alphabet := shuffle(g.Alphabet, g.Salt)
+	n, ok := decodeNumber(code[1:], shuffle(alphabet, code[:1]+g.Salt))
+	if !ok {
+		return 0, false
+	}
+	n -= codeOffset(len(alphabet), g.MinLength-1)
+	if n < 0 || alphabet[n%int64(len(alphabet))] != code[0] {
+		return 0, false
+	}
+	return n, true
+}
+
+// codeOffset returns the smallest number which takes length digits in the base
+func codeOffset(base, length int) int64 {
+	offset := int64(1)
+	for i := 1; i < length; i++ {
+		offset *= int64(base)
+	}
+	return offset
+}
+
+func encodeNumber(n int64, alphabet string) string {
+	base := int64(len(alphabet))
+	var b []byte
+	for {
+		b = append(b, alphabet[n%base])
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func decodeNumber(code, alphabet string) (int64, bool) {
+	var n int64
+	for i := 0; i < len(code); i++ {
+		d := strings.IndexByte(alphabet, code[i])
+		if d < 0 {
+			return 0, false
+		}
+		n = n*int64(len(alphabet)) + int64(d)
+	}
+	return n, true
+}
+
+// shuffle is a consistent shuffle of hashids, the same salt always produces the same order
+func shuffle(alphabet, salt string) string {
+
+	if salt == "" {
+		return alphabet
+	}
+
+	b := []byte(alphabet)
+	for i, v, p := len(b)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		c := int(salt[v])
+		p += c
+		j := (c + v + p) % i
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// GetCodeSettings returns code settings of an account, defaults are returned for accounts without own settings
+func (repo *LinksRepository) GetCodeSettings(accountID int64) (CodeSettings, error) {
+
+	settings := DefaultCodeSettings(accountID)
+	err := repo.DB.QueryRow(`
+		select strategy, alphabet, min_length, case_sensitive, salt from link_code_settings where account_id = $1`, accountID,
+	).Scan(&settings.Strategy, &settings.Alphabet, &settings.MinLength, &settings.CaseSensitive, &settings.Salt)
+	if err == sql.ErrNoRows {
+		return DefaultCodeSettings(accountID), nil
+	}
+
+	return settings, err
+}
+
+// UpdateCodeSettings stores code settings of an account, the salt of hashids codes is kept once it is set
+func (repo *LinksRepository) UpdateCodeSettings(settings CodeSettings) error {
+
+	salt, err := (&RandomGenerator{Alphabet: DefaultCodeAlphabet, MinLength: 16}).Generate(0)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.DB.Exec(`
+		insert into link_code_settings (account_id, strategy, alphabet, min_length, case_sensitive, salt) values ($1, $2, $3, $4, $5, $6)
+		on conflict (account_id) do update set strategy = excluded.strategy, alphabet = excluded.alphabet,
+		min_length = excluded.min_length, case_sensitive = excluded.case_sensitive`,
+		settings.AccountID, settings.Strategy, settings.Alphabet, settings.MinLength, settings.CaseSensitive, salt,
+	)
+	return err
+}
+
+// insertWithCode runs an insert of a link, links without a short url get a generated code which
+// is regenerated while it is a reserved word or collides with existing short urls
+func (repo *LinksRepository) insertWithCode(accountID int64, link *Link, insert func() error) error {
+
+	if link.Short != "" {
+		return insert()
+	}
+
+	settings, err := repo.GetCodeSettings(accountID)
+	if err != nil {
+		return err
+	}
+	generator := settings.Generator(repo.DB)
+
+	for attempt := 0; attempt < codeMaxAttempts; attempt++ {
+		link.Short, err = generator.Generate(attempt)
+		if err != nil {
+			return err
+		}
+		var reserved bool
+		reserved, err = repo.isReservedSlug(accountID, link.Short)
+		if err != nil {
+			return err
+		}
+		if reserved {
+			repo.Logger.Printf("generated code(%s) of account(%v) is reserved, attempt %v\n", link.Short, accountID, attempt+1)
+			err = ErrSlugReserved
+			continue
+		}
+		err = insert()
+		if !isUniqueViolation(err) {
+			return err
+		}
+		repo.Logger.Printf("generated code(%s) of account(%v) is taken, attempt %v\n", link.Short, accountID, attempt+1)
+	}
+
+	return err
+}
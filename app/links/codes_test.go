@@ -0,0 +1,226 @@
+package links
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestCodeSettingsValidate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		settings CodeSettings
+		err      error
+	}{
+		{"default", DefaultCodeSettings(1), nil},
+		{"hashids", CodeSettings{Strategy: CodeStrategyHashids, MinLength: 6}, nil},
+		{"unknown strategy", CodeSettings{Strategy: "uuid", MinLength: 5}, ErrInvalidCodeStrategy},
+		{"short length", CodeSettings{Strategy: CodeStrategyRandom, MinLength: 2}, ErrInvalidCodeMinLength},
+		{"long length", CodeSettings{Strategy: CodeStrategyRandom, MinLength: 11}, ErrInvalidCodeMinLength},
+		{"invalid chars", CodeSettings{Strategy: CodeStrategyRandom, MinLength: 5, Alphabet: "abcdefghijklmnop-"}, ErrInvalidCodeAlphabet},
+		{"few chars", CodeSettings{Strategy: CodeStrategyRandom, MinLength: 5, Alphabet: "abcdef0123"}, ErrInvalidCodeAlphabet},
+		{
+			"case sensitive chars",
+			CodeSettings{Strategy: CodeStrategyRandom, MinLength: 5, Alphabet: "abcdefghABCDEFGH", CaseSensitive: true},
+			nil,
+		},
+		{
+			"few case insensitive chars",
+			CodeSettings{Strategy: CodeStrategyRandom, MinLength: 5, Alphabet: "abcdefghABCDEFGH"},
+			ErrInvalidCodeAlphabet,
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.settings.Validate(); err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+
+	// case insensitive codes use lower cased alphabet
+	settings := CodeSettings{Strategy: CodeStrategyRandom, MinLength: 4, Alphabet: "ABCDEFGHIJKLMNOPQRSTUVWXYZ", CaseSensitive: false}
+	g := settings.Generator(nil)
+
+	for attempt, length := range []int{4, 4, 4, 5, 5, 5, 6} {
+		code, err := g.Generate(attempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != length {
+			t.Errorf("attempt %d: expected length %d, got %s", attempt, length, code)
+		}
+		if code != strings.ToLower(code) {
+			t.Errorf("case insensitive code must be lower cased, got %s", code)
+		}
+	}
+}
+
+func counter(start int64) func() (int64, error) {
+	n := start
+	return func() (int64, error) {
+		n++
+		return n, nil
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+
+	g := &SequenceGenerator{Next: counter(0), Alphabet: DefaultCodeAlphabet, MinLength: 3}
+
+	first, _ := g.Generate(0)
+	second, _ := g.Generate(0)
+
+	// 62*62 + 1 and 62*62 + 2 in base62
+	if first != "bab" || second != "bac" {
+		t.Errorf("unexpected codes %s, %s", first, second)
+	}
+}
+
+func TestHashidsGenerator(t *testing.T) {
+
+	g := &HashidsGenerator{Next: counter(0), Alphabet: DefaultCodeAlphabet, Salt: "account salt", MinLength: 5}
+	other := &HashidsGenerator{Alphabet: DefaultCodeAlphabet, Salt: "another salt", MinLength: 5}
+
+	codes := make(map[string]bool)
+	for i := 0; i < 5000; i++ {
+		code, err := g.Generate(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) < 5 {
+			t.Fatalf("code %s is shorter than minimal length", code)
+		}
+		if codes[code] {
+			t.Fatalf("duplicate code %s", code)
+		}
+		codes[code] = true
+
+		n, ok := g.Decode(code)
+		if !ok || n != int64(i+1) {
+			t.Fatalf("code %s decoded to %v, expected %v", code, n, i+1)
+		}
+	}
+
+	if g.Encode(1) == other.Encode(1) {
+		t.Errorf("codes of different salts must differ")
+	}
+}
+
+func TestCreateUserLinkRetriesGeneratedCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	accountID := int64(3)
+
+	mock.ExpectQuery("select strategy, alphabet, min_length, case_sensitive, salt from link_code_settings").WithArgs(accountID).
+		WillReturnRows(sqlmock.NewRows([]string{"strategy", "alphabet", "min_length", "case_sensitive", "salt"}).
+			AddRow(CodeStrategySequence, "", 3, true, ""))
+
+	mock.ExpectQuery("select nextval").WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	mock.ExpectQuery("select exists(.+) from reserved_slugs").WithArgs(accountID, "bab").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into links").WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	mock.ExpectQuery("select nextval").WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(2))
+	mock.ExpectQuery("select exists(.+) from reserved_slugs").WithArgs(accountID, "bac").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into links").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	repo := &LinksRepository{DB: db, Logger: log.New(ioutil.Discard, "", 0)}
+
+	link := &Link{Long: "https://example.com"}
+	tx, linkID, err := repo.CreateUserLink(accountID, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if linkID != 7 || link.Short != "bac" {
+		t.Errorf("unexpected link %v with code %s", linkID, link.Short)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateUserLinkSkipsReservedCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	accountID := int64(3)
+
+	mock.ExpectQuery("select strategy, alphabet, min_length, case_sensitive, salt from link_code_settings").WithArgs(accountID).
+		WillReturnRows(sqlmock.NewRows([]string{"strategy", "alphabet", "min_length", "case_sensitive", "salt"}).
+			AddRow(CodeStrategySequence, "", 3, true, ""))
+
+	mock.ExpectQuery("select nextval").WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(1))
+	mock.ExpectQuery("select exists(.+) from reserved_slugs").WithArgs(accountID, "bab").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("select nextval").WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(2))
+	mock.ExpectQuery("select exists(.+) from reserved_slugs").WithArgs(accountID, "bac").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("insert into links").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	repo := &LinksRepository{DB: db, Logger: log.New(ioutil.Discard, "", 0)}
+
+	link := &Link{Long: "https://example.com"}
+	tx, linkID, err := repo.CreateUserLink(accountID, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if linkID != 7 || link.Short != "bac" {
+		t.Errorf("unexpected link %v with code %s", linkID, link.Short)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetCodeSettingsDefaults(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("from link_code_settings").WithArgs(int64(4)).WillReturnError(sql.ErrNoRows)
+
+	repo := &LinksRepository{DB: db}
+	settings, err := repo.GetCodeSettings(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings != DefaultCodeSettings(4) {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+}
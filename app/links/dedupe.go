@@ -0,0 +1,91 @@
+package links
+
+import (
+	"database/sql"
+
+	"shortly/app/urls"
+)
+
+// normalizeBatch is a number of links normalized by one query of NormalizeStoredURLs
+const normalizeBatch = 1000
+
+// NormalizeURL returns a canonical form of a destination url used to find links to the same page,
+// urls which can't be canonicalized are returned as is
+func NormalizeURL(rawURL string) string {
+	canonical, err := urls.Canonicalize(rawURL, urls.DeepLink)
+	if err != nil {
+		return rawURL
+	}
+	return canonical
+}
+
+// FindActiveLink returns the latest active link of an account with the same normalized destination
+// and the same expiration date, fallback url and redirect type as a requested link.
+// Protected, hidden, expired and deleted links and links with a clicks limit are not reused,
+// sql.ErrNoRows is returned for requested links with a password or a clicks limit
+func (repo *LinksRepository) FindActiveLink(accountID int64, requested Link) (Link, error) {
+
+	var link Link
+	if requested.Password != "" || requested.MaxClicks > 0 {
+		return link, sql.ErrNoRows
+	}
+
+	err := scanLink(repo.DB.QueryRow("select "+linkFields+" from "+linkTables+`
+		where links.account_id = $1 and links.domain_id = $2 and md5(links.normalized_url) = md5($3) and links.normalized_url = $3
+		and links.deleted_at is null and links.hide = false and links.password = '' and links.max_clicks = 0
+		and (links.expires_at is null or links.expires_at > now()) and links.expires_at is not distinct from $4
+		and links.fallback_url = $5 and links.redirect_type = $6
+		order by links.id desc limit 1`,
+		accountID, requested.DomainID, NormalizeURL(requested.Long), requested.ExpiresAt, requested.FallbackURL,
+		requested.RedirectType,
+	), &link)
+
+	return link, err
+}
+
+// NormalizeStoredURLs fills normalized destinations of links stored before they were normalized,
+// it returns a number of updated links
+func (repo *LinksRepository) NormalizeStoredURLs() (int, error) {
+
+	var total int
+	var lastID int64
+
+	for {
+		rows, err := repo.DB.Query(`select id, long_url from links where normalized_url = '' and id > $1 order by id limit $2`,
+			lastID, normalizeBatch)
+		if err != nil {
+			return total, err
+		}
+
+		type storedURL struct {
+			id      int64
+			longURL string
+		}
+
+		var list []storedURL
+		for rows.Next() {
+			var u storedURL
+			if err := rows.Scan(&u.id, &u.longURL); err != nil {
+				rows.Close()
+				return total, err
+			}
+			list = append(list, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+
+		for _, u := range list {
+			if _, err := repo.DB.Exec("update links set normalized_url = $1 where id = $2", NormalizeURL(u.longURL), u.id); err != nil {
+				return total, err
+			}
+			lastID = u.id
+			total++
+		}
+
+		if len(list) < normalizeBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package links
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeURL(t *testing.T) {
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://Example.COM", "https://example.com/"},
+		{"HTTPS://example.com:443/Path?b=1&a=2", "https://example.com/Path?b=1&a=2"},
+		{"http://example.com:80/", "http://example.com/"},
+		{"http://example.com:8080", "http://example.com:8080/"},
+		{"  https://example.com/a#top ", "https://example.com/a#top"},
+		{"not a url", "not a url"},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeURL(test.url); got != test.expected {
+			t.Errorf("NormalizeURL(%q) = %q, expected %q", test.url, got, test.expected)
+		}
+	}
+}
+
+func TestFindActiveLinkOptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("links.max_clicks = 0").
+		WithArgs(int64(1), int64(0), "https://example.com/", nil, "", 301).
+		WillReturnError(sql.ErrNoRows)
+
+	repo := &LinksRepository{DB: db}
+
+	if _, err := repo.FindActiveLink(1, Link{Long: "https://Example.com", RedirectType: 301}); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error, got %v", err)
+	}
+
+	// protected and limited links are never reused, no queries are made
+	if _, err := repo.FindActiveLink(1, Link{Long: "https://example.com", Password: "hash"}); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error for a protected link, got %v", err)
+	}
+	if _, err := repo.FindActiveLink(1, Link{Long: "https://example.com", MaxClicks: 5}); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error for a limited link, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,41 @@
+package links
+
+import (
+	"time"
+)
+
+// UpdateLinkHealth stores a result of the latest destination probe
+func (repo *LinksRepository) UpdateLinkHealth(linkID int64, statusCode int, broken bool) error {
+	_, err := repo.DB.Exec(`
+		update links set broken = $1, health_status_code = $2, health_checked_at = now() where id = $3`,
+		broken, statusCode, linkID,
+	)
+	return err
+}
+
+// GetLinksToProbe returns active links to web destinations which were not probed since a provided time
+func (repo *LinksRepository) GetLinksToProbe(checkedBefore time.Time, limit, offset int) ([]Link, error) {
+
+	rows, err := repo.DB.Query("select "+linkFields+" from "+linkTables+`
+		where links.hide = false and links.deleted_at is null and (links.health_checked_at is null or links.health_checked_at < $1)
+		and (links.long_url ~* '^https?://' or links.long_url !~ ':')
+		order by links.health_checked_at nulls first, links.id limit $2 offset $3`,
+		checkedBefore, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Link
+	for rows.Next() {
+		var link Link
+		if err := scanLink(rows, &link); err != nil {
+			return nil, err
+		}
+		list = append(list, link)
+	}
+
+	return list, rows.Err()
+}
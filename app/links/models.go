@@ -1,5 +1,9 @@
 package links
 
+import (
+	"time"
+)
+
 // Link ...
 type Link struct {
 	ID          int64
@@ -8,5 +12,54 @@ type Link struct {
 	Long        string
 	Description string
 	Tags        []string
+	// Groups are names of link groups, they are read by StreamUserLinks only
+	Groups      []string
 	Hidden      bool
+	ExpiresAt   *time.Time
+	MaxClicks   int64
+	FallbackURL string
+	// Password is a bcrypt hash of the link password, empty for public links
+	Password string
+	// DomainID is an id of account custom domain, zero for the default domain
+	DomainID int64
+	Domain   string
+	// RedirectType is a redirect http status code, zero means account default
+	RedirectType int
+	// AccountRedirectType is a default redirect type of link account
+	AccountRedirectType int
+	// Rules are ordered conditional destinations
+	Rules []Rule
+	// Variants are weighted destinations used when no rule is matched
+	Variants []Variant
+	// StickyVariants keeps the same variant for repeat visitors
+	StickyVariants bool
+	CreatedAt      time.Time
+	// UTM is set for links of an active campaign channel
+	UTM *UTM
+	// SafetyScore is a destination risk score from 0 to 100, SafetyReasons explain it
+	SafetyScore   int
+	SafetyStatus  string
+	SafetyReasons []string
+	// Broken is set by destination health monitor for unreachable destinations
+	Broken           bool
+	HealthStatusCode int
+	HealthCheckedAt  *time.Time
+	// DeletedAt is set for links in trash
+	DeletedAt *time.Time
+	// Pixels are fired by an interstitial page before a redirect,
+	// PixelDelay is its delay in milliseconds, zero means the default delay
+	Pixels     []Pixel
+	PixelDelay int
+}
+
+// Protected ...
+func (l Link) Protected() bool {
+	return l.Password != ""
+}
+
+// ReservedSlug ...
+type ReservedSlug struct {
+	ID        int64
+	AccountID int64
+	Slug      string
 }
@@ -0,0 +1,80 @@
+package links
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Pixel is a retargeting pixel attached to a link, URL is a template expanded on each redirect
+type Pixel struct {
+	ID   int64  `json:"id"`
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+// linkPixelsField aggregates link pixels into a json array, see linkRulesField
+const linkPixelsField = `coalesce((
+		select json_agg(json_build_object(
+			'id', pixels.id, 'kind', pixels.kind, 'url', pixels.url
+		) order by pixels.id)
+		from link_pixels join pixels on pixels.id = link_pixels.pixel_id where link_pixels.link_id = links.id
+	), '[]')`
+
+// pixelsJSON is a scan destination for linkPixelsField
+type pixelsJSON []Pixel
+
+// Scan ...
+func (p *pixelsJSON) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	case nil:
+		*p = nil
+		return nil
+	default:
+		return errors.New("unsupported link pixels type")
+	}
+	return json.Unmarshal(data, (*[]Pixel)(p))
+}
+
+// SetLinkPixels replaces pixels of a link, pixel ids must be checked to belong to the link account,
+// delay is an interstitial delay in milliseconds, zero means the default delay
+func (repo *LinksRepository) SetLinkPixels(linkID int64, pixelIDs []int64, delay int) error {
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec("update links set pixel_delay = $1 where id = $2 and deleted_at is null", delay, linkID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("delete from link_pixels where link_id = $1", linkID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(pixelIDs) > 0 {
+		_, err := tx.Exec(`insert into link_pixels (link_id, pixel_id) select $1, unnest($2::bigint[])`,
+			linkID, pq.Array(pixelIDs))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
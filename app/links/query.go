@@ -8,17 +8,18 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
 
 	"shortly/utils"
 )
 
 // ILinksRepository ...
 type ILinksRepository interface {
-	UnshortenURL(string) (string, error)
+	UnshortenURL(domainID int64, shortURL string) (Link, error)
 	GetLinkByID(int64) (Link, error)
-	UpdateUserLink(int64, int64, *Link) (*sql.Tx, error)
+	VerifyLinkPassword(domainID int64, shortURL, password string) (bool, error)
+	UpdateUserLink(int64, int64, int64, *Link) (*sql.Tx, error)
 	GetAllLinks() ([]Link, error)
-	GenerateLink() string
 	CreateLink(*Link) error
 	CreateUserLink(accountID int64, link *Link) (*sql.Tx, int64, error)
 	DeleteUserLink(accountID int64, linkID int64) (*sql.Tx, int64, error)
@@ -30,6 +31,7 @@ type ILinksRepository interface {
 
 // LinksRepository ...
 type LinksRepository struct {
+	utils.AuditQuery
 	DB        *sql.DB
 	Logger    *log.Logger
 	callbacks map[string]func(int64, interface{})
@@ -65,61 +67,191 @@ func (repo *LinksRepository) OnHide(f func(int64, interface{})) {
 	repo.addCallback("Hide", f)
 }
 
+// linkFields is a list of columns read by scanLink, linkTables must be used as a source
+const linkFields = `links.id, links.account_id, links.short_url, links.long_url, links.description, links.hide,
+	links.expires_at, links.max_clicks, links.fallback_url, links.password, links.domain_id, coalesce(domains.host, ''),
+	links.redirect_type, coalesce(accounts.default_redirect_type, 0), links.sticky_variants, links.created_at,
+	links.safety_score, links.safety_status, links.safety_reasons, links.broken, links.health_status_code, links.health_checked_at, links.deleted_at, links.pixel_delay, ` +
+	linkRulesField + ", " + linkVariantsField + ", " + linkUTMField + ", " + linkPixelsField
+
+const linkTables = `links left join domains on domains.id = links.domain_id
+	left join accounts on accounts.id = links.account_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLink(row rowScanner, link *Link) error {
+	return row.Scan(
+		&link.ID,
+		&link.AccountID,
+		&link.Short,
+		&link.Long,
+		&link.Description,
+		&link.Hidden,
+		&link.ExpiresAt,
+		&link.MaxClicks,
+		&link.FallbackURL,
+		&link.Password,
+		&link.DomainID,
+		&link.Domain,
+		&link.RedirectType,
+		&link.AccountRedirectType,
+		&link.StickyVariants,
+		&link.CreatedAt,
+		&link.SafetyScore,
+		&link.SafetyStatus,
+		pq.Array(&link.SafetyReasons),
+		&link.Broken,
+		&link.HealthStatusCode,
+		&link.HealthCheckedAt,
+		&link.DeletedAt,
+		&link.PixelDelay,
+		(*rulesJSON)(&link.Rules),
+		(*variantsJSON)(&link.Variants),
+		utmJSON{&link.UTM},
+		(*pixelsJSON)(&link.Pixels),
+	)
+}
+
 // UnshortenURL ...
-func (repo *LinksRepository) UnshortenURL(shortURL string) (string, error) {
+func (repo *LinksRepository) UnshortenURL(domainID int64, shortURL string) (Link, error) {
+
+	query := "select " + linkFields + " from " + linkTables +
+		" where links.domain_id = $1 and links.short_url = $2 and links.hide = false and links.deleted_at is null"
+
+	var link Link
+	err := scanLink(repo.DB.QueryRow(query, domainID, shortURL), &link)
+	return link, err
+}
 
-	query := "select long_url from links where short_url = $1 and active = true"
+// VerifyLinkPassword compares password with a hash stored for an active link
+func (repo *LinksRepository) VerifyLinkPassword(domainID int64, shortURL, password string) (bool, error) {
 
-	var longURL string
-	err := repo.DB.QueryRow(query, shortURL).Scan(&longURL)
+	var hash string
+	err := repo.DB.QueryRow(
+		"select password from links where domain_id = $1 and short_url = $2 and hide = false and deleted_at is null", domainID, shortURL,
+	).Scan(&hash)
 	if err != nil {
-		return "", err
+		return false, err
+	}
+
+	if hash == "" {
+		return true, nil
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
 	}
 
-	return longURL, nil
+	return err == nil, err
+}
+
+// HashPassword ...
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
 }
 
-// GetAllLinks ...
+// GetAllLinks returns links which are not in trash
 func (repo *LinksRepository) GetAllLinks() ([]Link, error) {
 
-	query := "select short_url, long_url, account_id from links"
+	query := "select " + linkFields + " from " + linkTables + " where links.deleted_at is null"
 	var queryArgs []interface{}
 	rows, err := repo.DB.Query(query, queryArgs...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var list []Link
 
 	for rows.Next() {
-		var shortURL, longURL string
-		var accountID int64
-		err := rows.Scan(&shortURL, &longURL, &accountID)
-		if err != nil {
+		var link Link
+		if err := scanLink(rows, &link); err != nil {
+			return nil, err
+		}
+		list = append(list, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetAccountLinks returns active links of account
+func (repo *LinksRepository) GetAccountLinks(accountID int64) ([]Link, error) {
+
+	query := "select " + linkFields + " from " + linkTables + " where links.account_id = $1 and links.hide = false and links.deleted_at is null"
+	rows, err := repo.DB.Query(query, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Link
+
+	for rows.Next() {
+		var link Link
+		if err := scanLink(rows, &link); err != nil {
 			return nil, err
 		}
-		list = append(list, Link{
-			AccountID: accountID,
-			Short:     shortURL,
-			Long:      longURL,
-		})
+		list = append(list, link)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
+	return list, nil
+}
+
+// GetAccountLinksByIDs returns links of an account with given ids including hidden ones, links in trash and
+// links of other accounts are skipped
+func (repo *LinksRepository) GetAccountLinksByIDs(accountID int64, ids []int64) ([]Link, error) {
+
+	query := "select " + linkFields + " from " + linkTables + " where links.account_id = $1 and links.id = any($2) and links.deleted_at is null"
+	rows, err := repo.DB.Query(query, accountID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
 	defer rows.Close()
 
+	list := make([]Link, 0, len(ids))
+
+	for rows.Next() {
+		var link Link
+		if err := scanLink(rows, &link); err != nil {
+			return nil, err
+		}
+		list = append(list, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return list, nil
 }
 
 // CreateLink ...
 func (repo *LinksRepository) CreateLink(link *Link) error {
-	_, err := repo.DB.Exec(`
-		insert into "links" (short_url, long_url, description) VALUES ( $1, $2, $3 )
-	`, link.Short, link.Long, link.Description)
-	return err
+	return repo.insertWithCode(0, link, func() error {
+		_, err := repo.DB.Exec(`
+			insert into "links" (short_url, long_url, description, safety_score, safety_status, safety_reasons, safety_checked_at)
+			values ($1, $2, $3, $4, $5, $6, now())
+		`, link.Short, link.Long, link.Description, link.SafetyScore, link.safetyStatus(), pq.Array(link.SafetyReasons))
+		return err
+	})
 }
 
 // LinkFilter ...
@@ -129,6 +261,9 @@ type LinkFilter struct {
 	Tags     []string
 	FullText string
 	LinkID   int64
+	LinkIDs  []int64
+	// Trash selects deleted links instead of active ones
+	Trash bool
 }
 
 type LinkResult struct {
@@ -136,10 +271,13 @@ type LinkResult struct {
 	Total int64
 }
 
-// GetUserLinks ...
-func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset int64, filters ...LinkFilter) (*LinkResult, error) {
+// userLinksSelect is a list of columns read by scanUserLink from userLinksQuery
+const userLinksSelect = "select u.id, u.short_url, u.long_url, u.description, u.tl, u.hide, u.expires_at, u.max_clicks, u.fallback_url, u.password" +
+	", u.domain_id, coalesce(u.domain_host, ''), u.redirect_type, coalesce(u.account_redirect_type, 0), u.sticky_variants" +
+	", u.safety_score, u.safety_status, u.safety_reasons, u.broken, u.health_status_code, u.health_checked_at, u.deleted_at, u.created_at"
 
-	querySelect := "select u.id, u.short_url, u.long_url, u.description, u.tl, u.hide"
+// userLinksQuery returns a query of links visible to a user, a select list must be set in place of %s
+func userLinksQuery(accountID, userID int64, filters []LinkFilter) (string, []interface{}) {
 
 	query := `
 	with url_group as (
@@ -152,7 +290,10 @@ func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset
 	)
 	%s
 	from (
-		select *, t.tags_list tl from links
+		select *, t.tags_list tl,
+			(select host from domains where domains.id = links.domain_id) domain_host,
+			(select default_redirect_type from accounts where accounts.id = links.account_id) account_redirect_type
+		from links
 		left join url_group ug on ug.link_id = links.id
 		left outer join url_tags t on t.link_id = links.id
 		where (links.account_id = $1 and not exists (select 1 from url_group)) 
@@ -162,9 +303,12 @@ func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset
 
 	queryArgs := []interface{}{accountID, userID}
 
-	var filterExpressions []string
+	filterExpressions := []string{"u.deleted_at is null"}
 
 	for _, f := range filters {
+		if f.Trash {
+			filterExpressions[0] = "u.deleted_at is not null"
+		}
 		if len(f.Tags) > 0 {
 			exp := []string{fmt.Sprintf("u.tl && $%d", len(queryArgs)+1)}
 			queryArgs = append(queryArgs, pq.Array(f.Tags))
@@ -201,11 +345,49 @@ func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset
 			filterExpressions = append(filterExpressions, fmt.Sprintf("u.id = $%d", len(queryArgs)+1))
 			queryArgs = append(queryArgs, f.LinkID)
 		}
+		if len(f.LinkIDs) > 0 {
+			filterExpressions = append(filterExpressions, fmt.Sprintf("u.id = any($%d)", len(queryArgs)+1))
+			queryArgs = append(queryArgs, pq.Array(f.LinkIDs))
+		}
 	}
 
-	if len(filterExpressions) > 0 {
-		query += "where " + strings.Join(filterExpressions, " AND ")
-	}
+	query += "where " + strings.Join(filterExpressions, " AND ")
+
+	return query, queryArgs
+}
+
+func scanUserLink(row rowScanner, link *Link, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&link.ID,
+		&link.Short,
+		&link.Long,
+		&link.Description,
+		pq.Array(&link.Tags),
+		&link.Hidden,
+		&link.ExpiresAt,
+		&link.MaxClicks,
+		&link.FallbackURL,
+		&link.Password,
+		&link.DomainID,
+		&link.Domain,
+		&link.RedirectType,
+		&link.AccountRedirectType,
+		&link.StickyVariants,
+		&link.SafetyScore,
+		&link.SafetyStatus,
+		pq.Array(&link.SafetyReasons),
+		&link.Broken,
+		&link.HealthStatusCode,
+		&link.HealthCheckedAt,
+		&link.DeletedAt,
+		&link.CreatedAt,
+	}, extra...)...)
+}
+
+// GetUserLinks ...
+func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset int64, filters ...LinkFilter) (*LinkResult, error) {
+
+	query, queryArgs := userLinksQuery(accountID, userID, filters)
 
 	var result LinkResult
 
@@ -215,7 +397,7 @@ func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset
 		return nil, err
 	}
 
-	query = fmt.Sprintf(query, querySelect) + " order by u.id desc"
+	query = fmt.Sprintf(query, userLinksSelect) + " order by u.id desc"
 	if limit > 0 {
 		queryArgs = append(queryArgs, limit)
 		query += fmt.Sprintf(" limit $%d", len(queryArgs))
@@ -232,28 +414,60 @@ func (repo *LinksRepository) GetUserLinks(accountID, userID int64, limit, offset
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var list []Link
 
 	for rows.Next() {
 		var link Link
-		err := rows.Scan(&link.ID, &link.Short, &link.Long, &link.Description, pq.Array(&link.Tags), &link.Hidden)
-		if err != nil {
+		if err := scanUserLink(rows, &link); err != nil {
 			return nil, err
 		}
 		list = append(list, link)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	result.Rows = list
 	return &result, nil
 }
 
+// StreamUserLinks reads links visible to a user one by one with names of their groups,
+// reading stops on the first error returned by f
+func (repo *LinksRepository) StreamUserLinks(accountID, userID int64, f func(Link) error, filters ...LinkFilter) error {
+
+	query, queryArgs := userLinksQuery(accountID, userID, filters)
+
+	query = fmt.Sprintf(query, userLinksSelect+`, (select array_agg(g.name order by g.name) from links_groups lg
+		inner join groups g on g.id = lg.group_id where lg.link_id = u.id)`) + " order by u.id desc"
+
+	rows, err := repo.DB.Query(query, queryArgs...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var link Link
+		if err := scanUserLink(rows, &link, pq.Array(&link.Groups)); err != nil {
+			return err
+		}
+		if err := f(link); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 // GetLinkByID ...
 func (repo *LinksRepository) GetLinkByID(linkID int64) (Link, error) {
 
 	var link Link
-	err := repo.DB.QueryRow(`
-		 select short_url, long_url, description from "links" where id = $1
-	`, linkID).Scan(&link.Short, &link.Long, &link.Description)
+	err := scanLink(repo.DB.QueryRow(`select `+linkFields+` from `+linkTables+` where links.id = $1 and links.deleted_at is null`, linkID), &link)
 
 	return link, err
 }
@@ -272,23 +486,125 @@ func (repo *LinksRepository) GetUserLinksCount(accountID int64, createdStartTime
 	return count, nil
 }
 
-// GenerateLink ...
-func (repo *LinksRepository) GenerateLink() string {
-	return utils.RandomString(5)
+// isReservedSlug checks a short url against global and account reserved words
+func (repo *LinksRepository) isReservedSlug(accountID int64, slug string) (bool, error) {
+	var reserved bool
+	err := repo.DB.QueryRow(`
+		select exists(select 1 from reserved_slugs where account_id in (0, $1) and lower(slug) = lower($2))
+	`, accountID, slug).Scan(&reserved)
+	return reserved, err
 }
 
-// CreateUserLink ...
-func (repo *LinksRepository) CreateUserLink(accountID int64, link *Link) (*sql.Tx, int64, error) {
-	var rowID int64
-	tx, err := repo.DB.Begin()
+// CheckSlug validates a custom short url and checks it against account reserved words and existing links of the domain
+func (repo *LinksRepository) CheckSlug(accountID, domainID int64, slug string) error {
+
+	if err := ValidateSlug(slug); err != nil {
+		return err
+	}
+
+	reserved, err := repo.isReservedSlug(accountID, slug)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
-	err = tx.QueryRow(
-		"insert into links (short_url, long_url, account_id, created_at) values ($1, $2, $3, now()) returning id",
-		link.Short, link.Long, accountID,
-	).Scan(&rowID)
+
+	if reserved {
+		return ErrSlugReserved
+	}
+
+	var exists bool
+	err = repo.DB.QueryRow(`
+		select exists(select 1 from links where domain_id = $1 and short_url = $2)
+	`, domainID, slug).Scan(&exists)
 	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrSlugConflict
+	}
+
+	return nil
+}
+
+// GetReservedSlugs returns words reserved by account, global words (account_id = 0) are included
+func (repo *LinksRepository) GetReservedSlugs(accountID int64) ([]ReservedSlug, error) {
+
+	rows, err := repo.DB.Query(`
+		select id, account_id, slug from reserved_slugs where account_id in (0, $1) order by slug
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []ReservedSlug
+	for rows.Next() {
+		var row ReservedSlug
+		if err := rows.Scan(&row.ID, &row.AccountID, &row.Slug); err != nil {
+			return nil, err
+		}
+		list = append(list, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// AddReservedSlug ...
+func (repo *LinksRepository) AddReservedSlug(accountID int64, slug string) (int64, error) {
+	var rowID int64
+	err := repo.DB.QueryRow(`
+		insert into reserved_slugs (account_id, slug) values ($1, $2) returning id
+	`, accountID, slug).Scan(&rowID)
+	if isUniqueViolation(err) {
+		return 0, ErrSlugReserved
+	}
+	return rowID, err
+}
+
+// DeleteReservedSlug ...
+func (repo *LinksRepository) DeleteReservedSlug(accountID, id int64) error {
+	_, err := repo.DB.Exec(`
+		delete from reserved_slugs where id = $1 and account_id = $2
+	`, id, accountID)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// CreateUserLink creates a link within a transaction to be committed by a caller,
+// links without a short url get a code from the account code generator
+func (repo *LinksRepository) CreateUserLink(accountID int64, link *Link) (*sql.Tx, int64, error) {
+	var rowID int64
+	var tx *sql.Tx
+	err := repo.insertWithCode(accountID, link, func() error {
+		var err error
+		tx, err = repo.DB.Begin()
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(`
+			insert into links (short_url, long_url, account_id, expires_at, max_clicks, fallback_url, password, domain_id, redirect_type,
+				sticky_variants, safety_score, safety_status, safety_reasons, normalized_url, safety_checked_at, created_at)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, now(), now()) returning id`,
+			link.Short, link.Long, accountID, link.ExpiresAt, link.MaxClicks, link.FallbackURL, link.Password, link.DomainID, link.RedirectType,
+			link.StickyVariants, link.SafetyScore, link.safetyStatus(), pq.Array(link.SafetyReasons), NormalizeURL(link.Long),
+		).Scan(&rowID)
+		if err != nil {
+			_ = tx.Rollback()
+		}
+		return err
+	})
+	if isUniqueViolation(err) {
+		return nil, 0, ErrSlugConflict
+	} else if err != nil {
 		return nil, 0, err
 	}
 
@@ -296,20 +612,28 @@ func (repo *LinksRepository) CreateUserLink(accountID int64, link *Link) (*sql.T
 	return tx, rowID, err
 }
 
-// UpdateUserLink ...
-func (repo *LinksRepository) UpdateUserLink(accountID, linkID int64, link *Link) (*sql.Tx, error) {
+// UpdateUserLink updates a link and stores its previous state as a version authored by userID
+func (repo *LinksRepository) UpdateUserLink(accountID, userID, linkID int64, link *Link) (*sql.Tx, error) {
 	tx, err := repo.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	_, err = tx.Exec(
-		"update links set long_url = $1, description = $2 where id = $3 and account_id = $4",
-		link.Long, link.Description, linkID, accountID,
+	err = repo.Update("links", versionColumns, tx, userID, linkID, `
+		update links set short_url = $1, long_url = $2, description = $3, expires_at = $4, max_clicks = $5, fallback_url = $6,
+		password = $7, redirect_type = $8, sticky_variants = $9, safety_score = $10, safety_status = $11, safety_reasons = $12,
+		safety_checked_at = now(), normalized_url = $15,
+		broken = (broken and long_url = $2), health_checked_at = case when long_url = $2 then health_checked_at end
+		where id = $13 and account_id = $14`,
+		link.Short, link.Long, link.Description, link.ExpiresAt, link.MaxClicks, link.FallbackURL, link.Password, link.RedirectType,
+		link.StickyVariants, link.SafetyScore, link.safetyStatus(), pq.Array(link.SafetyReasons), linkID, accountID, NormalizeURL(link.Long),
 	)
+	if isUniqueViolation(err) {
+		return tx, ErrSlugConflict
+	}
 	return tx, err
 }
 
-// DeleteUserLink ...
+// DeleteUserLink moves a link to trash, its short url stays reserved until the link is purged
 func (repo *LinksRepository) DeleteUserLink(accountID int64, linkID int64) (*sql.Tx, int64, error) {
 	var rowID int64
 	tx, err := repo.DB.Begin()
@@ -317,10 +641,10 @@ func (repo *LinksRepository) DeleteUserLink(accountID int64, linkID int64) (*sql
 		return nil, 0, err
 	}
 	err = tx.QueryRow(
-		"delete from links WHERE id = $1 AND account_id = $2 returning id", linkID, accountID,
+		"update links set deleted_at = now() where id = $1 and account_id = $2 and deleted_at is null returning id", linkID, accountID,
 	).Scan(&rowID)
 	if err != nil {
-		return nil, 0, err
+		return tx, 0, err
 	}
 	repo.callback("Delete", accountID, linkID)
 	return tx, rowID, err
@@ -342,33 +666,6 @@ func (repo *LinksRepository) DeleteUrlFromGroup(groupID, linkID int64) error {
 	return err
 }
 
-// BulkCreateLinks ...
-func (repo *LinksRepository) BulkCreateLinks(accountID int64, links []string) ([]Link, error) {
-
-	query := "insert into links (short_url, long_url, account_id) values "
-	var queryArgs []interface{}
-
-	var createdLinks []Link
-	for i, l := range links {
-		if i > 0 {
-			query += ", "
-		}
-		query += fmt.Sprintf("($%v, $%v, $%v)", i*3+1, i*3+2, i*3+3)
-
-		shortURL := repo.GenerateLink()
-		queryArgs = append(queryArgs, []interface{}{
-			shortURL, l, accountID,
-		}...)
-		createdLinks = append(createdLinks, Link{
-			Short: shortURL,
-			Long:  l,
-		})
-	}
-
-	_, err := repo.DB.Exec(query, queryArgs...)
-	return createdLinks, err
-}
-
 // HideUserLink ...
 func (repo *LinksRepository) HideUserLink(accountID int64, linkID int64) (*sql.Tx, error) {
 
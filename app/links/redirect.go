@@ -0,0 +1,55 @@
+package links
+
+import (
+	"errors"
+	"net/http"
+)
+
+// DefaultRedirectType is used when neither link nor account redirect type is set
+const DefaultRedirectType = http.StatusSeeOther
+
+// RedirectTypes is a list of http status codes allowed for redirects
+var RedirectTypes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+var (
+	// ErrInvalidRedirectType ...
+	ErrInvalidRedirectType = errors.New("redirect type must be one of 301, 302, 303, 307, 308")
+)
+
+// ValidateRedirectType checks redirect status code, zero value means default redirect type
+func ValidateRedirectType(code int) error {
+	if code == 0 {
+		return nil
+	}
+	for _, t := range RedirectTypes {
+		if t == code {
+			return nil
+		}
+	}
+	return ErrInvalidRedirectType
+}
+
+// StatusCode returns link redirect type falling back to account default one
+func (l Link) StatusCode() int {
+	if l.RedirectType != 0 {
+		return l.RedirectType
+	}
+	if l.AccountRedirectType != 0 {
+		return l.AccountRedirectType
+	}
+	return DefaultRedirectType
+}
+
+// StatusCode ...
+func (l CachedLink) StatusCode() int {
+	if l.RedirectType != 0 {
+		return l.RedirectType
+	}
+	return DefaultRedirectType
+}
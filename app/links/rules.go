@@ -0,0 +1,238 @@
+package links
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"shortly/app/urls"
+	"shortly/utils"
+)
+
+var (
+	// ErrRuleNoConditions ...
+	ErrRuleNoConditions = errors.New("rule must have at least one condition")
+	// ErrRuleInvalidCountry ...
+	ErrRuleInvalidCountry = errors.New("country must be an ISO 3166-1 alpha-2 code, e.g. DE")
+	// ErrRuleInvalidDevice ...
+	ErrRuleInvalidDevice = errors.New("device must be one of mobile, tablet, desktop, bot, ios, android, windows, macos, linux, chromeos")
+	// ErrRuleInvalidDestination ...
+	ErrRuleInvalidDestination = errors.New("rule destination url has incorrect format")
+	// ErrRuleInvalidFallback ...
+	ErrRuleInvalidFallback = errors.New("rule fallback must be a web url")
+)
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Rule is a conditional destination of a link, rules are evaluated by position
+// and the first matched rule overrides link long url
+type Rule struct {
+	ID        int64    `json:"id"`
+	LinkID    int64    `json:"-"`
+	Position  int      `json:"position"`
+	Countries []string `json:"countries,omitempty"`
+	// Devices are device classes or operating systems, see utils.UserAgentTargets
+	Devices     []string `json:"devices,omitempty"`
+	Destination string   `json:"destination"`
+	// Fallback is a web url opened when deep link destination can not be handled by device
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Visitor describes a redirect request for rules evaluation
+type Visitor struct {
+	// Country is an ISO 3166-1 alpha-2 country code
+	Country   string
+	UserAgent utils.UserAgent
+}
+
+// Matches ...
+func (r Rule) Matches(v Visitor) bool {
+	if len(r.Countries) > 0 && !containsFold(r.Countries, v.Country) {
+		return false
+	}
+	if len(r.Devices) > 0 && !matchesDevice(r.Devices, v.UserAgent) {
+		return false
+	}
+	return true
+}
+
+func matchesDevice(devices []string, ua utils.UserAgent) bool {
+	for _, d := range devices {
+		if ua.Matches(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeepLink checks whether url has an application specific scheme, e.g. myapp://product/1
+func IsDeepLink(rawURL string) bool {
+	return !urls.IsWeb(rawURL)
+}
+
+// ValidateRule normalizes rule conditions and canonicalizes rule urls, a destination may be a deep link
+func ValidateRule(rule *Rule) error {
+
+	if len(rule.Countries) == 0 && len(rule.Devices) == 0 {
+		return ErrRuleNoConditions
+	}
+
+	for i, c := range rule.Countries {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if !countryCodePattern.MatchString(c) {
+			return ErrRuleInvalidCountry
+		}
+		rule.Countries[i] = c
+	}
+
+	for i, d := range rule.Devices {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if !containsFold(utils.UserAgentTargets, d) {
+			return ErrRuleInvalidDevice
+		}
+		rule.Devices[i] = d
+	}
+
+	destination, err := urls.Canonicalize(rule.Destination, urls.DeepLink)
+	if err != nil {
+		return ErrRuleInvalidDestination
+	}
+	rule.Destination = destination
+
+	if rule.Fallback != "" {
+		fallback, err := urls.Canonicalize(rule.Fallback, urls.Web)
+		if err != nil {
+			return ErrRuleInvalidFallback
+		}
+		rule.Fallback = fallback
+	}
+
+	return nil
+}
+
+// Destination returns a long url for visitor and a rule which has been matched
+func (l CachedLink) Destination(v Visitor) (string, *Rule) {
+	for i := range l.Rules {
+		if l.Rules[i].Matches(v) {
+			return l.Rules[i].Destination, &l.Rules[i]
+		}
+	}
+	return l.Long, nil
+}
+
+func containsFold(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkRulesField aggregates link rules into a json array, so rules are loaded with the link itself
+const linkRulesField = `coalesce((
+		select json_agg(json_build_object(
+			'id', link_rules.id, 'position', link_rules.position,
+			'countries', link_rules.countries, 'devices', link_rules.devices,
+			'destination', link_rules.destination, 'fallback', link_rules.fallback
+		) order by link_rules.position, link_rules.id)
+		from link_rules where link_rules.link_id = links.id
+	), '[]')`
+
+// rulesJSON is a scan destination for linkRulesField
+type rulesJSON []Rule
+
+// Scan ...
+func (r *rulesJSON) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*r = nil
+		return nil
+	default:
+		return errors.New("unsupported link rules type")
+	}
+	return json.Unmarshal(data, (*[]Rule)(r))
+}
+
+// GetLinkRules ...
+func (repo *LinksRepository) GetLinkRules(linkID int64) ([]Rule, error) {
+
+	rows, err := repo.DB.Query(`
+		select id, link_id, position, countries, devices, destination, fallback from link_rules
+		where link_id = $1 order by position, id
+	`, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Rule
+	for rows.Next() {
+		var rule Rule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.LinkID,
+			&rule.Position,
+			pq.Array(&rule.Countries),
+			pq.Array(&rule.Devices),
+			&rule.Destination,
+			&rule.Fallback,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CreateLinkRule ...
+func (repo *LinksRepository) CreateLinkRule(linkID int64, rule Rule) (int64, error) {
+	var rowID int64
+	err := repo.DB.QueryRow(`
+		insert into link_rules (link_id, position, countries, devices, destination, fallback)
+		values ($1, $2, $3, $4, $5, $6) returning id
+	`, linkID, rule.Position, pq.Array(rule.Countries), pq.Array(rule.Devices), rule.Destination, rule.Fallback).Scan(&rowID)
+	return rowID, err
+}
+
+// UpdateLinkRule ...
+func (repo *LinksRepository) UpdateLinkRule(linkID int64, rule Rule) error {
+	res, err := repo.DB.Exec(`
+		update link_rules set position = $1, countries = $2, devices = $3, destination = $4, fallback = $5
+		where id = $6 and link_id = $7
+	`, rule.Position, pq.Array(rule.Countries), pq.Array(rule.Devices), rule.Destination, rule.Fallback, rule.ID, linkID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteLinkRule ...
+func (repo *LinksRepository) DeleteLinkRule(linkID, ruleID int64) error {
+	_, err := repo.DB.Exec(`
+		delete from link_rules where id = $1 and link_id = $2
+	`, ruleID, linkID)
+	return err
+}
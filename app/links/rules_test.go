@@ -0,0 +1,112 @@
+package links
+
+import (
+	"testing"
+
+	"shortly/utils"
+)
+
+func TestValidateRule(t *testing.T) {
+
+	cases := []struct {
+		rule Rule
+		err  error
+	}{
+		{Rule{Countries: []string{"de", " AT "}, Destination: "https://example.de"}, nil},
+		{Rule{Destination: "https://example.de"}, ErrRuleNoConditions},
+		{Rule{Countries: []string{"Germany"}, Destination: "https://example.de"}, ErrRuleInvalidCountry},
+		{Rule{Countries: []string{"DE"}}, ErrRuleInvalidDestination},
+		{Rule{Devices: []string{"iOS"}, Destination: "myapp://product/1", Fallback: "https://apps.apple.com/app/id1"}, nil},
+		{Rule{Devices: []string{"iphone"}, Destination: "https://example.com"}, ErrRuleInvalidDevice},
+		{Rule{Devices: []string{"android"}, Destination: "javascript:alert(1)"}, ErrRuleInvalidDestination},
+		{Rule{Devices: []string{"android"}, Destination: "myapp://product/1", Fallback: "otherapp://"}, ErrRuleInvalidFallback},
+		{Rule{Devices: []string{"android"}, Destination: "data:text/html,hi"}, ErrRuleInvalidDestination},
+	}
+
+	for _, c := range cases {
+		if err := ValidateRule(&c.rule); err != c.err {
+			t.Errorf("rule(%v): expected error %v, got %v", c.rule.Countries, c.err, err)
+		}
+	}
+}
+
+func TestCachedLinkDestination(t *testing.T) {
+
+	var rules rulesJSON
+	err := rules.Scan([]byte(`[
+		{"id": 1, "position": 0, "countries": ["DE", "AT"], "destination": "example.de"},
+		{"id": 2, "position": 1, "countries": ["FR"], "destination": "example.fr"}
+	]`))
+	if err != nil {
+		t.Fatalf("unexpected rules scan error: %v", err)
+	}
+
+	link := Link{Long: "example.com", Rules: rules}.Cached()
+
+	decoded, ok := DecodeCachedLink(link.Encode())
+	if !ok {
+		t.Fatalf("cached link is not decoded")
+	}
+
+	cases := []struct {
+		country     string
+		destination string
+		ruleID      int64
+	}{
+		{"AT", "example.de", 1},
+		{"fr", "example.fr", 2},
+		{"US", "example.com", 0},
+		{"", "example.com", 0},
+	}
+
+	for _, c := range cases {
+		destination, rule := decoded.Destination(Visitor{Country: c.country})
+		if destination != c.destination {
+			t.Errorf("country(%s): expected destination %s, got %s", c.country, c.destination, destination)
+		}
+		var ruleID int64
+		if rule != nil {
+			ruleID = rule.ID
+		}
+		if ruleID != c.ruleID {
+			t.Errorf("country(%s): expected rule %d, got %d", c.country, c.ruleID, ruleID)
+		}
+	}
+}
+
+func TestDeviceRules(t *testing.T) {
+
+	link := CachedLink{
+		Long: "https://example.com",
+		Rules: []Rule{
+			{ID: 1, Devices: []string{"ios"}, Destination: "https://apps.apple.com/app/id1"},
+			{ID: 2, Devices: []string{"android"}, Countries: []string{"DE"}, Destination: "market://details?id=com.example"},
+			{ID: 3, Devices: []string{"android"}, Destination: "https://play.google.com/store/apps/details?id=com.example"},
+		},
+	}
+
+	cases := []struct {
+		visitor Visitor
+		ruleID  int64
+	}{
+		{Visitor{UserAgent: utils.UserAgent{Device: utils.DeviceMobile, OS: utils.OSIOS}}, 1},
+		{Visitor{Country: "DE", UserAgent: utils.UserAgent{Device: utils.DeviceMobile, OS: utils.OSAndroid}}, 2},
+		{Visitor{Country: "US", UserAgent: utils.UserAgent{Device: utils.DeviceTablet, OS: utils.OSAndroid}}, 3},
+		{Visitor{UserAgent: utils.UserAgent{Device: utils.DeviceDesktop, OS: utils.OSWindows}}, 0},
+	}
+
+	for _, c := range cases {
+		_, rule := link.Destination(c.visitor)
+		var ruleID int64
+		if rule != nil {
+			ruleID = rule.ID
+		}
+		if ruleID != c.ruleID {
+			t.Errorf("visitor(%v): expected rule %d, got %d", c.visitor, c.ruleID, ruleID)
+		}
+	}
+
+	if !IsDeepLink("market://details?id=com.example") || IsDeepLink("https://example.com") || IsDeepLink("example.com") {
+		t.Errorf("deep links are not detected correctly")
+	}
+}
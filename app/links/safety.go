@@ -0,0 +1,65 @@
+package links
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Link safety statuses
+const (
+	SafetyOK = "ok"
+	// SafetyQuarantined links are suspicious, they are not redirected until recheck clears them
+	SafetyQuarantined = "quarantined"
+	// SafetyBlocked links point to blocklisted destinations
+	SafetyBlocked = "blocked"
+)
+
+// Safe ...
+func (l CachedLink) Safe() bool {
+	return l.SafetyStatus == "" || l.SafetyStatus == SafetyOK
+}
+
+// safetyStatus treats links without a safety check as safe
+func (l Link) safetyStatus() string {
+	if l.SafetyStatus == "" {
+		return SafetyOK
+	}
+	return l.SafetyStatus
+}
+
+// UpdateLinkSafety stores a result of a link destination check
+func (repo *LinksRepository) UpdateLinkSafety(linkID int64, score int, status string, reasons []string) error {
+	_, err := repo.DB.Exec(`
+		update links set safety_score = $1, safety_status = $2, safety_reasons = $3, safety_checked_at = now()
+		where id = $4`,
+		score, status, pq.Array(reasons), linkID,
+	)
+	return err
+}
+
+// GetLinksToCheck returns active links which were not checked since a provided time, the oldest checks go first
+func (repo *LinksRepository) GetLinksToCheck(checkedBefore time.Time, limit int) ([]Link, error) {
+
+	rows, err := repo.DB.Query("select "+linkFields+" from "+linkTables+`
+		where links.hide = false and links.deleted_at is null and (links.safety_checked_at is null or links.safety_checked_at < $1)
+		order by links.safety_checked_at nulls first, links.id limit $2`,
+		checkedBefore, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Link
+	for rows.Next() {
+		var link Link
+		if err := scanLink(rows, &link); err != nil {
+			return nil, err
+		}
+		list = append(list, link)
+	}
+
+	return list, rows.Err()
+}
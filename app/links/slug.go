@@ -0,0 +1,77 @@
+package links
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SlugMinLength ...
+	SlugMinLength = 3
+	// SlugMaxLength is limited by the size of the short_url column
+	SlugMaxLength = 20
+)
+
+var (
+	// ErrSlugTooShort ...
+	ErrSlugTooShort = errors.New("short url is too short")
+	// ErrSlugTooLong ...
+	ErrSlugTooLong = errors.New("short url is too long")
+	// ErrSlugInvalidChars ...
+	ErrSlugInvalidChars = errors.New("short url may contain only latin letters, digits, '-' and '_' and must start with a letter or digit")
+	// ErrSlugReserved ...
+	ErrSlugReserved = errors.New("short url is reserved")
+	// ErrSlugConflict ...
+	ErrSlugConflict = errors.New("short url is already taken")
+)
+
+// ReservedSlugs is a global list of names which can not be used as short urls,
+// it mostly consists of the top level routes served by the application
+var ReservedSlugs = []string{
+	"admin",
+	"api",
+	"health",
+	"maintance",
+	"metrics",
+	"qr",
+	"static",
+	"swagger",
+	"webhook",
+}
+
+var slugPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// ValidateSlug checks that a custom short url has an allowed length and characters set
+// and is not listed in global reserved words
+func ValidateSlug(slug string) error {
+
+	if len(slug) < SlugMinLength {
+		return ErrSlugTooShort
+	}
+
+	if len(slug) > SlugMaxLength {
+		return ErrSlugTooLong
+	}
+
+	if !slugPattern.MatchString(slug) {
+		return ErrSlugInvalidChars
+	}
+
+	for _, reserved := range ReservedSlugs {
+		if strings.EqualFold(slug, reserved) {
+			return ErrSlugReserved
+		}
+	}
+
+	return nil
+}
+
+// IsSlugError returns true if an error is caused by an incorrect short url provided by user
+func IsSlugError(err error) bool {
+	switch err {
+	case ErrSlugTooShort, ErrSlugTooLong, ErrSlugInvalidChars, ErrSlugReserved, ErrSlugConflict:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package links
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateSlug(t *testing.T) {
+
+	cases := []struct {
+		slug string
+		err  error
+	}{
+		{"spring-sale", nil},
+		{"Sale_2020", nil},
+		{"abc", nil},
+		{"ab", ErrSlugTooShort},
+		{"this-slug-is-way-too-long", ErrSlugTooLong},
+		{"-sale", ErrSlugInvalidChars},
+		{"sale/2020", ErrSlugInvalidChars},
+		{"скидки", ErrSlugInvalidChars},
+		{"sale!", ErrSlugInvalidChars},
+		{"admin", ErrSlugReserved},
+		{"API", ErrSlugReserved},
+		{"static", ErrSlugReserved},
+	}
+
+	for _, c := range cases {
+		if err := ValidateSlug(c.slug); err != c.err {
+			t.Errorf("slug(%s): expected error %v, got %v", c.slug, c.err, err)
+		}
+	}
+}
+
+func TestCheckSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	accountID := int64(10)
+
+	mock.ExpectQuery("select exists(.+) from reserved_slugs").WithArgs(accountID, "team-only").WillReturnRows(
+		sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	mock.ExpectQuery("select exists(.+) from reserved_slugs").WithArgs(accountID, "spring-sale").WillReturnRows(
+		sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("select exists(.+) from links").WithArgs(int64(0), "spring-sale").WillReturnRows(
+		sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	repo := &LinksRepository{DB: db}
+
+	if err := repo.CheckSlug(accountID, 0, "team-only"); err != ErrSlugReserved {
+		t.Errorf("expected reserved error, got %v", err)
+	}
+
+	if err := repo.CheckSlug(accountID, 0, "spring-sale"); err != ErrSlugConflict {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	// we make sure that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,70 @@
+package links
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetTrashedLink returns sql.ErrNoRows when a link is not in trash of an account
+func (repo *LinksRepository) GetTrashedLink(accountID, linkID int64) (Link, error) {
+
+	var link Link
+	err := scanLink(repo.DB.QueryRow(`select `+linkFields+` from `+linkTables+`
+		where links.id = $1 and links.account_id = $2 and links.deleted_at is not null`, linkID, accountID,
+	), &link)
+
+	return link, err
+}
+
+// RestoreUserLink moves a link out of trash
+func (repo *LinksRepository) RestoreUserLink(accountID, linkID int64) error {
+
+	result, err := repo.DB.Exec(
+		"update links set deleted_at = null where id = $1 and account_id = $2 and deleted_at is not null", linkID, accountID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// PurgeLink removes a link from trash permanently
+func (repo *LinksRepository) PurgeLink(linkID int64) error {
+
+	var rowID int64
+	return repo.DB.QueryRow(
+		"delete from links where id = $1 and deleted_at is not null returning id", linkID,
+	).Scan(&rowID)
+}
+
+// GetLinksToPurge returns links deleted before a provided time
+func (repo *LinksRepository) GetLinksToPurge(deletedBefore time.Time, limit int) ([]Link, error) {
+
+	rows, err := repo.DB.Query("select "+linkFields+" from "+linkTables+`
+		where links.deleted_at < $1 order by links.deleted_at limit $2`,
+		deletedBefore, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Link
+	for rows.Next() {
+		var link Link
+		if err := scanLink(rows, &link); err != nil {
+			return nil, err
+		}
+		list = append(list, link)
+	}
+
+	return list, rows.Err()
+}
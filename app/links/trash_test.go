@@ -0,0 +1,48 @@
+package links
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDeleteAndRestoreUserLink(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	accountID, linkID := int64(10), int64(5)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("update links set deleted_at = now()").WithArgs(linkID, accountID).WillReturnRows(
+		sqlmock.NewRows([]string{"id"}).AddRow(linkID))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("update links set deleted_at = null").WithArgs(linkID, accountID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("update links set deleted_at = null").WithArgs(linkID, accountID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &LinksRepository{DB: db}
+
+	tx, rowID, err := repo.DeleteUserLink(accountID, linkID)
+	if err != nil || rowID != linkID {
+		t.Fatalf("unexpected delete result %v, %v", rowID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.RestoreUserLink(accountID, linkID); err != nil {
+		t.Errorf("expected restored link, got %v", err)
+	}
+
+	if err := repo.RestoreUserLink(accountID, linkID); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error for a link out of trash, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,106 @@
+package links
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+)
+
+// UTM override modes of campaign channel links
+const (
+	// UTMPreserve keeps parameters already present on a destination url
+	UTMPreserve = "preserve"
+	// UTMOverride replaces destination parameters with campaign values
+	UTMOverride = "override"
+)
+
+// ErrInvalidUTMMode ...
+var ErrInvalidUTMMode = errors.New("utm mode must be one of: preserve, override")
+
+// ValidateUTMMode allows an empty mode which means UTMPreserve
+func ValidateUTMMode(mode string) error {
+	switch mode {
+	case "", UTMPreserve, UTMOverride:
+		return nil
+	}
+	return ErrInvalidUTMMode
+}
+
+// UTM is a set of utm parameters of an active campaign channel a link belongs to
+type UTM struct {
+	Campaign string `json:"campaign,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// Apply merges utm parameters into destination query string,
+// empty values are never written
+func (u UTM) Apply(destination *url.URL) {
+
+	query := destination.Query()
+	changed := false
+
+	for _, param := range [][2]string{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	} {
+		if param[1] == "" {
+			continue
+		}
+		if _, ok := query[param[0]]; ok && u.Mode != UTMOverride {
+			continue
+		}
+		query.Set(param[0], param[1])
+		changed = true
+	}
+
+	if changed {
+		destination.RawQuery = query.Encode()
+	}
+}
+
+// linkUTMField reads utm parameters of a link from an active campaign
+const linkUTMField = `(
+		select json_build_object(
+			'campaign', campaigns.name, 'source', cl.utm_source, 'medium', cl.utm_medium,
+			'term', cl.utm_term, 'content', cl.utm_content, 'mode', cl.utm_mode
+		)
+		from campaigns_channels_links cl
+		inner join campaigns_channels on campaigns_channels.id = cl.chan_campaign_id
+		inner join campaigns on campaigns.id = campaigns_channels.campaign_id
+		where cl.link_id = links.id and campaigns.active = true
+		order by cl.id limit 1
+	)`
+
+// utmJSON is a scan destination for linkUTMField, links out of active campaigns have no utm
+type utmJSON struct {
+	utm **UTM
+}
+
+// Scan ...
+func (u utmJSON) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*u.utm = nil
+		return nil
+	default:
+		return errors.New("unsupported link utm type")
+	}
+	var utm UTM
+	if err := json.Unmarshal(data, &utm); err != nil {
+		return err
+	}
+	*u.utm = &utm
+	return nil
+}
@@ -0,0 +1,64 @@
+package links
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestUTMApply(t *testing.T) {
+
+	utm := UTM{Campaign: "spring sale", Source: "newsletter", Medium: "email"}
+
+	tests := []struct {
+		name        string
+		destination string
+		mode        string
+		expected    string
+	}{
+		{
+			name:        "no query",
+			destination: "https://example.com/page",
+			expected:    "https://example.com/page?utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:        "other parameters are kept",
+			destination: "https://example.com/page?id=1",
+			expected:    "https://example.com/page?id=1&utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:        "destination parameters are preserved",
+			destination: "https://example.com/page?utm_source=blog",
+			mode:        UTMPreserve,
+			expected:    "https://example.com/page?utm_campaign=spring+sale&utm_medium=email&utm_source=blog",
+		},
+		{
+			name:        "destination parameters are overridden",
+			destination: "https://example.com/page?utm_source=blog",
+			mode:        UTMOverride,
+			expected:    "https://example.com/page?utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination, err := url.Parse(tt.destination)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			linkUTM := utm
+			linkUTM.Mode = tt.mode
+			linkUTM.Apply(destination)
+
+			if destination.String() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, destination.String())
+			}
+		})
+	}
+
+	destination, _ := url.Parse("https://example.com/page?b=2&a=1")
+	UTM{}.Apply(destination)
+	if destination.RawQuery != "b=2&a=1" {
+		t.Errorf("empty utm must not change destination, got %s", destination.RawQuery)
+	}
+}
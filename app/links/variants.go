@@ -0,0 +1,163 @@
+package links
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"shortly/app/urls"
+)
+
+const (
+	// VariantMaxWeight ...
+	VariantMaxWeight = 1000
+)
+
+var (
+	// ErrVariantInvalidWeight ...
+	ErrVariantInvalidWeight = errors.New("variant weight must be between 1 and 1000")
+	// ErrVariantInvalidDestination ...
+	ErrVariantInvalidDestination = errors.New("variant destination url has incorrect format")
+)
+
+// Variant is a weighted destination of a link used for split testing
+type Variant struct {
+	ID          int64  `json:"id"`
+	LinkID      int64  `json:"-"`
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// ValidateVariant checks a variant weight and canonicalizes its destination
+func ValidateVariant(v *Variant) error {
+	if v.Weight < 1 || v.Weight > VariantMaxWeight {
+		return ErrVariantInvalidWeight
+	}
+	destination, err := urls.Canonicalize(v.Destination, urls.Destination)
+	if err != nil {
+		return ErrVariantInvalidDestination
+	}
+	v.Destination = destination
+	return nil
+}
+
+// PickVariant chooses a variant proportionally to variants weights,
+// intn must return a random number in [0, n), e.g. rand.Intn
+func (l CachedLink) PickVariant(intn func(n int) int) *Variant {
+
+	var total int
+	for _, v := range l.Variants {
+		total += v.Weight
+	}
+
+	if total <= 0 {
+		return nil
+	}
+
+	n := intn(total)
+	for i := range l.Variants {
+		n -= l.Variants[i].Weight
+		if n < 0 {
+			return &l.Variants[i]
+		}
+	}
+
+	return nil
+}
+
+// VariantByID is used to restore a sticky variant assignment
+func (l CachedLink) VariantByID(id int64) *Variant {
+	for i := range l.Variants {
+		if l.Variants[i].ID == id {
+			return &l.Variants[i]
+		}
+	}
+	return nil
+}
+
+// linkVariantsField aggregates link variants into a json array, see linkRulesField
+const linkVariantsField = `coalesce((
+		select json_agg(json_build_object(
+			'id', link_variants.id, 'destination', link_variants.destination, 'weight', link_variants.weight
+		) order by link_variants.id)
+		from link_variants where link_variants.link_id = links.id
+	), '[]')`
+
+// variantsJSON is a scan destination for linkVariantsField
+type variantsJSON []Variant
+
+// Scan ...
+func (v *variantsJSON) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	case nil:
+		*v = nil
+		return nil
+	default:
+		return errors.New("unsupported link variants type")
+	}
+	return json.Unmarshal(data, (*[]Variant)(v))
+}
+
+// GetLinkVariants ...
+func (repo *LinksRepository) GetLinkVariants(linkID int64) ([]Variant, error) {
+
+	rows, err := repo.DB.Query(`
+		select id, link_id, destination, weight from link_variants where link_id = $1 order by id
+	`, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []Variant
+	for rows.Next() {
+		var v Variant
+		if err := rows.Scan(&v.ID, &v.LinkID, &v.Destination, &v.Weight); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CreateLinkVariant ...
+func (repo *LinksRepository) CreateLinkVariant(linkID int64, v Variant) (int64, error) {
+	var rowID int64
+	err := repo.DB.QueryRow(`
+		insert into link_variants (link_id, destination, weight) values ($1, $2, $3) returning id
+	`, linkID, v.Destination, v.Weight).Scan(&rowID)
+	return rowID, err
+}
+
+// UpdateLinkVariant ...
+func (repo *LinksRepository) UpdateLinkVariant(linkID int64, v Variant) error {
+	res, err := repo.DB.Exec(`
+		update link_variants set destination = $1, weight = $2 where id = $3 and link_id = $4
+	`, v.Destination, v.Weight, v.ID, linkID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteLinkVariant ...
+func (repo *LinksRepository) DeleteLinkVariant(linkID, variantID int64) error {
+	_, err := repo.DB.Exec(`
+		delete from link_variants where id = $1 and link_id = $2
+	`, variantID, linkID)
+	return err
+}
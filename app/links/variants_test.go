@@ -0,0 +1,59 @@
+package links
+
+import (
+	"testing"
+)
+
+func TestPickVariant(t *testing.T) {
+
+	link := CachedLink{
+		Long: "https://example.com",
+		Variants: []Variant{
+			{ID: 1, Destination: "https://example.com/a", Weight: 70},
+			{ID: 2, Destination: "https://example.com/b", Weight: 30},
+		},
+	}
+
+	counts := make(map[int64]int)
+	for n := 0; n < 100; n++ {
+		variant := link.PickVariant(func(total int) int {
+			if total != 100 {
+				t.Fatalf("expected total weight 100, got %d", total)
+			}
+			return n
+		})
+		counts[variant.ID]++
+	}
+
+	if counts[1] != 70 || counts[2] != 30 {
+		t.Errorf("expected 70/30 split, got %d/%d", counts[1], counts[2])
+	}
+
+	if v := link.VariantByID(2); v == nil || v.Destination != "https://example.com/b" {
+		t.Errorf("variant is not found by id")
+	}
+
+	if v := (CachedLink{}).PickVariant(func(int) int { return 0 }); v != nil {
+		t.Errorf("link without variants must not pick a variant")
+	}
+}
+
+func TestValidateVariant(t *testing.T) {
+
+	cases := []struct {
+		variant Variant
+		err     error
+	}{
+		{Variant{Destination: "https://example.com/a", Weight: 70}, nil},
+		{Variant{Destination: "https://example.com/a"}, ErrVariantInvalidWeight},
+		{Variant{Destination: "https://example.com/a", Weight: VariantMaxWeight + 1}, ErrVariantInvalidWeight},
+		{Variant{Weight: 10}, ErrVariantInvalidDestination},
+		{Variant{Destination: "javascript:alert(1)", Weight: 10}, ErrVariantInvalidDestination},
+	}
+
+	for _, c := range cases {
+		if err := ValidateVariant(&c.variant); err != c.err {
+			t.Errorf("variant(%v): expected error %v, got %v", c.variant, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package links
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrNoPreviousState is returned for versions which do not keep a link state before the change
+var ErrNoPreviousState = errors.New("version has no previous state")
+
+// LinkState is a versioned part of a link, fields are named as links table columns in audit snapshots
+type LinkState struct {
+	Long         string `json:"long_url"`
+	Description  string `json:"description"`
+	FallbackURL  string `json:"fallback_url"`
+	RedirectType int    `json:"redirect_type"`
+}
+
+// versionColumns are links table columns of LinkState, link versions keep these columns only
+var versionColumns = []string{"long_url", "description", "fallback_url", "redirect_type"}
+
+// Apply sets versioned fields of a link
+func (s LinkState) Apply(link *Link) {
+	link.Long = s.Long
+	link.Description = s.Description
+	link.FallbackURL = s.FallbackURL
+	link.RedirectType = s.RedirectType
+}
+
+// LinkChange ...
+type LinkChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// LinkVersion is a single change of a link, State is a link state after the change
+type LinkVersion struct {
+	ID       int64
+	Action   string
+	Time     time.Time
+	UserID   int64
+	Username string
+	State    LinkState
+	Previous *LinkState
+}
+
+// Changes lists versioned fields modified by the version
+func (v LinkVersion) Changes() []LinkChange {
+	var old LinkState
+	if v.Previous != nil {
+		old = *v.Previous
+	}
+
+	var changes []LinkChange
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, LinkChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	add("long", old.Long, v.State.Long)
+	add("description", old.Description, v.State.Description)
+	add("fallbackUrl", old.FallbackURL, v.State.FallbackURL)
+	add("redirectType", strconv.Itoa(old.RedirectType), strconv.Itoa(v.State.RedirectType))
+	return changes
+}
+
+const linkVersionsQuery = `
+	select audit.id, audit.action, audit.timestamp, coalesce(audit.user_id, 0), coalesce(users.username, ''),
+		audit.snapshot, audit.previous
+	from audit
+	left join users on users.id = audit.user_id
+	where audit.entity = 'links' and audit.entity_id = $1`
+
+func scanLinkVersion(row rowScanner, version *LinkVersion) error {
+	var snapshot, previous []byte
+	err := row.Scan(&version.ID, &version.Action, &version.Time, &version.UserID, &version.Username, &snapshot, &previous)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(snapshot, &version.State); err != nil {
+		return err
+	}
+	if previous != nil {
+		version.Previous = &LinkState{}
+		return json.Unmarshal(previous, version.Previous)
+	}
+	return nil
+}
+
+// GetLinkVersions returns link changes, latest first
+func (repo *LinksRepository) GetLinkVersions(linkID int64) ([]LinkVersion, error) {
+
+	rows, err := repo.DB.Query(linkVersionsQuery+" order by audit.id desc", linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var list []LinkVersion
+	for rows.Next() {
+		var version LinkVersion
+		if err := scanLinkVersion(rows, &version); err != nil {
+			return nil, err
+		}
+		list = append(list, version)
+	}
+
+	return list, rows.Err()
+}
+
+// GetLinkVersion returns sql.ErrNoRows when a version does not belong to a link
+func (repo *LinksRepository) GetLinkVersion(linkID, versionID int64) (LinkVersion, error) {
+	var version LinkVersion
+	err := scanLinkVersion(repo.DB.QueryRow(linkVersionsQuery+" and audit.id = $2", linkID, versionID), &version)
+	return version, err
+}
+
+// RollbackState returns a link state before the version, rollback to it undoes the version and all later changes
+func (v LinkVersion) RollbackState() (LinkState, error) {
+	if v.Previous == nil {
+		return LinkState{}, ErrNoPreviousState
+	}
+	return *v.Previous, nil
+}
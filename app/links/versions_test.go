@@ -0,0 +1,73 @@
+package links
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLinkVersionChanges(t *testing.T) {
+
+	version := LinkVersion{
+		Previous: &LinkState{Long: "https://example.com/old", Description: "sale", RedirectType: 301},
+		State:    LinkState{Long: "https://example.com/new", Description: "sale", RedirectType: 302},
+	}
+
+	changes := version.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if changes[0] != (LinkChange{Field: "long", Old: "https://example.com/old", New: "https://example.com/new"}) {
+		t.Errorf("unexpected change %+v", changes[0])
+	}
+	if changes[1] != (LinkChange{Field: "redirectType", Old: "301", New: "302"}) {
+		t.Errorf("unexpected change %+v", changes[1])
+	}
+
+	state, err := version.RollbackState()
+	if err != nil || state.Long != "https://example.com/old" {
+		t.Errorf("expected previous state, got %+v, %v", state, err)
+	}
+
+	if _, err := (LinkVersion{}).RollbackState(); err != ErrNoPreviousState {
+		t.Errorf("expected no previous state error, got %v", err)
+	}
+}
+
+func TestGetLinkVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "action", "timestamp", "user_id", "username", "snapshot", "previous"}
+	now := time.Now()
+
+	mock.ExpectQuery("select (.+) from audit").WithArgs(int64(5), int64(7)).WillReturnRows(
+		sqlmock.NewRows(columns).AddRow(int64(7), "update", now, int64(3), "alice",
+			[]byte(`{"id": 5, "long_url": "https://example.com/new", "description": null, "password": "hash"}`),
+			[]byte(`{"id": 5, "long_url": "https://example.com/old", "description": "sale"}`)))
+
+	mock.ExpectQuery("select (.+) from audit").WithArgs(int64(5), int64(8)).WillReturnError(sql.ErrNoRows)
+
+	repo := &LinksRepository{DB: db}
+
+	version, err := repo.GetLinkVersion(5, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.Username != "alice" || version.State.Long != "https://example.com/new" || version.Previous.Description != "sale" {
+		t.Errorf("unexpected version %+v", version)
+	}
+
+	if _, err := repo.GetLinkVersion(5, 8); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,153 @@
+package pages
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shortly/app/urls"
+)
+
+const (
+	// MaxItems is a number of links allowed on a page
+	MaxItems = 100
+	// maxTitleLength limits page and item titles
+	maxTitleLength = 100
+	// maxDescriptionLength limits page descriptions
+	maxDescriptionLength = 500
+)
+
+// Theme is a color scheme of a published page
+type Theme struct {
+	Background string
+	Text       string
+	Button     string
+	ButtonText string
+}
+
+// Themes are color schemes pages can use, DefaultTheme is used when none is set
+var Themes = map[string]Theme{
+	"light":  {Background: "#f4f6f9", Text: "#212529", Button: "#ffffff", ButtonText: "#212529"},
+	"dark":   {Background: "#16181d", Text: "#f1f3f5", Button: "#2b2f36", ButtonText: "#f1f3f5"},
+	"ocean":  {Background: "#0b4f6c", Text: "#ffffff", Button: "#01baef", ButtonText: "#ffffff"},
+	"sunset": {Background: "#ff7e5f", Text: "#ffffff", Button: "#feb47b", ButtonText: "#3d1f0f"},
+	"forest": {Background: "#1b4332", Text: "#ffffff", Button: "#40916c", ButtonText: "#ffffff"},
+}
+
+// DefaultTheme ...
+const DefaultTheme = "light"
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,31}$`)
+
+var (
+	// ErrInvalidHandle ...
+	ErrInvalidHandle = errors.New("handle must be 3 to 32 lowercase letters, digits, dashes or underscores")
+	// ErrHandleTaken ...
+	ErrHandleTaken = errors.New("handle is already taken")
+	// ErrUnknownTheme ...
+	ErrUnknownTheme = errors.New("unknown theme")
+	// ErrTitleTooLong ...
+	ErrTitleTooLong = errors.New("title is too long")
+	// ErrDescriptionTooLong ...
+	ErrDescriptionTooLong = errors.New("description is too long")
+	// ErrTooManyItems ...
+	ErrTooManyItems = errors.New("page has too many links")
+	// ErrDuplicateItem ...
+	ErrDuplicateItem = errors.New("link is added to page twice")
+)
+
+// Page is a hosted link-in-bio page listing an ordered set of account links
+type Page struct {
+	ID          int64
+	AccountID   int64
+	Handle      string
+	Title       string
+	Description string
+	AvatarURL   string
+	Theme       string
+	// Published pages are served at /p/{handle}
+	Published bool
+	Items     []Item
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Item is a link shown on a page, Title and Icon override link description on the page
+type Item struct {
+	ID       int64
+	LinkID   int64
+	Position int
+	Title    string
+	Icon     string
+}
+
+// Validate checks page fields, the handle is lowercased and the avatar url is canonicalized
+func (p *Page) Validate() error {
+
+	p.Handle = strings.ToLower(strings.TrimSpace(p.Handle))
+	if !handlePattern.MatchString(p.Handle) {
+		return ErrInvalidHandle
+	}
+
+	if p.Theme == "" {
+		p.Theme = DefaultTheme
+	}
+	if _, ok := Themes[p.Theme]; !ok {
+		return ErrUnknownTheme
+	}
+
+	p.Title = strings.TrimSpace(p.Title)
+	if utf8.RuneCountInString(p.Title) > maxTitleLength {
+		return ErrTitleTooLong
+	}
+	if utf8.RuneCountInString(p.Description) > maxDescriptionLength {
+		return ErrDescriptionTooLong
+	}
+
+	if p.AvatarURL != "" {
+		avatarURL, err := urls.Canonicalize(p.AvatarURL, urls.Web)
+		if err != nil {
+			return err
+		}
+		p.AvatarURL = avatarURL
+	}
+
+	return nil
+}
+
+// ValidateItems checks page items, icon urls are canonicalized and positions are set by the item order
+func ValidateItems(items []Item) error {
+
+	if len(items) > MaxItems {
+		return ErrTooManyItems
+	}
+
+	linkIDs := make(map[int64]bool, len(items))
+	for i := range items {
+		item := &items[i]
+
+		if linkIDs[item.LinkID] {
+			return ErrDuplicateItem
+		}
+		linkIDs[item.LinkID] = true
+
+		item.Title = strings.TrimSpace(item.Title)
+		if utf8.RuneCountInString(item.Title) > maxTitleLength {
+			return ErrTitleTooLong
+		}
+
+		if item.Icon != "" {
+			icon, err := urls.Canonicalize(item.Icon, urls.Web)
+			if err != nil {
+				return err
+			}
+			item.Icon = icon
+		}
+
+		item.Position = i
+	}
+
+	return nil
+}
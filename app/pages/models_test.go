@@ -0,0 +1,71 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+
+	"shortly/app/urls"
+)
+
+func TestPageValidate(t *testing.T) {
+
+	cases := []struct {
+		page Page
+		err  error
+	}{
+		{Page{Handle: "jane_doe"}, nil},
+		{Page{Handle: " Jane-Doe ", Theme: "dark"}, nil},
+		{Page{Handle: "jd"}, ErrInvalidHandle},
+		{Page{Handle: "-jane"}, ErrInvalidHandle},
+		{Page{Handle: "jane doe"}, ErrInvalidHandle},
+		{Page{Handle: strings.Repeat("a", 33)}, ErrInvalidHandle},
+		{Page{Handle: "jane", Theme: "neon"}, ErrUnknownTheme},
+		{Page{Handle: "jane", Title: strings.Repeat("t", 101)}, ErrTitleTooLong},
+		{Page{Handle: "jane", Description: strings.Repeat("d", 501)}, ErrDescriptionTooLong},
+		{Page{Handle: "jane", AvatarURL: "javascript:alert(1)"}, urls.ErrUnsafeScheme},
+	}
+
+	for _, c := range cases {
+		if err := c.page.Validate(); err != c.err {
+			t.Errorf("page(%q): expected error %v, got %v", c.page.Handle, c.err, err)
+		}
+	}
+
+	page := Page{Handle: "Jane-Doe", AvatarURL: "Example.com/me.png"}
+	if err := page.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if page.Handle != "jane-doe" || page.Theme != DefaultTheme || page.AvatarURL != "https://example.com/me.png" {
+		t.Errorf("unexpected normalized page %+v", page)
+	}
+}
+
+func TestValidateItems(t *testing.T) {
+
+	items := []Item{{LinkID: 3, Title: " Shop ", Icon: "example.com/shop.svg"}, {LinkID: 1}}
+	if err := ValidateItems(items); err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Position != 0 || items[1].Position != 1 {
+		t.Errorf("positions must follow item order, got %+v", items)
+	}
+	if items[0].Title != "Shop" || items[0].Icon != "https://example.com/shop.svg" {
+		t.Errorf("unexpected normalized item %+v", items[0])
+	}
+
+	if err := ValidateItems([]Item{{LinkID: 1}, {LinkID: 1}}); err != ErrDuplicateItem {
+		t.Errorf("expected duplicate item error, got %v", err)
+	}
+
+	if err := ValidateItems([]Item{{LinkID: 1, Icon: "ftp://example.com/icon.png"}}); err == nil {
+		t.Error("expected icon url error")
+	}
+
+	tooMany := make([]Item, MaxItems+1)
+	for i := range tooMany {
+		tooMany[i].LinkID = int64(i + 1)
+	}
+	if err := ValidateItems(tooMany); err != ErrTooManyItems {
+		t.Errorf("expected too many items error, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package pages
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+const pageFields = `id, account_id, handle, title, description, avatar_url, theme, published, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPage(row rowScanner, p *Page) error {
+	return row.Scan(&p.ID, &p.AccountID, &p.Handle, &p.Title, &p.Description, &p.AvatarURL, &p.Theme, &p.Published,
+		&p.CreatedAt, &p.UpdatedAt)
+}
+
+func handleError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrHandleTaken
+	}
+	return err
+}
+
+// CreatePage ...
+func (r *Repository) CreatePage(p *Page) error {
+	err := r.DB.QueryRow(`
+		insert into bio_pages (account_id, handle, title, description, avatar_url, theme, published)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id, created_at, updated_at`,
+		p.AccountID, p.Handle, p.Title, p.Description, p.AvatarURL, p.Theme, p.Published,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	return handleError(err)
+}
+
+// UpdatePage updates page fields, sql.ErrNoRows is returned for pages of other accounts
+func (r *Repository) UpdatePage(p *Page) error {
+	err := r.DB.QueryRow(`
+		update bio_pages set handle = $1, title = $2, description = $3, avatar_url = $4, theme = $5, published = $6,
+		updated_at = now() where id = $7 and account_id = $8 returning created_at, updated_at`,
+		p.Handle, p.Title, p.Description, p.AvatarURL, p.Theme, p.Published, p.ID, p.AccountID,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	return handleError(err)
+}
+
+// DeletePage removes a page with its items, sql.ErrNoRows is returned for pages of other accounts
+func (r *Repository) DeletePage(accountID, id int64) error {
+	res, err := r.DB.Exec("delete from bio_pages where id = $1 and account_id = $2", id, accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPages returns pages of an account without items
+func (r *Repository) GetPages(accountID int64) ([]Page, error) {
+
+	rows, err := r.DB.Query(`select `+pageFields+` from bio_pages where account_id = $1 order by id`, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]Page, 0)
+	for rows.Next() {
+		var p Page
+		if err := scanPage(rows, &p); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// GetPage returns an account page with its items
+func (r *Repository) GetPage(accountID, id int64) (*Page, error) {
+
+	var p Page
+	err := scanPage(r.DB.QueryRow(`select `+pageFields+` from bio_pages where id = $1 and account_id = $2`, id, accountID), &p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Items, err = r.getItems(p.ID); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// GetPublishedPage returns a published page with its items by a case insensitive handle
+func (r *Repository) GetPublishedPage(handle string) (*Page, error) {
+
+	var p Page
+	err := scanPage(r.DB.QueryRow(`select `+pageFields+` from bio_pages where lower(handle) = lower($1) and published = true`, handle), &p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Items, err = r.getItems(p.ID); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *Repository) getItems(pageID int64) ([]Item, error) {
+
+	rows, err := r.DB.Query(
+		"select id, link_id, position, title, icon from bio_page_items where page_id = $1 order by position, id", pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]Item, 0)
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ID, &item.LinkID, &item.Position, &item.Title, &item.Icon); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+
+	return list, rows.Err()
+}
+
+// SetItems replaces items of a page in a single transaction
+func (r *Repository) SetItems(pageID int64, items []Item) error {
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("delete from bio_page_items where page_id = $1", pageID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		err := tx.QueryRow(
+			"insert into bio_page_items (page_id, link_id, position, title, icon) values ($1, $2, $3, $4, $5) returning id",
+			pageID, item.LinkID, item.Position, item.Title, item.Icon,
+		).Scan(&item.ID)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("update bio_pages set updated_at = now() where id = $1", pageID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
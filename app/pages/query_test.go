@@ -0,0 +1,100 @@
+package pages
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+var pageColumns = []string{"id", "account_id", "handle", "title", "description", "avatar_url", "theme", "published",
+	"created_at", "updated_at"}
+
+func TestGetPublishedPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("from bio_pages where lower\\(handle\\) = lower\\(\\$1\\) and published = true").WithArgs("Jane").
+		WillReturnRows(sqlmock.NewRows(pageColumns).AddRow(4, 1, "jane", "Jane", "", "", "dark", true, now, now))
+	mock.ExpectQuery("from bio_page_items where page_id = \\$1 order by position").WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "link_id", "position", "title", "icon"}).
+			AddRow(1, 7, 0, "Shop", "").
+			AddRow(2, 5, 1, "", ""))
+	mock.ExpectQuery("from bio_pages where lower\\(handle\\)").WithArgs("missing").WillReturnError(sql.ErrNoRows)
+
+	repo := &Repository{DB: db}
+
+	page, err := repo.GetPublishedPage("Jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.ID != 4 || page.Theme != "dark" || len(page.Items) != 2 || page.Items[0].LinkID != 7 {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	if _, err := repo.GetPublishedPage("missing"); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreatePageHandleTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("insert into bio_pages").WillReturnError(&pq.Error{Code: "23505"})
+
+	repo := &Repository{DB: db}
+
+	if err := repo.CreatePage(&Page{AccountID: 1, Handle: "jane", Theme: DefaultTheme}); err != ErrHandleTaken {
+		t.Errorf("expected handle taken error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetItems(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("delete from bio_page_items where page_id = \\$1").WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("insert into bio_page_items").WithArgs(int64(4), int64(7), 0, "Shop", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery("insert into bio_page_items").WithArgs(int64(4), int64(5), 1, "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec("update bio_pages set updated_at = now\\(\\)").WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := &Repository{DB: db}
+
+	items := []Item{{LinkID: 7, Position: 0, Title: "Shop"}, {LinkID: 5, Position: 1}}
+	if err := repo.SetItems(4, items); err != nil {
+		t.Fatal(err)
+	}
+	if items[0].ID != 11 || items[1].ID != 12 {
+		t.Errorf("expected item ids to be set, got %+v", items)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
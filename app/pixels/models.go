@@ -0,0 +1,183 @@
+package pixels
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shortly/app/domains"
+	"shortly/app/urls"
+)
+
+// pixel kinds
+const (
+	// KindScript pixels are loaded as async scripts
+	KindScript = "script"
+	// KindImage pixels are loaded as 1x1 images
+	KindImage = "image"
+)
+
+const (
+	// MaxLinkPixels is a number of pixels allowed on a link
+	MaxLinkPixels = 10
+	// MaxDelay limits a delay of the pixel interstitial
+	MaxDelay = 5 * time.Second
+	// maxNameLength limits pixel names
+	maxNameLength = 100
+)
+
+// placeholders are template variables of pixel urls, values are query escaped
+var placeholders = []string{"{short_url}", "{destination}", "{referrer}", "{cachebuster}"}
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+var (
+	// ErrInvalidKind ...
+	ErrInvalidKind = errors.New("kind must be script or image")
+	// ErrNameRequired ...
+	ErrNameRequired = errors.New("name is required")
+	// ErrNameTooLong ...
+	ErrNameTooLong = errors.New("name is too long")
+	// ErrUnknownPlaceholder ...
+	ErrUnknownPlaceholder = errors.New("url has an unknown placeholder, supported are {short_url}, {destination}, {referrer}, {cachebuster}")
+	// ErrNotHTTPS ...
+	ErrNotHTTPS = errors.New("pixel url must be an https url")
+	// ErrTemplatedHost ...
+	ErrTemplatedHost = errors.New("pixel url host must not contain placeholders")
+	// ErrHostNotAllowed ...
+	ErrHostNotAllowed = errors.New("pixel url host is not in the account allowlist")
+	// ErrHostExists ...
+	ErrHostExists = errors.New("host is already allowed")
+	// ErrHostInUse ...
+	ErrHostInUse = errors.New("host is used by pixels")
+	// ErrTooManyPixels ...
+	ErrTooManyPixels = errors.New("link has too many pixels")
+	// ErrInvalidDelay ...
+	ErrInvalidDelay = errors.New("delay must be between 0 and 5000 milliseconds")
+	// ErrPixelNotFound ...
+	ErrPixelNotFound = errors.New("pixel not found")
+)
+
+// Pixel is a retargeting pixel of an account, URL is a template expanded on each redirect
+type Pixel struct {
+	ID        int64
+	AccountID int64
+	Name      string
+	Kind      string
+	URL       string
+	Host      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Host is an allowlisted host of account pixels, hosts are matched exactly
+type Host struct {
+	ID        int64
+	AccountID int64
+	Host      string
+	CreatedAt time.Time
+}
+
+// NormalizeHost lowercases a host and checks its format
+func NormalizeHost(host string) (string, error) {
+	host = domains.NormalizeHost(host)
+	if err := domains.ValidateHost(host); err != nil {
+		return "", err
+	}
+	return host, nil
+}
+
+// Validate checks pixel fields, the url must be an https url of an allowed host
+func (p *Pixel) Validate(allowed []Host) error {
+
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return ErrNameRequired
+	}
+	if utf8.RuneCountInString(p.Name) > maxNameLength {
+		return ErrNameTooLong
+	}
+
+	if p.Kind != KindScript && p.Kind != KindImage {
+		return ErrInvalidKind
+	}
+
+	p.URL = strings.TrimSpace(p.URL)
+	host, err := TemplateHost(p.URL)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range allowed {
+		if h.Host == host {
+			p.Host = host
+			return nil
+		}
+	}
+
+	return ErrHostNotAllowed
+}
+
+// TemplateHost checks a pixel url template and returns its host
+func TemplateHost(template string) (string, error) {
+
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		if !contains(placeholders, placeholder) {
+			return "", ErrUnknownPlaceholder
+		}
+	}
+
+	if urls.Scheme(template) != "https" || !strings.Contains(template, "://") {
+		return "", ErrNotHTTPS
+	}
+
+	authority := template[strings.Index(template, "://")+3:]
+	if i := strings.IndexAny(authority, "/?#"); i >= 0 {
+		authority = authority[:i]
+	}
+	if strings.ContainsAny(authority, "{}") {
+		return "", ErrTemplatedHost
+	}
+
+	canonical, err := urls.Canonicalize(Expand(template, Vars{}), urls.Web)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(canonical)
+	if err != nil {
+		return "", urls.ErrInvalid
+	}
+
+	return u.Hostname(), nil
+}
+
+// Vars are values of pixel url placeholders
+type Vars struct {
+	ShortURL    string
+	Destination string
+	Referrer    string
+	CacheBuster string
+}
+
+// Expand replaces placeholders of a pixel url template with query escaped values
+func Expand(template string, vars Vars) string {
+	return strings.NewReplacer(
+		"{short_url}", url.QueryEscape(vars.ShortURL),
+		"{destination}", url.QueryEscape(vars.Destination),
+		"{referrer}", url.QueryEscape(vars.Referrer),
+		"{cachebuster}", url.QueryEscape(vars.CacheBuster),
+	).Replace(template)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
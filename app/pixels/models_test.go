@@ -0,0 +1,72 @@
+package pixels
+
+import (
+	"strings"
+	"testing"
+
+	"shortly/app/urls"
+)
+
+func TestPixelValidate(t *testing.T) {
+
+	allowed := []Host{{Host: "px.example.com"}, {Host: "www.googletagmanager.com"}}
+
+	cases := []struct {
+		pixel Pixel
+		err   error
+	}{
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "https://px.example.com/p?u={short_url}&d={destination}&cb={cachebuster}"}, nil},
+		{Pixel{Name: "Tag", Kind: KindScript, URL: " HTTPS://www.googletagmanager.com/gtag/js?id=G-1 "}, nil},
+		{Pixel{Name: " ", Kind: KindImage, URL: "https://px.example.com/p"}, ErrNameRequired},
+		{Pixel{Name: strings.Repeat("n", 101), Kind: KindImage, URL: "https://px.example.com/p"}, ErrNameTooLong},
+		{Pixel{Name: "Ads", Kind: "iframe", URL: "https://px.example.com/p"}, ErrInvalidKind},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "http://px.example.com/p"}, ErrNotHTTPS},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "px.example.com/p"}, ErrNotHTTPS},
+		{Pixel{Name: "Ads", Kind: KindScript, URL: "javascript:alert(1)"}, ErrNotHTTPS},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "https://px.example.com/p?ip={ip}"}, ErrUnknownPlaceholder},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "https://{referrer}/p"}, ErrTemplatedHost},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "https://evil.example.com/p"}, ErrHostNotAllowed},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "https://example.com/p"}, ErrHostNotAllowed},
+		{Pixel{Name: "Ads", Kind: KindImage, URL: "https://px.example.com@evil.example.com/p"}, urls.ErrCredentials},
+	}
+
+	for _, c := range cases {
+		if err := c.pixel.Validate(allowed); err != c.err {
+			t.Errorf("pixel(%q): expected error %v, got %v", c.pixel.URL, c.err, err)
+		}
+	}
+
+	pixel := Pixel{Name: " Ads ", Kind: KindImage, URL: " https://PX.example.com/p "}
+	if err := pixel.Validate(allowed); err != nil {
+		t.Fatal(err)
+	}
+	if pixel.Name != "Ads" || pixel.URL != "https://PX.example.com/p" || pixel.Host != "px.example.com" {
+		t.Errorf("unexpected normalized pixel %+v", pixel)
+	}
+}
+
+func TestExpand(t *testing.T) {
+
+	got := Expand("https://px.example.com/p?u={short_url}&d={destination}&r={referrer}&cb={cachebuster}", Vars{
+		ShortURL:    "https://sho.rt/abc",
+		Destination: "https://example.com/?a=1&b=2",
+		CacheBuster: "42",
+	})
+
+	expected := "https://px.example.com/p?u=https%3A%2F%2Fsho.rt%2Fabc&d=https%3A%2F%2Fexample.com%2F%3Fa%3D1%26b%3D2&r=&cb=42"
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+
+	host, err := NormalizeHost(" PX.Example.com ")
+	if err != nil || host != "px.example.com" {
+		t.Errorf("unexpected host %q, error %v", host, err)
+	}
+
+	if _, err := NormalizeHost("https://px.example.com/"); err == nil {
+		t.Error("expected an error for a url")
+	}
+}
@@ -0,0 +1,168 @@
+package pixels
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+const pixelFields = `id, account_id, name, kind, url, host, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPixel(row rowScanner, p *Pixel) error {
+	return row.Scan(&p.ID, &p.AccountID, &p.Name, &p.Kind, &p.URL, &p.Host, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// CreatePixel stores a validated pixel
+func (r *Repository) CreatePixel(p *Pixel) error {
+	return r.DB.QueryRow(`
+		insert into pixels (account_id, name, kind, url, host) values ($1, $2, $3, $4, $5)
+		returning id, created_at, updated_at`,
+		p.AccountID, p.Name, p.Kind, p.URL, p.Host,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// UpdatePixel updates a validated pixel, sql.ErrNoRows is returned for pixels of other accounts
+func (r *Repository) UpdatePixel(p *Pixel) error {
+	return r.DB.QueryRow(`
+		update pixels set name = $1, kind = $2, url = $3, host = $4, updated_at = now()
+		where id = $5 and account_id = $6 returning created_at, updated_at`,
+		p.Name, p.Kind, p.URL, p.Host, p.ID, p.AccountID,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+}
+
+// DeletePixel removes a pixel from all links, sql.ErrNoRows is returned for pixels of other accounts
+func (r *Repository) DeletePixel(accountID, id int64) error {
+	res, err := r.DB.Exec("delete from pixels where id = $1 and account_id = $2", id, accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPixels returns pixels of an account
+func (r *Repository) GetPixels(accountID int64) ([]Pixel, error) {
+	return r.queryPixels(`select `+pixelFields+` from pixels where account_id = $1 order by id`, accountID)
+}
+
+// GetPixelsByIDs returns pixels of an account with given ids, ids of other accounts are skipped
+func (r *Repository) GetPixelsByIDs(accountID int64, ids []int64) ([]Pixel, error) {
+	return r.queryPixels(`select `+pixelFields+` from pixels where account_id = $1 and id = any($2) order by id`,
+		accountID, pq.Array(ids))
+}
+
+func (r *Repository) queryPixels(query string, args ...interface{}) ([]Pixel, error) {
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]Pixel, 0)
+	for rows.Next() {
+		var p Pixel
+		if err := scanPixel(rows, &p); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// GetPixelLinkIDs returns ids of links a pixel is attached to
+func (r *Repository) GetPixelLinkIDs(id int64) ([]int64, error) {
+
+	rows, err := r.DB.Query("select link_id from link_pixels where pixel_id = $1 order by link_id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var linkID int64
+		if err := rows.Scan(&linkID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, linkID)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetHosts returns the pixel host allowlist of an account
+func (r *Repository) GetHosts(accountID int64) ([]Host, error) {
+
+	rows, err := r.DB.Query("select id, account_id, host, created_at from pixel_hosts where account_id = $1 order by host",
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]Host, 0)
+	for rows.Next() {
+		var h Host
+		if err := rows.Scan(&h.ID, &h.AccountID, &h.Host, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+
+	return list, rows.Err()
+}
+
+// CreateHost adds a normalized host to the account allowlist
+func (r *Repository) CreateHost(h *Host) error {
+	err := r.DB.QueryRow("insert into pixel_hosts (account_id, host) values ($1, $2) returning id, created_at",
+		h.AccountID, h.Host,
+	).Scan(&h.ID, &h.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrHostExists
+	}
+	return err
+}
+
+// DeleteHost removes a host from the account allowlist, hosts of existing pixels can't be removed
+func (r *Repository) DeleteHost(accountID, id int64) error {
+
+	var inUse bool
+	err := r.DB.QueryRow(`
+		select exists(select 1 from pixels p join pixel_hosts h on h.account_id = p.account_id and h.host = p.host
+		where h.id = $1 and h.account_id = $2)`,
+		id, accountID,
+	).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrHostInUse
+	}
+
+	res, err := r.DB.Exec("delete from pixel_hosts where id = $1 and account_id = $2", id, accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
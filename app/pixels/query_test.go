@@ -0,0 +1,88 @@
+package pixels
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestGetPixelsByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("from pixels where account_id = \\$1 and id = any\\(\\$2\\)").
+		WithArgs(int64(1), pq.Array([]int64{3, 9})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "name", "kind", "url", "host", "created_at", "updated_at"}).
+			AddRow(3, 1, "Ads", KindImage, "https://px.example.com/p", "px.example.com", now, now))
+
+	repo := &Repository{DB: db}
+
+	list, err := repo.GetPixelsByIDs(1, []int64{3, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != 3 || list[0].Host != "px.example.com" {
+		t.Errorf("unexpected pixels %+v", list)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("insert into pixel_hosts").WithArgs(int64(1), "px.example.com").
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	repo := &Repository{DB: db}
+
+	if err := repo.CreateHost(&Host{AccountID: 1, Host: "px.example.com"}); err != ErrHostExists {
+		t.Errorf("expected host exists error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("select exists").WithArgs(int64(2), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("select exists").WithArgs(int64(3), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("delete from pixel_hosts").WithArgs(int64(3), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &Repository{DB: db}
+
+	if err := repo.DeleteHost(1, 2); err != ErrHostInUse {
+		t.Errorf("expected host in use error, got %v", err)
+	}
+	if err := repo.DeleteHost(1, 3); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
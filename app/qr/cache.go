@@ -0,0 +1,64 @@
+package qr
+
+import (
+	"container/list"
+	"sync"
+)
+
+// imageCache keeps recently rendered images up to a total size in bytes,
+// the least recently used images are evicted first
+type imageCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key   string
+	image []byte
+}
+
+func newImageCache(maxBytes int) *imageCache {
+	return &imageCache{maxBytes: maxBytes, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *imageCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).image, true
+}
+
+func (c *imageCache) put(key string, image []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(image) > c.maxBytes {
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		c.bytes -= len(el.Value.(*cacheEntry).image)
+		el.Value.(*cacheEntry).image = image
+		c.bytes += len(image)
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&cacheEntry{key: key, image: image})
+		c.bytes += len(image)
+	}
+
+	for c.bytes > c.maxBytes {
+		el := c.order.Back()
+		entry := el.Value.(*cacheEntry)
+		c.order.Remove(el)
+		delete(c.items, entry.key)
+		c.bytes -= len(entry.image)
+	}
+}
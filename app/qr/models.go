@@ -0,0 +1,205 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// output formats
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+const (
+	// MinSize and MaxSize limit an image width in pixels
+	MinSize = 64
+	MaxSize = 2048
+	// MaxQuietZone limits a border width in modules
+	MaxQuietZone = 16
+)
+
+var (
+	// ErrInvalidSize ...
+	ErrInvalidSize = fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	// ErrInvalidLevel ...
+	ErrInvalidLevel = errors.New("level must be one of L, M, Q, H")
+	// ErrInvalidColor ...
+	ErrInvalidColor = errors.New("colors must be hex values like #000000")
+	// ErrSameColors ...
+	ErrSameColors = errors.New("foreground and background colors must differ")
+	// ErrInvalidQuietZone ...
+	ErrInvalidQuietZone = fmt.Errorf("quiet zone must be between 0 and %d modules", MaxQuietZone)
+	// ErrInvalidFormat ...
+	ErrInvalidFormat = errors.New("format must be png or svg")
+)
+
+// levels maps error correction levels to encoder recovery levels
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Style is a look of rendered qr codes, zero values are replaced with defaults by Validate
+type Style struct {
+	// Size is an image width and height in pixels
+	Size int
+	// Level is an error correction level, one of L, M, Q, H
+	Level      string
+	Foreground string
+	Background string
+	// QuietZone is a border width in modules, nil means the standard 4 modules
+	QuietZone *int
+	Format    string
+	// Logo places the account logo in the center, error correction is raised to H for L and M levels
+	Logo      bool
+	UpdatedAt time.Time
+}
+
+// DefaultStyle matches qr codes served before styles were introduced
+func DefaultStyle() Style {
+	quietZone := 4
+	return Style{Size: 256, Level: "M", Foreground: "#000000", Background: "#ffffff", QuietZone: &quietZone, Format: FormatPNG}
+}
+
+// Validate checks style fields and sets defaults of empty ones
+func (s *Style) Validate() error {
+
+	def := DefaultStyle()
+
+	if s.Size == 0 {
+		s.Size = def.Size
+	}
+	if s.Size < MinSize || s.Size > MaxSize {
+		return ErrInvalidSize
+	}
+
+	s.Level = strings.ToUpper(s.Level)
+	if s.Level == "" {
+		s.Level = def.Level
+	}
+	if _, ok := levels[s.Level]; !ok {
+		return ErrInvalidLevel
+	}
+	if s.Logo && (s.Level == "L" || s.Level == "M") {
+		s.Level = "H"
+	}
+
+	if s.Foreground == "" {
+		s.Foreground = def.Foreground
+	}
+	if s.Background == "" {
+		s.Background = def.Background
+	}
+	fg, err := parseColor(s.Foreground)
+	if err != nil {
+		return err
+	}
+	bg, err := parseColor(s.Background)
+	if err != nil {
+		return err
+	}
+	if fg == bg {
+		return ErrSameColors
+	}
+	s.Foreground, s.Background = formatColor(fg), formatColor(bg)
+
+	if s.QuietZone == nil {
+		s.QuietZone = def.QuietZone
+	}
+	if *s.QuietZone < 0 || *s.QuietZone > MaxQuietZone {
+		return ErrInvalidQuietZone
+	}
+
+	s.Format = strings.ToLower(s.Format)
+	if s.Format == "" {
+		s.Format = def.Format
+	}
+	if s.Format != FormatPNG && s.Format != FormatSVG {
+		return ErrInvalidFormat
+	}
+
+	return nil
+}
+
+// Override replaces style fields with query parameters size, level, fg, bg, margin, format and logo
+func (s *Style) Override(query url.Values) error {
+
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return ErrInvalidSize
+		}
+		s.Size = size
+	}
+	if v := query.Get("level"); v != "" {
+		s.Level = v
+	}
+	if v := query.Get("fg"); v != "" {
+		s.Foreground = v
+	}
+	if v := query.Get("bg"); v != "" {
+		s.Background = v
+	}
+	if v := query.Get("margin"); v != "" {
+		quietZone, err := strconv.Atoi(v)
+		if err != nil {
+			return ErrInvalidQuietZone
+		}
+		s.QuietZone = &quietZone
+	}
+	if v := query.Get("format"); v != "" {
+		s.Format = v
+	}
+	if v := query.Get("logo"); v != "" {
+		logo, _ := strconv.ParseBool(v)
+		s.Logo = logo
+	}
+
+	return s.Validate()
+}
+
+// ContentType ...
+func (s Style) ContentType() string {
+	if s.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// key identifies rendered images of a style in cache
+func (s Style) key() string {
+	return fmt.Sprintf("%d:%s:%s:%s:%d:%s:%t", s.Size, s.Level, s.Foreground, s.Background, *s.QuietZone, s.Format, s.Logo)
+}
+
+// parseColor reads #rgb and #rrggbb colors
+func parseColor(value string) (color.RGBA, error) {
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+func formatColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,73 @@
+package qr
+
+import (
+	"net/url"
+	"testing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestStyleValidate(t *testing.T) {
+
+	cases := []struct {
+		style Style
+		err   error
+	}{
+		{Style{}, nil},
+		{Style{Size: 512, Level: "q", Foreground: "#123", Background: "#FFFFFF", QuietZone: intPtr(0), Format: "SVG"}, nil},
+		{Style{Size: 32}, ErrInvalidSize},
+		{Style{Size: 4096}, ErrInvalidSize},
+		{Style{Level: "X"}, ErrInvalidLevel},
+		{Style{Foreground: "black"}, ErrInvalidColor},
+		{Style{Background: "#12345g"}, ErrInvalidColor},
+		{Style{Foreground: "#fff", Background: "#ffffff"}, ErrSameColors},
+		{Style{QuietZone: intPtr(-1)}, ErrInvalidQuietZone},
+		{Style{QuietZone: intPtr(17)}, ErrInvalidQuietZone},
+		{Style{Format: "jpeg"}, ErrInvalidFormat},
+	}
+
+	for _, c := range cases {
+		if err := c.style.Validate(); err != c.err {
+			t.Errorf("style(%+v): expected error %v, got %v", c.style, c.err, err)
+		}
+	}
+
+	style := Style{Level: "q", Foreground: "#123", Format: "SVG"}
+	if err := style.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if style.Size != 256 || style.Level != "Q" || style.Foreground != "#112233" || style.Background != "#ffffff" ||
+		*style.QuietZone != 4 || style.Format != FormatSVG {
+		t.Errorf("unexpected normalized style %+v", style)
+	}
+
+	logo := Style{Level: "M", Logo: true}
+	if err := logo.Validate(); err != nil || logo.Level != "H" {
+		t.Errorf("expected level H for a style with logo, got %v, %v", logo.Level, err)
+	}
+}
+
+func TestStyleOverride(t *testing.T) {
+
+	style := DefaultStyle()
+	style.Foreground = "#ff0000"
+
+	query := url.Values{"size": {"512"}, "margin": {"1"}, "format": {"svg"}, "bg": {"#eee"}}
+	if err := style.Override(query); err != nil {
+		t.Fatal(err)
+	}
+	if style.Size != 512 || *style.QuietZone != 1 || style.Format != FormatSVG || style.Background != "#eeeeee" ||
+		style.Foreground != "#ff0000" {
+		t.Errorf("unexpected style %+v", style)
+	}
+	if style.ContentType() != "image/svg+xml" {
+		t.Errorf("unexpected content type %v", style.ContentType())
+	}
+
+	style = DefaultStyle()
+	if err := style.Override(url.Values{"size": {"big"}}); err != ErrInvalidSize {
+		t.Errorf("expected size error, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package qr
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+const styleFields = `size, level, foreground, background, quiet_zone, format, logo, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStyle(row rowScanner, s *Style, dest ...interface{}) error {
+	var quietZone int
+	err := row.Scan(append(dest, &s.Size, &s.Level, &s.Foreground, &s.Background, &quietZone, &s.Format, &s.Logo, &s.UpdatedAt)...)
+	s.QuietZone = &quietZone
+	return err
+}
+
+// GetLinkStyle returns a saved style of an account link, sql.ErrNoRows is returned for links without a style
+func (r *Repository) GetLinkStyle(accountID, linkID int64) (*Style, error) {
+	var s Style
+	err := scanStyle(r.DB.QueryRow(`select `+styleFields+` from link_qr_styles where link_id = $1 and account_id = $2`,
+		linkID, accountID), &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetLinkStyles returns saved styles of account links by link id
+func (r *Repository) GetLinkStyles(accountID int64, linkIDs []int64) (map[int64]Style, error) {
+
+	rows, err := r.DB.Query(`select link_id, `+styleFields+` from link_qr_styles where account_id = $1 and link_id = any($2)`,
+		accountID, pq.Array(linkIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	styles := make(map[int64]Style)
+	for rows.Next() {
+		var linkID int64
+		var s Style
+		if err := scanStyle(rows, &s, &linkID); err != nil {
+			return nil, err
+		}
+		styles[linkID] = s
+	}
+
+	return styles, rows.Err()
+}
+
+// FindLinkStyle returns an account and a saved style of a link by its short url,
+// style is nil for links without a saved style
+func (r *Repository) FindLinkStyle(domainID int64, shortURL string) (int64, *Style, error) {
+
+	var accountID int64
+	var size, quietZone sql.NullInt64
+	var level, foreground, background, format sql.NullString
+	var logo sql.NullBool
+	var updatedAt pq.NullTime
+
+	err := r.DB.QueryRow(`
+		select links.account_id, s.size, s.level, s.foreground, s.background, s.quiet_zone, s.format, s.logo, s.updated_at
+		from links left join link_qr_styles s on s.link_id = links.id
+		where links.domain_id = $1 and links.short_url = $2 and links.deleted_at is null`,
+		domainID, shortURL,
+	).Scan(&accountID, &size, &level, &foreground, &background, &quietZone, &format, &logo, &updatedAt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !size.Valid {
+		return accountID, nil, nil
+	}
+
+	zone := int(quietZone.Int64)
+	return accountID, &Style{
+		Size:       int(size.Int64),
+		Level:      level.String,
+		Foreground: foreground.String,
+		Background: background.String,
+		QuietZone:  &zone,
+		Format:     format.String,
+		Logo:       logo.Bool,
+		UpdatedAt:  updatedAt.Time,
+	}, nil
+}
+
+// SaveLinkStyle stores a validated style of an account link
+func (r *Repository) SaveLinkStyle(accountID, linkID int64, s *Style) error {
+	return r.DB.QueryRow(`
+		insert into link_qr_styles (link_id, account_id, size, level, foreground, background, quiet_zone, format, logo, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+		on conflict (link_id) do update set size = excluded.size, level = excluded.level, foreground = excluded.foreground,
+		background = excluded.background, quiet_zone = excluded.quiet_zone, format = excluded.format, logo = excluded.logo,
+		updated_at = excluded.updated_at
+		returning updated_at`,
+		linkID, accountID, s.Size, s.Level, s.Foreground, s.Background, *s.QuietZone, s.Format, s.Logo,
+	).Scan(&s.UpdatedAt)
+}
+
+// DeleteLinkStyle removes a saved style, sql.ErrNoRows is returned for links without a style
+func (r *Repository) DeleteLinkStyle(accountID, linkID int64) error {
+	res, err := r.DB.Exec("delete from link_qr_styles where link_id = $1 and account_id = $2", linkID, accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetLogoVersion returns a time the account logo was uploaded, sql.ErrNoRows is returned for accounts without a logo
+func (r *Repository) GetLogoVersion(accountID int64) (time.Time, error) {
+	var updatedAt time.Time
+	err := r.DB.QueryRow("select updated_at from qr_logos where account_id = $1", accountID).Scan(&updatedAt)
+	return updatedAt, err
+}
+
+// GetLogo returns a png image of the account logo
+func (r *Repository) GetLogo(accountID int64) ([]byte, error) {
+	var content []byte
+	err := r.DB.QueryRow("select content from qr_logos where account_id = $1", accountID).Scan(&content)
+	return content, err
+}
+
+// SaveLogo replaces the account logo with a png image
+func (r *Repository) SaveLogo(accountID int64, content []byte) error {
+	_, err := r.DB.Exec(`
+		insert into qr_logos (account_id, content, updated_at) values ($1, $2, now())
+		on conflict (account_id) do update set content = excluded.content, updated_at = excluded.updated_at`,
+		accountID, content)
+	return err
+}
+
+// DeleteLogo ...
+func (r *Repository) DeleteLogo(accountID int64) error {
+	res, err := r.DB.Exec("delete from qr_logos where account_id = $1", accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package qr
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFindLinkStyle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"account_id", "size", "level", "foreground", "background", "quiet_zone", "format", "logo", "updated_at"}
+
+	mock.ExpectQuery("from links left join link_qr_styles").WithArgs(int64(0), "abc").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow(3, 512, "H", "#112233", "#ffffff", 2, "svg", true, now))
+	mock.ExpectQuery("from links left join link_qr_styles").WithArgs(int64(0), "plain").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow(3, nil, nil, nil, nil, nil, nil, nil, nil))
+	mock.ExpectQuery("from links left join link_qr_styles").WithArgs(int64(2), "missing").WillReturnError(sql.ErrNoRows)
+
+	repo := &Repository{DB: db}
+
+	accountID, style, err := repo.FindLinkStyle(0, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accountID != 3 || style == nil || style.Size != 512 || *style.QuietZone != 2 || !style.Logo || !style.UpdatedAt.Equal(now) {
+		t.Errorf("unexpected style %v, %+v", accountID, style)
+	}
+
+	accountID, style, err = repo.FindLinkStyle(0, "plain")
+	if err != nil || accountID != 3 || style != nil {
+		t.Errorf("expected link without a style, got %v, %+v, %v", accountID, style, err)
+	}
+
+	if _, _, err := repo.FindLinkStyle(2, "missing"); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,183 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// logoShare is a share of the code width covered by a logo, about 4% of modules are hidden
+// which error correction of levels Q and H restores
+const logoShare = 0.2
+
+// Encode renders a qr code of content with a style, logo is drawn in the center of styles with a logo
+func Encode(content string, style Style, logo image.Image) ([]byte, error) {
+
+	code, err := qrcode.New(content, levels[style.Level])
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+
+	fg, _ := parseColor(style.Foreground)
+	bg, _ := parseColor(style.Background)
+
+	g := newGrid(code.Bitmap(), *style.QuietZone, style.Size)
+	if !style.Logo {
+		logo = nil
+	}
+
+	if style.Format == FormatSVG {
+		return encodeSVG(g, style, logo)
+	}
+	return encodePNG(g, fg, bg, logo)
+}
+
+// grid places code modules on an image, modules are scaled by a whole number of pixels
+// and the code is centered when the size isn't a multiple of the module count
+type grid struct {
+	bitmap    [][]bool
+	quietZone int
+	size      int
+	scale     int
+	offset    int
+}
+
+func newGrid(bitmap [][]bool, quietZone, size int) grid {
+
+	modules := len(bitmap) + 2*quietZone
+	if size < modules {
+		size = modules
+	}
+	scale := size / modules
+
+	return grid{
+		bitmap:    bitmap,
+		quietZone: quietZone,
+		size:      size,
+		scale:     scale,
+		offset:    (size - modules*scale) / 2,
+	}
+}
+
+// logoBox returns a square in pixels covered by a logo and its padding
+func (g grid) logoBox() image.Rectangle {
+	side := int(float64(len(g.bitmap)*g.scale) * logoShare)
+	min := (g.size - side) / 2
+	return image.Rect(min, min, min+side, min+side)
+}
+
+func encodePNG(g grid, fg, bg color.RGBA, logo image.Image) ([]byte, error) {
+
+	rect := image.Rect(0, 0, g.size, g.size)
+
+	var img draw.Image
+	if logo == nil {
+		// two color images are stored with a palette and stay small
+		img = image.NewPaletted(rect, color.Palette{bg, fg})
+	} else {
+		img = image.NewRGBA(rect)
+		draw.Draw(img, rect, image.NewUniform(bg), image.Point{}, draw.Src)
+	}
+
+	dark := image.NewUniform(fg)
+	for row, line := range g.bitmap {
+		for col, isDark := range line {
+			if !isDark {
+				continue
+			}
+			x := g.offset + (col+g.quietZone)*g.scale
+			y := g.offset + (row+g.quietZone)*g.scale
+			draw.Draw(img, image.Rect(x, y, x+g.scale, y+g.scale), dark, image.Point{}, draw.Src)
+		}
+	}
+
+	if logo != nil {
+		box := g.logoBox()
+		draw.Draw(img, box, image.NewUniform(bg), image.Point{}, draw.Src)
+		inner := box.Inset(g.scale)
+		scaled := scaleImage(logo, inner.Dx(), inner.Dy())
+		draw.Draw(img, scaled.Bounds().Add(inner.Min), scaled, image.Point{}, draw.Over)
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// scaleImage fits src into a box keeping its aspect ratio, the result is centered in the box,
+// pixels are picked by nearest neighbour
+func scaleImage(src image.Image, width, height int) *image.RGBA {
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	bounds := src.Bounds()
+	if bounds.Empty() || width <= 0 || height <= 0 {
+		return dst
+	}
+
+	w, h := width, height
+	if bounds.Dx()*h > bounds.Dy()*w {
+		h = bounds.Dy() * w / bounds.Dx()
+	} else {
+		w = bounds.Dx() * h / bounds.Dy()
+	}
+	left, top := (width-w)/2, (height-h)/2
+
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			dst.Set(left+x, top+y, src.At(bounds.Min.X+x*bounds.Dx()/w, bounds.Min.Y+y*bounds.Dy()/h))
+		}
+	}
+
+	return dst
+}
+
+func encodeSVG(g grid, style Style, logo image.Image) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		g.size, g.size, g.size, g.size)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, g.size, g.size, style.Background)
+
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, style.Foreground)
+	for row, line := range g.bitmap {
+		for col, dark := range line {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh%dv%dh-%dz",
+					g.offset+(col+g.quietZone)*g.scale, g.offset+(row+g.quietZone)*g.scale, g.scale, g.scale, g.scale)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if logo != nil {
+		var logoPNG bytes.Buffer
+		if err := png.Encode(&logoPNG, logo); err != nil {
+			return nil, err
+		}
+
+		box := g.logoBox()
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+			box.Min.X, box.Min.Y, box.Dx(), box.Dy(), style.Background)
+
+		inner := box.Inset(g.scale)
+		fmt.Fprintf(&buf, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`,
+			inner.Min.X, inner.Min.Y, inner.Dx(), inner.Dy(), base64.StdEncoding.EncodeToString(logoPNG.Bytes()))
+	}
+
+	buf.WriteString("</svg>")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+func TestEncodePNG(t *testing.T) {
+
+	style := DefaultStyle()
+	style.Size = 300
+	style.Foreground = "#112233"
+
+	content, err := Encode("https://example.com/abc", style, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 300 || img.Bounds().Dy() != 300 {
+		t.Errorf("unexpected image size %v", img.Bounds())
+	}
+
+	// the corner is in the quiet zone and the finder pattern starts right after it
+	if r, g, b, _ := img.At(0, 0).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("expected background in the quiet zone, got %v", img.At(0, 0))
+	}
+	code, err := qrcode.New("https://example.com/abc", qrcode.Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code.DisableBorder = true
+	g := newGrid(code.Bitmap(), 4, 300)
+	scale, offset := g.scale, g.offset
+	if c := color.RGBAModel.Convert(img.At(offset+4*scale, offset+4*scale)).(color.RGBA); c.R != 0x11 || c.B != 0x33 {
+		t.Errorf("expected foreground at the finder pattern, got %v", c)
+	}
+}
+
+func TestEncodeLogo(t *testing.T) {
+
+	logo := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			logo.Set(x, y, color.RGBA{R: 0xff, A: 0xff})
+		}
+	}
+
+	style := DefaultStyle()
+	style.Logo = true
+	if err := style.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := Encode("https://example.com/abc", style, logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(img.At(128, 128)).(color.RGBA); c.R != 0xff || c.G != 0 {
+		t.Errorf("expected logo in the center, got %v", c)
+	}
+
+	style.Format = FormatSVG
+	content, err = Encode("https://example.com/abc", style, logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "<svg") || !strings.Contains(string(content), "data:image/png;base64,") {
+		t.Errorf("unexpected svg %s", content)
+	}
+}
+
+func TestEncodeSVG(t *testing.T) {
+
+	style := DefaultStyle()
+	style.Format = FormatSVG
+	style.Background = "#fafafa"
+
+	content, err := Encode("https://example.com/abc", style, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svg := string(content)
+	if !strings.Contains(svg, `width="256" height="256"`) || !strings.Contains(svg, `fill="#fafafa"`) ||
+		!strings.Contains(svg, `<path fill="#000000" d="M`) || strings.Contains(svg, "<image") {
+		t.Errorf("unexpected svg %s", svg)
+	}
+}
+
+func TestImageCache(t *testing.T) {
+
+	c := newImageCache(10)
+	c.put("a", []byte("1234"))
+	c.put("b", []byte("1234"))
+	c.get("a")
+	c.put("c", []byte("1234"))
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected least recently used image to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("expected recently used image to be kept")
+	}
+	c.put("d", []byte("12345678901"))
+	if _, ok := c.get("d"); ok {
+		t.Error("expected images over the cache size to be skipped")
+	}
+}
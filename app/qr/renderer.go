@@ -0,0 +1,109 @@
+package qr
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"image"
+	"image/png"
+	"log"
+	"strconv"
+
+	// logos are accepted in gif and jpeg formats too
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+const (
+	// MaxLogoBytes limits a size of uploaded logo files
+	MaxLogoBytes = 1 << 20
+	// maxLogoSide limits logo width and height in pixels
+	maxLogoSide = 1024
+)
+
+var (
+	// ErrNoLogo ...
+	ErrNoLogo = errors.New("account has no qr logo")
+	// ErrInvalidLogo ...
+	ErrInvalidLogo = errors.New("logo must be a png, jpeg or gif image")
+	// ErrLogoTooLarge ...
+	ErrLogoTooLarge = errors.New("logo must be at most 1024x1024 pixels")
+)
+
+// Renderer renders qr codes of short urls and caches rendered images
+type Renderer struct {
+	Repo   *Repository
+	Logger *log.Logger
+	cache  *imageCache
+}
+
+// NewRenderer creates a renderer keeping up to cacheSize bytes of rendered images
+func NewRenderer(repo *Repository, cacheSize int, logger *log.Logger) *Renderer {
+	return &Renderer{Repo: repo, Logger: logger, cache: newImageCache(cacheSize)}
+}
+
+// Render returns an image of a qr code with a validated style, the account logo is used by styles with a logo.
+// Cached images of a logo are invalidated by uploading a new logo
+func (r *Renderer) Render(accountID int64, content string, style Style) ([]byte, error) {
+
+	key := style.key() + ":" + content
+	if style.Logo {
+		version, err := r.Repo.GetLogoVersion(accountID)
+		if err == sql.ErrNoRows {
+			return nil, ErrNoLogo
+		} else if err != nil {
+			return nil, err
+		}
+		key = strconv.FormatInt(accountID, 10) + ":" + strconv.FormatInt(version.UnixNano(), 10) + ":" + key
+	}
+
+	if img, ok := r.cache.get(key); ok {
+		return img, nil
+	}
+
+	var logo image.Image
+	if style.Logo {
+		logoContent, err := r.Repo.GetLogo(accountID)
+		if err == sql.ErrNoRows {
+			return nil, ErrNoLogo
+		} else if err != nil {
+			return nil, err
+		}
+		if logo, err = png.Decode(bytes.NewReader(logoContent)); err != nil {
+			return nil, err
+		}
+	}
+
+	img, err := Encode(content, style, logo)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.put(key, img)
+
+	return img, nil
+}
+
+// NormalizeLogo checks an uploaded logo and converts it to png
+func NormalizeLogo(content []byte) ([]byte, error) {
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrInvalidLogo
+	}
+	if config.Width > maxLogoSide || config.Height > maxLogoSide {
+		return nil, ErrLogoTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrInvalidLogo
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
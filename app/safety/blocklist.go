@@ -0,0 +1,146 @@
+package safety
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"shortly/app/domains"
+)
+
+var (
+	// ErrInvalidEntryKind ...
+	ErrInvalidEntryKind = errors.New("kind must be one of: domain, ip, pattern")
+	// ErrInvalidEntryValue ...
+	ErrInvalidEntryValue = errors.New("value is not valid for entry kind")
+)
+
+// ValidateEntry checks entry value against its kind and normalizes domains and ip addresses
+func ValidateEntry(entry *Entry) error {
+
+	entry.Value = strings.TrimSpace(entry.Value)
+
+	switch entry.Kind {
+	case KindDomain:
+		entry.Value = domains.NormalizeHost(entry.Value)
+		if domains.ValidateHost(entry.Value) != nil {
+			return ErrInvalidEntryValue
+		}
+	case KindIP:
+		network, err := parseNetwork(entry.Value)
+		if err != nil {
+			return ErrInvalidEntryValue
+		}
+		entry.Value = network.String()
+	case KindPattern:
+		if entry.Value == "" {
+			return ErrInvalidEntryValue
+		}
+		if _, err := regexp.Compile(entry.Value); err != nil {
+			return ErrInvalidEntryValue
+		}
+	default:
+		return ErrInvalidEntryKind
+	}
+
+	return nil
+}
+
+// parseNetwork accepts a single ip address as a network of one host
+func parseNetwork(value string) (*net.IPNet, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	return network, err
+}
+
+type blockedNetwork struct {
+	network *net.IPNet
+	entry   Entry
+}
+
+type blockedPattern struct {
+	pattern *regexp.Regexp
+	entry   Entry
+}
+
+// Blocklist matches urls against blocklist entries
+type Blocklist struct {
+	domains  map[string]Entry
+	networks []blockedNetwork
+	patterns []blockedPattern
+}
+
+// NewBlocklist ...
+func NewBlocklist(entries []Entry) (*Blocklist, error) {
+
+	list := &Blocklist{domains: make(map[string]Entry)}
+
+	for _, entry := range entries {
+		switch entry.Kind {
+		case KindDomain:
+			list.domains[domains.NormalizeHost(entry.Value)] = entry
+		case KindIP:
+			network, err := parseNetwork(entry.Value)
+			if err != nil {
+				return nil, fmt.Errorf("blocklist entry %d: %v", entry.ID, err)
+			}
+			list.networks = append(list.networks, blockedNetwork{network: network, entry: entry})
+		case KindPattern:
+			pattern, err := regexp.Compile(entry.Value)
+			if err != nil {
+				return nil, fmt.Errorf("blocklist entry %d: %v", entry.ID, err)
+			}
+			list.patterns = append(list.patterns, blockedPattern{pattern: pattern, entry: entry})
+		}
+	}
+
+	return list, nil
+}
+
+// Match returns a blocklist entry matched by url host or the whole url
+func (b *Blocklist) Match(u *url.URL) (Entry, bool) {
+
+	if b == nil {
+		return Entry{}, false
+	}
+
+	host := domains.NormalizeHost(u.Hostname())
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range b.networks {
+			if n.network.Contains(ip) {
+				return n.entry, true
+			}
+		}
+	} else {
+		// a domain blocks its subdomains too
+		for name := host; name != ""; {
+			if entry, ok := b.domains[name]; ok {
+				return entry, true
+			}
+			i := strings.Index(name, ".")
+			if i < 0 {
+				break
+			}
+			name = name[i+1:]
+		}
+	}
+
+	for _, p := range b.patterns {
+		if p.pattern.MatchString(u.String()) {
+			return p.entry, true
+		}
+	}
+
+	return Entry{}, false
+}
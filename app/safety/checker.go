@@ -0,0 +1,353 @@
+package safety
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"shortly/app/domains"
+	"shortly/app/links"
+	"shortly/app/urls"
+)
+
+// risk scores added by checks, a result score is capped by 100
+const (
+	blocklistScore = 100
+	ipHostScore    = 40
+	punycodeScore  = 30
+	lookalikeScore = 60
+	shortenerScore = 30
+	redirectsScore = 30
+)
+
+// DefaultBrands are domains protected from lookalikes when none are configured
+var DefaultBrands = []string{
+	"google.com", "apple.com", "icloud.com", "microsoft.com", "office.com", "live.com", "amazon.com",
+	"paypal.com", "facebook.com", "instagram.com", "whatsapp.com", "netflix.com", "linkedin.com",
+	"twitter.com", "github.com", "dropbox.com", "chase.com", "wellsfargo.com", "bankofamerica.com",
+}
+
+// DefaultShorteners are known url shorteners when none are configured
+var DefaultShorteners = []string{
+	"bit.ly", "bitly.com", "tinyurl.com", "t.co", "goo.gl", "ow.ly", "is.gd", "buff.ly", "rebrand.ly",
+	"cutt.ly", "shorturl.at", "tiny.cc", "rb.gy", "s.id", "v.gd", "t.ly",
+}
+
+// Checker scores link destinations by blocklist and heuristics
+type Checker struct {
+	// Brands are domains protected from lookalikes
+	Brands []string
+	// Shorteners are hosts of url shorteners, links to them hide a final destination
+	Shorteners []string
+	// Client follows destination redirects, redirects are not followed when it is nil
+	Client *http.Client
+	// MaxRedirects is a number of redirects allowed before a destination
+	MaxRedirects int
+	// QuarantineScore and BlockScore are thresholds of quarantined and blocked statuses
+	QuarantineScore int
+	BlockScore      int
+
+	mu        sync.RWMutex
+	blocklist *Blocklist
+}
+
+// SetBlocklist replaces blocklist used by checks
+func (c *Checker) SetBlocklist(blocklist *Blocklist) {
+	c.mu.Lock()
+	c.blocklist = blocklist
+	c.mu.Unlock()
+}
+
+func (c *Checker) getBlocklist() *Blocklist {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blocklist
+}
+
+// check collects risk reasons, every reason is scored once
+type check struct {
+	score   int
+	reasons []string
+	seen    map[string]bool
+}
+
+func (c *check) add(score int, reason string) {
+	if c.seen[reason] {
+		return
+	}
+	c.seen[reason] = true
+	c.score += score
+	c.reasons = append(c.reasons, reason)
+}
+
+// Check scores a destination url, redirects are followed when checker has a client
+func (c *Checker) Check(ctx context.Context, destination string) Result {
+	return c.check(ctx, destination, c.Client != nil)
+}
+
+// CheckLocal scores a destination url by blocklist and host heuristics only, redirects are not followed
+func (c *Checker) CheckLocal(destination string) Result {
+	return c.check(context.Background(), destination, false)
+}
+
+func (c *Checker) check(ctx context.Context, destination string, followRedirects bool) Result {
+
+	if urls.Scheme(destination) == "" {
+		destination = "https://" + destination
+	}
+
+	result := &check{seen: make(map[string]bool)}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		result.add(blocklistScore, "destination url is not valid")
+		return c.result(result)
+	}
+
+	c.checkURL(u, result)
+
+	if followRedirects && (u.Scheme == "http" || u.Scheme == "https") {
+		hops := c.followRedirects(ctx, u)
+		for _, hop := range hops {
+			c.checkURL(hop, result)
+		}
+		if len(hops) > c.MaxRedirects {
+			result.add(redirectsScore, fmt.Sprintf("excessive redirects: more than %d", c.MaxRedirects))
+		}
+	}
+
+	return c.result(result)
+}
+
+func (c *Checker) result(check *check) Result {
+
+	score := check.score
+	if score > 100 {
+		score = 100
+	}
+
+	status := links.SafetyOK
+	if score >= c.BlockScore {
+		status = links.SafetyBlocked
+	} else if score >= c.QuarantineScore {
+		status = links.SafetyQuarantined
+	}
+
+	return Result{Score: score, Status: status, Reasons: check.reasons}
+}
+
+// checkURL applies blocklist and host heuristics to a single url
+func (c *Checker) checkURL(u *url.URL, result *check) {
+
+	if entry, ok := c.getBlocklist().Match(u); ok {
+		reason := fmt.Sprintf("blocklisted %s %s", entry.Kind, entry.Value)
+		if entry.Reason != "" {
+			reason += ": " + entry.Reason
+		}
+		result.add(blocklistScore, reason)
+	}
+
+	// hosts of application deep links are not domain names
+	host := domains.NormalizeHost(u.Hostname())
+	if host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+
+	if net.ParseIP(host) != nil {
+		result.add(ipHostScore, "ip address host "+host)
+		return
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			result.add(punycodeScore, "internationalized domain name "+host)
+			break
+		}
+	}
+
+	if brand, ok := lookalike(host, c.Brands); ok {
+		result.add(lookalikeScore, fmt.Sprintf("%s looks like %s", host, brand))
+	}
+
+	for _, shortener := range c.Shorteners {
+		if host == shortener || strings.HasSuffix(host, "."+shortener) {
+			result.add(shortenerScore, "chained url shortener "+shortener)
+			break
+		}
+	}
+}
+
+// followRedirects returns urls of destination redirects, at most MaxRedirects+1 are followed
+func (c *Checker) followRedirects(ctx context.Context, u *url.URL) []*url.URL {
+
+	client := *c.Client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	var hops []*url.URL
+	current := u
+
+	for len(hops) <= c.MaxRedirects {
+
+		req, err := http.NewRequest(http.MethodHead, current.String(), nil)
+		if err != nil {
+			break
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			break
+		}
+		_ = resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			break
+		}
+
+		next, err := current.Parse(location)
+		if err != nil {
+			break
+		}
+
+		hops = append(hops, next)
+		current = next
+	}
+
+	return hops
+}
+
+// confusables are replaced to reveal names imitating a brand with similar characters
+var confusables = strings.NewReplacer("0", "o", "1", "l", "3", "e", "5", "s", "rn", "m", "vv", "w")
+
+// lookalike checks whether a host imitates a brand domain
+func lookalike(host string, brands []string) (string, bool) {
+
+	site, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", false
+	}
+
+	for _, brand := range brands {
+		if site == brand {
+			return "", false
+		}
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(site)
+	name := strings.TrimSuffix(site, "."+suffix)
+
+	for _, brand := range brands {
+
+		// brand domain placed in subdomains of another site, like paypal.com.example.net
+		if strings.HasPrefix(host, brand+".") || strings.Contains(host, "."+brand+".") {
+			return brand, true
+		}
+
+		brandSuffix, _ := publicsuffix.PublicSuffix(brand)
+		brandName := strings.TrimSuffix(brand, "."+brandSuffix)
+
+		// the same name in another zone, like google.de
+		if name == brandName {
+			continue
+		}
+
+		if strings.HasPrefix(name, brandName+"-") || strings.HasSuffix(name, "-"+brandName) ||
+			strings.Contains(name, "-"+brandName+"-") {
+			return brand, true
+		}
+
+		if confusables.Replace(name) == brandName {
+			return brand, true
+		}
+
+		if len(brandName) >= 6 && editDistance(name, brandName) == 1 {
+			return brand, true
+		}
+	}
+
+	return "", false
+}
+
+// editDistance is a levenshtein distance of two strings
+func editDistance(a, b string) int {
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// NewClient returns a client for redirects check, connections to private networks are refused
+func NewClient(timeout time.Duration) *http.Client {
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("connection to %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}
+
+// isPrivateIP checks loopback, link local and private network addresses
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package safety
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shortly/app/links"
+)
+
+func newTestChecker(t *testing.T, entries ...Entry) *Checker {
+
+	blocklist, err := NewBlocklist(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker := &Checker{
+		Brands:          DefaultBrands,
+		Shorteners:      DefaultShorteners,
+		MaxRedirects:    2,
+		QuarantineScore: 50,
+		BlockScore:      80,
+	}
+	checker.SetBlocklist(blocklist)
+	return checker
+}
+
+func TestCheck(t *testing.T) {
+
+	checker := newTestChecker(t,
+		Entry{ID: 1, Kind: KindDomain, Value: "phishing.example"},
+		Entry{ID: 2, Kind: KindIP, Value: "203.0.113.0/24"},
+		Entry{ID: 3, Kind: KindPattern, Value: `/wp-admin/.*\.zip$`},
+	)
+
+	tests := []struct {
+		destination string
+		status      string
+	}{
+		{"https://example.com/page", links.SafetyOK},
+		{"example.com/page", links.SafetyOK},
+		{"https://www.google.com/search?q=shortly", links.SafetyOK},
+		{"https://google.de", links.SafetyOK},
+		{"https://login.phishing.example/account", links.SafetyBlocked},
+		{"http://203.0.113.7/login", links.SafetyBlocked},
+		{"https://example.com/wp-admin/files/payload.zip", links.SafetyBlocked},
+		{"http://198.51.100.1/", links.SafetyOK},
+		{"https://paypa1.com/signin", links.SafetyQuarantined},
+		{"https://paypal.com.account-verify.net/signin", links.SafetyQuarantined},
+		{"https://secure-paypal.com", links.SafetyQuarantined},
+		{"https://micros0ft.com", links.SafetyQuarantined},
+		{"https://bit.ly/abc", links.SafetyOK},
+		{"http://203.0.114.1", links.SafetyOK},
+		{"myapp://product/15", links.SafetyOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.destination, func(t *testing.T) {
+			result := checker.Check(context.Background(), tt.destination)
+			if result.Status != tt.status {
+				t.Errorf("expected %s, got %s (score %d, reasons %v)", tt.status, result.Status, result.Score, result.Reasons)
+			}
+		})
+	}
+}
+
+func TestCheckScoresAdd(t *testing.T) {
+
+	checker := newTestChecker(t)
+
+	result := checker.Check(context.Background(), "http://192.0.2.1/login")
+	if result.Score != ipHostScore || len(result.Reasons) != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result = checker.Check(context.Background(), "https://xn--pypal-4ve.com")
+	if result.Score != punycodeScore {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCheckRedirects(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/one":
+			http.Redirect(w, r, "/two", http.StatusFound)
+		case "/two":
+			http.Redirect(w, r, "/three", http.StatusFound)
+		case "/three":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/shortener":
+			http.Redirect(w, r, "https://bit.ly/abc", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	checker := newTestChecker(t)
+	checker.Client = server.Client()
+
+	// a test server runs on loopback address, it is an ip host
+	base := checker.Check(context.Background(), server.URL+"/final").Score
+
+	if result := checker.Check(context.Background(), server.URL+"/two"); result.Score != base {
+		t.Errorf("allowed redirects must not change a score, got %+v", result)
+	}
+
+	if result := checker.Check(context.Background(), server.URL+"/one"); result.Score != base+redirectsScore {
+		t.Errorf("excessive redirects must be scored, got %+v", result)
+	}
+
+	if result := checker.Check(context.Background(), server.URL+"/shortener"); result.Score != base+shortenerScore {
+		t.Errorf("redirect to a shortener must be scored, got %+v", result)
+	}
+
+	if result := checker.CheckLocal(server.URL + "/shortener"); result.Score != base {
+		t.Errorf("local check must not follow redirects, got %+v", result)
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+
+	tests := []struct {
+		entry    Entry
+		expected string
+		err      error
+	}{
+		{Entry{Kind: KindDomain, Value: " Evil.Example. "}, "evil.example", nil},
+		{Entry{Kind: KindDomain, Value: "not a domain"}, "", ErrInvalidEntryValue},
+		{Entry{Kind: KindIP, Value: "203.0.113.7"}, "203.0.113.7/32", nil},
+		{Entry{Kind: KindIP, Value: "203.0.113.0/24"}, "203.0.113.0/24", nil},
+		{Entry{Kind: KindIP, Value: "300.1.1.1"}, "", ErrInvalidEntryValue},
+		{Entry{Kind: KindPattern, Value: "(unclosed"}, "", ErrInvalidEntryValue},
+		{Entry{Kind: "host", Value: "example.com"}, "", ErrInvalidEntryKind},
+	}
+
+	for _, tt := range tests {
+		entry := tt.entry
+		err := ValidateEntry(&entry)
+		if err != tt.err {
+			t.Errorf("%+v: expected error %v, got %v", tt.entry, tt.err, err)
+			continue
+		}
+		if err == nil && entry.Value != tt.expected {
+			t.Errorf("%+v: expected value %s, got %s", tt.entry, tt.expected, entry.Value)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package safety
+
+import (
+	"time"
+)
+
+// Blocklist entry kinds
+const (
+	// KindDomain blocks a domain with all its subdomains
+	KindDomain = "domain"
+	// KindIP blocks an ip address or a network in CIDR notation
+	KindIP = "ip"
+	// KindPattern blocks urls matched by a regular expression
+	KindPattern = "pattern"
+)
+
+// Entry is a locally maintained blocklist record
+type Entry struct {
+	ID        int64
+	Kind      string
+	Value     string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// Result is a destination check verdict, Score is a risk from 0 to 100
+type Result struct {
+	Score   int
+	Status  string
+	Reasons []string
+}
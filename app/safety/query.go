@@ -0,0 +1,93 @@
+package safety
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// ErrEntryExists ...
+var ErrEntryExists = errors.New("blocklist entry already exists")
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+// GetEntries ...
+func (r *Repository) GetEntries() ([]Entry, error) {
+
+	rows, err := r.DB.Query("select id, kind, value, reason, created_at from blocklist order by id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]Entry, 0)
+	for rows.Next() {
+		var entry Entry
+		if err := rows.Scan(&entry.ID, &entry.Kind, &entry.Value, &entry.Reason, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, entry)
+	}
+
+	return list, rows.Err()
+}
+
+// CreateEntry ...
+func (r *Repository) CreateEntry(entry *Entry) (int64, error) {
+
+	var id int64
+	err := r.DB.QueryRow(
+		"insert into blocklist (kind, value, reason) values ($1, $2, $3) returning id",
+		entry.Kind, entry.Value, entry.Reason,
+	).Scan(&id)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return 0, ErrEntryExists
+	}
+
+	return id, err
+}
+
+// DeleteEntry ...
+func (r *Repository) DeleteEntry(id int64) error {
+
+	res, err := r.DB.Exec("delete from blocklist where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// LoadBlocklist reads blocklist entries into a checker
+func (r *Repository) LoadBlocklist(checker *Checker) error {
+
+	entries, err := r.GetEntries()
+	if err != nil {
+		return err
+	}
+
+	blocklist, err := NewBlocklist(entries)
+	if err != nil {
+		return err
+	}
+
+	checker.SetBlocklist(blocklist)
+	return nil
+}
@@ -0,0 +1,100 @@
+package safety
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"shortly/app/links"
+	"shortly/cache"
+	"shortly/utils"
+)
+
+const (
+	// recheckBatch is a number of links read for a recheck at once
+	recheckBatch = 100
+	// recheckPause is a pause between rechecks of stale links
+	recheckPause = time.Minute
+	// checkTimeout limits a check of a single link with its redirects
+	checkTimeout = 10 * time.Second
+)
+
+// CheckLink scores all link destinations, the riskiest result is returned
+func (c *Checker) CheckLink(ctx context.Context, link links.Link) Result {
+
+	destinations := []string{link.Long}
+	if link.FallbackURL != "" {
+		destinations = append(destinations, link.FallbackURL)
+	}
+	for _, rule := range link.Rules {
+		destinations = append(destinations, rule.Destination)
+		if rule.Fallback != "" {
+			destinations = append(destinations, rule.Fallback)
+		}
+	}
+	for _, variant := range link.Variants {
+		destinations = append(destinations, variant.Destination)
+	}
+
+	var worst Result
+	for i, destination := range destinations {
+		result := c.Check(ctx, destination)
+		if i == 0 || result.Score > worst.Score {
+			worst = result
+		}
+	}
+
+	return worst
+}
+
+// Recheck periodically checks links which were not checked for an interval,
+// links with a changed status are removed from url cache to be reloaded on next redirect
+func Recheck(repo *Repository, linksRepo *links.LinksRepository, checker *Checker, urlCache cache.UrlCache, interval time.Duration, logger *log.Logger) {
+
+	go func() {
+
+		for {
+			if err := repo.LoadBlocklist(checker); err != nil {
+				logger.Println("blocklist load error", err)
+			}
+
+			for {
+				list, err := linksRepo.GetLinksToCheck(utils.Now().Add(-interval), recheckBatch)
+				if err != nil {
+					logger.Println("links safety fetch error", err)
+					break
+				}
+
+				if !recheckLinks(linksRepo, checker, urlCache, list, logger) || len(list) < recheckBatch {
+					break
+				}
+			}
+
+			time.Sleep(recheckPause)
+		}
+
+	}()
+}
+
+// recheckLinks returns false when a result is not stored, the rest of links waits for next recheck
+func recheckLinks(linksRepo *links.LinksRepository, checker *Checker, urlCache cache.UrlCache, list []links.Link, logger *log.Logger) bool {
+
+	for _, link := range list {
+
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		result := checker.CheckLink(ctx, link)
+		cancel()
+
+		if err := linksRepo.UpdateLinkSafety(link.ID, result.Score, result.Status, result.Reasons); err != nil {
+			logger.Println("link safety update error", err)
+			return false
+		}
+
+		if result.Status != link.SafetyStatus {
+			logger.Printf("link safety changed, short=%v, status=%v, reasons=%v\n", link.Key(), result.Status, result.Reasons)
+			urlCache.Delete(link.Key())
+		}
+	}
+
+	return true
+}
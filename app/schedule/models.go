@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"errors"
+	"time"
+
+	"shortly/app/urls"
+)
+
+// schedule actions
+const (
+	ActionActivate   = "activate"
+	ActionDeactivate = "deactivate"
+	ActionSwap       = "swap"
+)
+
+// entry statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusApplied   = "applied"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrInvalidAction ...
+	ErrInvalidAction = errors.New("action must be one of activate, deactivate, swap")
+	// ErrPastRunAt ...
+	ErrPastRunAt = errors.New("runAt must be a future date")
+	// ErrNoDestination ...
+	ErrNoDestination = errors.New("destination is required for swap action")
+	// ErrUnexpectedDestination ...
+	ErrUnexpectedDestination = errors.New("destination is allowed for swap action only")
+)
+
+// Entry is a change of a link applied at a set time
+type Entry struct {
+	ID        int64
+	AccountID int64
+	// UserID is an author of the entry, destination swaps are versioned on behalf of the author
+	UserID      int64
+	LinkID      int64
+	Action      string
+	Destination string
+	RunAt       time.Time
+	Status      string
+	Error       string
+	CreatedAt   time.Time
+	AppliedAt   *time.Time
+}
+
+// Validate checks an entry and canonicalizes a swap destination
+func (e *Entry) Validate(now time.Time) error {
+
+	switch e.Action {
+	case ActionActivate, ActionDeactivate:
+		if e.Destination != "" {
+			return ErrUnexpectedDestination
+		}
+	case ActionSwap:
+		if e.Destination == "" {
+			return ErrNoDestination
+		}
+		destination, err := urls.Canonicalize(e.Destination, urls.Destination)
+		if err != nil {
+			return err
+		}
+		e.Destination = destination
+	default:
+		return ErrInvalidAction
+	}
+
+	if !e.RunAt.After(now) {
+		return ErrPastRunAt
+	}
+
+	return nil
+}
+
+// AppliedPayload is sent with link__scheduled webhook
+type AppliedPayload struct {
+	ID          int64     `json:"id"`
+	LinkID      int64     `json:"linkId"`
+	Short       string    `json:"short"`
+	Action      string    `json:"action"`
+	Destination string    `json:"destination,omitempty"`
+	RunAt       time.Time `json:"runAt"`
+}
@@ -0,0 +1,39 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"shortly/app/urls"
+)
+
+func TestEntryValidate(t *testing.T) {
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(12 * time.Hour)
+
+	cases := []struct {
+		entry Entry
+		err   error
+	}{
+		{Entry{Action: ActionActivate, RunAt: later}, nil},
+		{Entry{Action: ActionDeactivate, RunAt: later}, nil},
+		{Entry{Action: ActionSwap, Destination: "Example.com/sale", RunAt: later}, nil},
+		{Entry{Action: "delete", RunAt: later}, ErrInvalidAction},
+		{Entry{Action: ActionActivate, RunAt: now}, ErrPastRunAt},
+		{Entry{Action: ActionActivate, Destination: "https://example.com", RunAt: later}, ErrUnexpectedDestination},
+		{Entry{Action: ActionSwap, RunAt: later}, ErrNoDestination},
+		{Entry{Action: ActionSwap, Destination: "javascript:alert(1)", RunAt: later}, urls.ErrUnsafeScheme},
+	}
+
+	for _, c := range cases {
+		if err := c.entry.Validate(now); err != c.err {
+			t.Errorf("entry(%v, %q): expected error %v, got %v", c.entry.Action, c.entry.Destination, c.err, err)
+		}
+	}
+
+	entry := Entry{Action: ActionSwap, Destination: "Example.com/sale", RunAt: later}
+	if err := entry.Validate(now); err != nil || entry.Destination != "https://example.com/sale" {
+		t.Errorf("expected canonical destination, got %q, %v", entry.Destination, err)
+	}
+}
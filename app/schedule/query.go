@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+)
+
+// Repository ...
+type Repository struct {
+	DB     *sql.DB
+	Logger *log.Logger
+}
+
+const entryFields = `id, account_id, user_id, link_id, action, destination, run_at, status, error, created_at, applied_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row rowScanner, e *Entry) error {
+	return row.Scan(&e.ID, &e.AccountID, &e.UserID, &e.LinkID, &e.Action, &e.Destination, &e.RunAt, &e.Status, &e.Error,
+		&e.CreatedAt, &e.AppliedAt)
+}
+
+func (r *Repository) queryEntries(query string, args ...interface{}) ([]Entry, error) {
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list := make([]Entry, 0)
+	for rows.Next() {
+		var e Entry
+		if err := scanEntry(rows, &e); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
+
+// CreateEntry ...
+func (r *Repository) CreateEntry(e *Entry) error {
+	e.Status = StatusPending
+	return r.DB.QueryRow(`
+		insert into link_schedules (account_id, user_id, link_id, action, destination, run_at, status, created_at)
+		values ($1, $2, $3, $4, $5, $6, $7, now()) returning id, created_at`,
+		e.AccountID, e.UserID, e.LinkID, e.Action, e.Destination, e.RunAt, e.Status,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+// GetEntries returns entries of an account ordered by run time, linkID and status are optional filters
+func (r *Repository) GetEntries(accountID, linkID int64, status string) ([]Entry, error) {
+	return r.queryEntries(`select `+entryFields+` from link_schedules
+		where account_id = $1 and ($2 = 0 or link_id = $2) and ($3 = '' or status = $3)
+		order by run_at, id`,
+		accountID, linkID, status,
+	)
+}
+
+// CancelEntry cancels a pending entry, sql.ErrNoRows is returned for missing and already started entries
+func (r *Repository) CancelEntry(accountID, id int64) error {
+	res, err := r.DB.Exec(
+		"update link_schedules set status = $1 where id = $2 and account_id = $3 and status = $4",
+		StatusCancelled, id, accountID, StatusPending,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// claimTimeout is a period after which running entries are considered abandoned by a stopped scheduler
+// and are claimed again, entries actions are idempotent
+const claimTimeout = 10 * time.Minute
+
+// ClaimDueEntries marks pending entries which run time has come as running and returns them ordered by run time,
+// entries claimed by another scheduler instance are skipped unless they are running longer than claimTimeout
+func (r *Repository) ClaimDueEntries(now time.Time, limit int) ([]Entry, error) {
+	list, err := r.queryEntries(`update link_schedules set status = $1, claimed_at = $3
+		where id in (
+			select id from link_schedules where (status = $2 and run_at <= $3) or (status = $1 and claimed_at < $5)
+			order by run_at, id limit $4 for update skip locked
+		) returning `+entryFields,
+		StatusRunning, StatusPending, now, limit, now.Add(-claimTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].RunAt.Equal(list[j].RunAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].RunAt.Before(list[j].RunAt)
+	})
+
+	return list, nil
+}
+
+// FinishEntry stores a result of an applied entry
+func (r *Repository) FinishEntry(id int64, status, errorMessage string) error {
+	_, err := r.DB.Exec(
+		"update link_schedules set status = $1, error = $2, applied_at = now() where id = $3",
+		status, errorMessage, id,
+	)
+	return err
+}
@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var entryColumns = []string{"id", "account_id", "user_id", "link_id", "action", "destination", "run_at", "status", "error",
+	"created_at", "applied_at"}
+
+func TestClaimDueEntries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	midnight := now.Add(-time.Minute)
+
+	mock.ExpectQuery("update link_schedules set status = \\$1").WithArgs(StatusRunning, StatusPending, now, 10, now.Add(-claimTimeout)).WillReturnRows(
+		sqlmock.NewRows(entryColumns).
+			AddRow(3, 1, 1, 7, ActionSwap, "https://example.com/sale", midnight, StatusRunning, "", midnight, nil).
+			AddRow(2, 1, 1, 7, ActionActivate, "", midnight, StatusRunning, "", midnight, nil).
+			AddRow(1, 1, 1, 8, ActionDeactivate, "", midnight.Add(-time.Hour), StatusRunning, "", midnight, nil))
+
+	repo := &Repository{DB: db}
+	list, err := repo.ClaimDueEntries(now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 3 || list[0].ID != 1 || list[1].ID != 2 || list[2].ID != 3 {
+		t.Errorf("entries must be ordered by run time and id, got %+v", list)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCancelEntry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("update link_schedules set status = \\$1").WithArgs(StatusCancelled, int64(5), int64(1), StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("update link_schedules set status = \\$1").WithArgs(StatusCancelled, int64(5), int64(1), StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &Repository{DB: db}
+
+	if err := repo.CancelEntry(1, 5); err != nil {
+		t.Errorf("expected cancelled entry, got %v", err)
+	}
+	if err := repo.CancelEntry(1, 5); err != sql.ErrNoRows {
+		t.Errorf("expected no rows error for applied entry, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
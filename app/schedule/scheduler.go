@@ -0,0 +1,146 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"shortly/app/links"
+	"shortly/app/safety"
+	"shortly/cache"
+	"shortly/utils"
+)
+
+const (
+	// schedulerBatch is a number of due entries claimed at once
+	schedulerBatch = 100
+	// checkTimeout limits a safety check of a swapped destination
+	checkTimeout = 10 * time.Second
+)
+
+// errLinkNotFound is stored for entries of deleted links
+var errLinkNotFound = errors.New("link not found")
+
+// Scheduler applies due schedule entries, links are changed with the same repository methods
+// as link endpoints, so repository callbacks and webhooks are fired
+type Scheduler struct {
+	Entries  *Repository
+	Links    *links.LinksRepository
+	Checker  *safety.Checker
+	URLCache cache.UrlCache
+	Interval time.Duration
+	Logger   *log.Logger
+	// OnApplied is called for every applied entry
+	OnApplied func(int64, interface{})
+}
+
+// Start ...
+func (s *Scheduler) Start() {
+
+	go func() {
+		for {
+			s.Run()
+			time.Sleep(s.Interval)
+		}
+	}()
+}
+
+// Run applies all due entries
+func (s *Scheduler) Run() {
+
+	for {
+		list, err := s.Entries.ClaimDueEntries(utils.Now(), schedulerBatch)
+		if err != nil {
+			s.Logger.Println("schedule entries fetch error", err)
+			return
+		}
+
+		for _, entry := range list {
+			status, message := StatusApplied, ""
+			if err := s.Apply(entry); err != nil {
+				s.Logger.Printf("schedule entry(%v) of link(%v) error: %v\n", entry.ID, entry.LinkID, err)
+				status, message = StatusFailed, err.Error()
+			}
+			if err := s.Entries.FinishEntry(entry.ID, status, message); err != nil {
+				s.Logger.Println("schedule entry finish error", err)
+			}
+		}
+
+		if len(list) < schedulerBatch {
+			return
+		}
+	}
+}
+
+// Apply changes a link of an entry, a cached link is removed to be reloaded on next redirect
+func (s *Scheduler) Apply(entry Entry) error {
+
+	link, err := s.Links.GetLinkByID(entry.LinkID)
+	if err != nil || link.AccountID != entry.AccountID {
+		return errLinkNotFound
+	}
+
+	switch entry.Action {
+	case ActionActivate:
+		tx, err := s.Links.ActivateUserLink(entry.AccountID, link.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+	case ActionDeactivate:
+		tx, err := s.Links.HideUserLink(entry.AccountID, link.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+	case ActionSwap:
+		link.Long = entry.Destination
+
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		result := s.Checker.CheckLink(ctx, link)
+		cancel()
+		if result.Status == links.SafetyBlocked {
+			return errors.New("destination url is blocked: " + strings.Join(result.Reasons, "; "))
+		}
+		link.SafetyScore = result.Score
+		link.SafetyStatus = result.Status
+		link.SafetyReasons = result.Reasons
+
+		tx, err := s.Links.UpdateUserLink(entry.AccountID, entry.UserID, link.ID, &link)
+		if err != nil {
+			if tx != nil {
+				_ = tx.Rollback()
+			}
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+	default:
+		return ErrInvalidAction
+	}
+
+	s.URLCache.Delete(link.Key())
+
+	if s.OnApplied != nil {
+		s.OnApplied(entry.AccountID, &AppliedPayload{
+			ID:          entry.ID,
+			LinkID:      link.ID,
+			Short:       link.Key(),
+			Action:      entry.Action,
+			Destination: entry.Destination,
+			RunAt:       entry.RunAt,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package trash
+
+import (
+	"log"
+	"time"
+
+	"shortly/app/billing"
+	"shortly/app/data"
+	"shortly/app/links"
+	"shortly/utils"
+)
+
+const (
+	// purgeBatch is a number of links read for purge at once
+	purgeBatch = 100
+)
+
+// Purger removes links which stay in trash longer than a retention period
+type Purger struct {
+	Links     *links.LinksRepository
+	History   *data.HistoryDB
+	Billing   *billing.BillingLimiter
+	Retention time.Duration
+	Interval  time.Duration
+	Logger    *log.Logger
+}
+
+// Start ...
+func (p *Purger) Start() {
+
+	go func() {
+		for {
+			p.Run()
+			time.Sleep(p.Interval)
+		}
+	}()
+}
+
+// Run purges all links with expired retention
+func (p *Purger) Run() {
+
+	for {
+		list, err := p.Links.GetLinksToPurge(utils.Now().Add(-p.Retention), purgeBatch)
+		if err != nil {
+			p.Logger.Println("trash links fetch error", err)
+			return
+		}
+
+		for _, link := range list {
+			if err := p.Purge(link); err != nil {
+				p.Logger.Println("link purge error", err)
+				return
+			}
+		}
+
+		if len(list) < purgeBatch {
+			return
+		}
+	}
+}
+
+// Purge removes a trashed link with its click history and frees a billing counter of the link
+func (p *Purger) Purge(link links.Link) error {
+
+	lock := p.Billing.Lock(link.AccountID)
+	defer lock.Unlock()
+
+	if err := p.Links.PurgeLink(link.ID); err != nil {
+		return err
+	}
+
+	if err := p.Billing.ReleaseLink(link.AccountID, link.CreatedAt); err != nil {
+		p.Logger.Println("billing counter release error", err)
+		_ = p.Billing.Reset("url_limit", link.AccountID)
+	}
+
+	return p.History.DeleteLink(link.Key())
+}
@@ -0,0 +1,289 @@
+package urls
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// MaxLength is a maximal length of a canonical url
+const MaxLength = 4096
+
+var (
+	// ErrEmpty ...
+	ErrEmpty = errors.New("url is empty")
+	// ErrTooLong ...
+	ErrTooLong = errors.New("url is too long")
+	// ErrInvalid ...
+	ErrInvalid = errors.New("url has incorrect format")
+	// ErrInvalidHost ...
+	ErrInvalidHost = errors.New("url host is not valid")
+	// ErrCredentials ...
+	ErrCredentials = errors.New("url must not contain credentials")
+	// ErrUnsafeScheme ...
+	ErrUnsafeScheme = errors.New("url scheme is not allowed for security reasons")
+	// ErrSchemeNotAllowed ...
+	ErrSchemeNotAllowed = errors.New("url scheme is not supported")
+)
+
+// UnsafeSchemes are never accepted, they run code or read local data in a browser
+var UnsafeSchemes = []string{"javascript", "data", "vbscript", "file", "blob"}
+
+// ContactSchemes open a phone, mail or messaging application
+var ContactSchemes = []string{"tel", "mailto", "sms"}
+
+// Policy lists url schemes accepted in addition to http and https
+type Policy struct {
+	// Contact allows tel:, mailto: and sms: urls
+	Contact bool
+	// App allows application deep links like myapp://product/1
+	App bool
+}
+
+var (
+	// Web accepts http and https urls only
+	Web = Policy{}
+	// Destination accepts urls of link destinations
+	Destination = Policy{Contact: true}
+	// DeepLink accepts urls of destinations which may open an application
+	DeepLink = Policy{Contact: true, App: true}
+)
+
+var (
+	schemePattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+	hostPattern   = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+)
+
+// Scheme returns a lower cased scheme of a raw url or an empty string for urls without a scheme,
+// a host with a port like "example.com:8080" is not taken for a scheme
+func Scheme(rawURL string) string {
+	m := schemePattern.FindStringSubmatch(strings.TrimSpace(rawURL))
+	if m == nil {
+		return ""
+	}
+	scheme := strings.ToLower(m[1])
+	rest := strings.TrimSpace(rawURL)[len(m[0]):]
+	if strings.Contains(scheme, ".") || (!contains(ContactSchemes, scheme) && !contains(UnsafeSchemes, scheme) &&
+		!strings.HasPrefix(rest, "//") && scheme != "http" && scheme != "https") {
+		return ""
+	}
+	return scheme
+}
+
+// IsWeb checks whether a url is an http url, urls without a scheme are treated as https urls
+func IsWeb(rawURL string) bool {
+	scheme := Scheme(rawURL)
+	return scheme == "" || scheme == "http" || scheme == "https"
+}
+
+// Canonicalize validates a url against a policy and returns its canonical form, urls without a scheme
+// are treated as https urls. Scheme and host are lower cased, international hosts are converted to punycode,
+// default ports are removed, an empty path becomes "/", dot segments are resolved and percent-encoding is
+// normalized, so equal destinations have equal canonical urls
+func Canonicalize(rawURL string, policy Policy) (string, error) {
+
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", ErrEmpty
+	}
+	if len(rawURL) > MaxLength {
+		return "", ErrTooLong
+	}
+	for _, c := range rawURL {
+		if c < 0x20 || c == 0x7f || c == ' ' {
+			return "", ErrInvalid
+		}
+	}
+
+	scheme := Scheme(rawURL)
+	switch {
+	case scheme == "":
+		return canonicalWeb("https://" + rawURL)
+	case scheme == "http" || scheme == "https":
+		return canonicalWeb(rawURL)
+	case contains(UnsafeSchemes, scheme):
+		return "", ErrUnsafeScheme
+	case contains(ContactSchemes, scheme):
+		if !policy.Contact {
+			return "", ErrSchemeNotAllowed
+		}
+		return canonicalOpaque(scheme, rawURL)
+	}
+
+	if !policy.App {
+		return "", ErrSchemeNotAllowed
+	}
+	return canonicalOpaque(scheme, rawURL)
+}
+
+func canonicalWeb(rawURL string) (string, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	if u.User != nil {
+		return "", ErrCredentials
+	}
+
+	host, err := canonicalHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+
+	path := removeDotSegments(normalizeEscapes(u.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme + "://" + host + path)
+	if u.RawQuery != "" || u.ForceQuery {
+		b.WriteString("?" + normalizeEscapes(u.RawQuery))
+	}
+	if u.Fragment != "" {
+		b.WriteString("#" + normalizeEscapes(u.EscapedFragment()))
+	}
+
+	canonical := b.String()
+	if len(canonical) > MaxLength {
+		return "", ErrTooLong
+	}
+	return canonical, nil
+}
+
+// canonicalHost converts a host to lower cased ascii form, ip addresses are kept as is
+func canonicalHost(host string) (string, error) {
+
+	if host == "" {
+		return "", ErrInvalidHost
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() == nil {
+			return "[" + ip.String() + "]", nil
+		}
+		return ip.String(), nil
+	}
+
+	host = strings.TrimSuffix(host, ".")
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", ErrInvalidHost
+	}
+	ascii = strings.ToLower(ascii)
+
+	if len(ascii) > 253 || !strings.Contains(ascii, ".") || !hostPattern.MatchString(ascii) {
+		return "", ErrInvalidHost
+	}
+	return ascii, nil
+}
+
+// canonicalOpaque handles urls like mailto:user@example.com or myapp://product/1
+func canonicalOpaque(scheme, rawURL string) (string, error) {
+	rest := normalizeEscapes(rawURL[len(scheme)+1:])
+	if strings.Trim(rest, "/") == "" {
+		return "", ErrInvalid
+	}
+	return scheme + ":" + rest, nil
+}
+
+// normalizeEscapes upper cases percent-encoded octets, decodes unreserved characters and encodes
+// characters which must not appear in a url, reserved characters are kept as is
+func normalizeEscapes(s string) string {
+
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			v := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(v) {
+				b.WriteByte(v)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(hex[v>>4])
+				b.WriteByte(hex[v&15])
+			}
+			i += 2
+			continue
+		}
+		if isUnreserved(c) || strings.IndexByte("!#$&'()*+,/:;=?@[]~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
+
+// removeDotSegments resolves "." and ".." path segments as described in RFC 3986, a trailing slash is kept
+func removeDotSegments(path string) string {
+
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	var out []string
+	for i, s := range segments {
+		last := i == len(segments)-1
+		switch s {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
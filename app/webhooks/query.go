@@ -168,7 +168,7 @@ func (r *WebhooksRepository) DeleteWebhook(accountID int64, id int64) error {
 
 	err = r.Cache.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("webhooks"))
-		for _, event := range []string{"link__created", "link__deleted", "link__redirect"} {
+		for _, event := range []string{"link__created", "link__deleted", "link__redirect", "link__broken", "link__scheduled"} {
 			if err := b.Delete([]byte(strconv.Itoa(int(accountID)) + ":" + event)); err != nil {
 				return err
 			}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"log"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 )
@@ -38,11 +39,18 @@ func (ch *MemcachedCache) Store(key interface{}, value interface{}) {
 	}
 }
 
+// maxRelativeExpiration memcached treats expiration values above 30 days as unix timestamps
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
 func (ch *MemcachedCache) StoreExp(key interface{}, value interface{}, ttl int) {
+	expiration := int32(ttl)
+	if ttl > maxRelativeExpiration {
+		expiration = int32(time.Now().Unix() + int64(ttl))
+	}
 	err := ch.c.Set(&memcache.Item{
 		Key:        key.(string),
 		Value:      []byte(value.(string)),
-		Expiration: int32(ttl),
+		Expiration: expiration,
 	})
 	if err != nil {
 		ch.logger.Printf("cache set(key=%v) error, cause: %+v\n", key, err)
@@ -57,7 +57,7 @@ func (ch *MemoryCache) Load(key interface{}) (interface{}, bool) {
 	if !ok {
 		return nil, false
 	}
-	return i.(CacheItem), true
+	return i.(CacheItem).Value, true
 }
 
 func (ch *MemoryCache) Store(key interface{}, value interface{}) {
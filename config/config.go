@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -70,6 +71,73 @@ type MaintanceConfig struct {
 	Password string
 }
 
+// DomainsConfig ...
+type DomainsConfig struct {
+	// DNSServer is an optional address (host:port) of a dns server used for domain verification
+	DNSServer string
+}
+
+// SafetyConfig ...
+type SafetyConfig struct {
+	// Brands are domains protected from lookalikes, Shorteners are known url shorteners
+	Brands     []string
+	Shorteners []string
+	// FollowRedirects enables requests to destinations for redirects check
+	FollowRedirects bool
+	MaxRedirects    int
+	Timeout         time.Duration
+	// links with a score above QuarantineScore are quarantined, above BlockScore are blocked
+	QuarantineScore int
+	BlockScore      int
+	RecheckInterval time.Duration
+}
+
+// HealthConfig ...
+type HealthConfig struct {
+	// Enabled starts destination health monitor
+	Enabled  bool
+	Interval time.Duration
+	Timeout  time.Duration
+	Workers  int
+	// HostConcurrency limits simultaneous probes of a host, failed hosts are paused from Backoff up to MaxBackoff
+	HostConcurrency int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	// BrokenWebhook enables link__broken webhooks
+	BrokenWebhook bool
+}
+
+// TrashConfig ...
+type TrashConfig struct {
+	// Retention is a period deleted links stay in trash before purge
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// ScheduleConfig ...
+type ScheduleConfig struct {
+	// Interval is a period between checks for due schedule entries
+	Interval time.Duration
+}
+
+// IdempotencyConfig ...
+type IdempotencyConfig struct {
+	// Window is a period responses of requests with an Idempotency-Key header are replayed
+	Window time.Duration
+}
+
+// QRConfig ...
+type QRConfig struct {
+	// CacheSize limits a total size of rendered qr images kept in memory, in bytes
+	CacheSize int
+}
+
+// PixelsConfig ...
+type PixelsConfig struct {
+	// Delay is a default delay of the interstitial page firing link pixels before a redirect
+	Delay time.Duration
+}
+
 type ApplicationConfig struct {
 	Server   ServerConfig
 	Database DatabaseConfig
@@ -83,6 +151,14 @@ type ApplicationConfig struct {
 	RedirectLogger RedirectLoggerConfig
 	Maintance      MaintanceConfig
 	GeoIP          GeoIPConfig
+	Domains        DomainsConfig
+	Safety         SafetyConfig
+	Health         HealthConfig
+	Trash          TrashConfig
+	Idempotency    IdempotencyConfig
+	Schedule       ScheduleConfig
+	QR             QRConfig
+	Pixels         PixelsConfig
 }
 
 type ServerConfig struct {
@@ -109,6 +185,39 @@ func setDefaults(cfg *viper.Viper) {
 	cfg.SetDefault("Database.SSLMode", "disable")
 
 	cfg.SetDefault("Billing.Dir", ".")
+
+	// destination safety checks default settings
+	cfg.SetDefault("Safety.FollowRedirects", true)
+	cfg.SetDefault("Safety.MaxRedirects", 3)
+	cfg.SetDefault("Safety.Timeout", "3s")
+	cfg.SetDefault("Safety.QuarantineScore", 50)
+	cfg.SetDefault("Safety.BlockScore", 80)
+	cfg.SetDefault("Safety.RecheckInterval", "24h")
+
+	// destination health monitor default settings
+	cfg.SetDefault("Health.Enabled", true)
+	cfg.SetDefault("Health.Interval", "6h")
+	cfg.SetDefault("Health.Timeout", "10s")
+	cfg.SetDefault("Health.Workers", 8)
+	cfg.SetDefault("Health.HostConcurrency", 2)
+	cfg.SetDefault("Health.Backoff", "1m")
+	cfg.SetDefault("Health.MaxBackoff", "1h")
+
+	// trash default settings
+	cfg.SetDefault("Trash.Retention", "720h")
+	cfg.SetDefault("Trash.PurgeInterval", "1h")
+
+	// link schedule default settings
+	cfg.SetDefault("Schedule.Interval", "1m")
+
+	// idempotency keys default settings
+	cfg.SetDefault("Idempotency.Window", "24h")
+
+	// qr codes default settings
+	cfg.SetDefault("QR.CacheSize", 64<<20)
+
+	// retargeting pixels default settings
+	cfg.SetDefault("Pixels.Delay", "500ms")
 }
 
 func ReadConfig(configFilePath string) (*ApplicationConfig, error) {
@@ -33,14 +33,25 @@ import (
 
 	"shortly/app/accounts"
 	"shortly/app/billing"
+	"shortly/app/bulk"
 	"shortly/app/campaigns"
 	"shortly/app/clicks"
 	"shortly/app/dashboards"
 	"shortly/app/data"
+	"shortly/app/domains"
+	"shortly/app/health"
+	"shortly/app/idempotency"
+	"shortly/app/importer"
 	"shortly/app/links"
 	"shortly/app/maintance"
+	"shortly/app/pages"
+	"shortly/app/pixels"
+	"shortly/app/qr"
 	"shortly/app/rbac"
+	"shortly/app/safety"
+	"shortly/app/schedule"
 	"shortly/app/tags"
+	"shortly/app/trash"
 	"shortly/app/webhooks"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -59,7 +70,7 @@ func LoadCacheFromDatabase(repo *links.LinksRepository, urlCache cache.UrlCache)
 	}
 
 	for _, r := range rows {
-		urlCache.Store(r.Short, r.Long)
+		links.StoreCache(urlCache, r)
 	}
 
 	return nil
@@ -73,24 +84,60 @@ func LoadHistoryFromDatabase(repo *links.LinksRepository, clicksRepo *clicks.Rep
 	}
 
 	for _, r := range rows {
-		if err := historyDB.InsertDetail(r.Short, r.AccountID); err != nil {
+		key := r.Key()
+
+		if err := historyDB.InsertDetail(key, r.AccountID); err != nil {
 			return err
 		}
 
-		_ = historyDB.DeleteClicks(r.Short)
-		_ = historyDB.DeleteInfos(r.Short)
+		_ = historyDB.DeleteClicks(key)
+		_ = historyDB.DeleteInfos(key)
 
-		clickData, err := clicksRepo.GetClicksDataByDay(r.Short)
+		clickData, err := clicksRepo.GetClicksDataByDay(key)
 		if err != nil {
 			return err
 		}
 
+		var totalClicks int64
 		for _, d := range clickData {
-			if err := historyDB.InsertClick(r.Short, d.Time, int(d.Count)); err != nil {
+			if err := historyDB.InsertClick(key, d.Time, int(d.Count)); err != nil {
+				return err
+			}
+			totalClicks += d.Count
+		}
+
+		if err := historyDB.SetTotalClicks(key, totalClicks); err != nil {
+			return err
+		}
+
+		_ = historyDB.DeleteScans(key)
+
+		scanData, err := clicksRepo.GetScansByDay(key)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range scanData {
+			if err := historyDB.InsertScans(key, d.Time, int(d.Count)); err != nil {
 				return err
 			}
 		}
-		info, err := clicksRepo.GetLinkInfoByDay(r.Short)
+
+		_ = historyDB.DeleteVariants(key)
+
+		variantData, err := clicksRepo.GetVariantClicksByDay(key)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range variantData {
+			variant := strconv.FormatInt(d.VariantID, 10)
+			if err := historyDB.InsertVariantClick(key, variant, d.Time, int(d.Count)); err != nil {
+				return err
+			}
+		}
+
+		info, err := clicksRepo.GetLinkInfoByDay(key)
 		if err != nil {
 			return err
 		}
@@ -105,10 +152,24 @@ func LoadHistoryFromDatabase(repo *links.LinksRepository, clicksRepo *clicks.Rep
 			}
 			agg[d.Time].Referrers[d.Referer] += int(d.Count)
 			agg[d.Time].Locations[d.Location] += int(d.Count)
+			if d.RuleID > 0 {
+				if agg[d.Time].Rules == nil {
+					agg[d.Time].Rules = make(map[string]int)
+				}
+				agg[d.Time].Rules[strconv.FormatInt(d.RuleID, 10)] += int(d.Count)
+			}
+			source := d.Source
+			if source == "" {
+				source = data.SourceDirect
+			}
+			if agg[d.Time].Sources == nil {
+				agg[d.Time].Sources = make(map[string]int)
+			}
+			agg[d.Time].Sources[source] += int(d.Count)
 		}
 
 		for t, d := range agg {
-			if err := historyDB.InsertInfo(r.Short, t, *d); err != nil {
+			if err := historyDB.InsertInfo(key, t, *d); err != nil {
 				return err
 			}
 		}
@@ -199,6 +260,12 @@ func main() {
 	linksRepository.OnDelete(webhooks.Send("link__deleted"))
 	linksRepository.OnHide(webhooks.Send("link__hide"))
 
+	domainsRepository := &domains.Repository{
+		DB:       database,
+		Resolver: domains.NewResolver(appConfig.Domains.DNSServer),
+		Logger:   logger,
+	}
+
 	err = billingDataStorage.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte("billing"))
 		if err != nil {
@@ -285,11 +352,111 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	// destinations are normalized before requests are served, so existing links are reused
+	n, err := linksRepository.NormalizeStoredURLs()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if n > 0 {
+		logger.Printf("normalized destinations of %v links\n", n)
+	}
+
 	err = LoadCacheFromDatabase(linksRepository, urlCache)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
+	// destination safety checks
+
+	safetyConfig := appConfig.Safety
+	linkChecker := &safety.Checker{
+		Brands:          safetyConfig.Brands,
+		Shorteners:      safetyConfig.Shorteners,
+		MaxRedirects:    safetyConfig.MaxRedirects,
+		QuarantineScore: safetyConfig.QuarantineScore,
+		BlockScore:      safetyConfig.BlockScore,
+	}
+	if len(linkChecker.Brands) == 0 {
+		linkChecker.Brands = safety.DefaultBrands
+	}
+	if len(linkChecker.Shorteners) == 0 {
+		linkChecker.Shorteners = safety.DefaultShorteners
+	}
+	if safetyConfig.FollowRedirects {
+		linkChecker.Client = safety.NewClient(safetyConfig.Timeout)
+	}
+
+	safetyRepository := &safety.Repository{DB: database, Logger: logger}
+	if err := safetyRepository.LoadBlocklist(linkChecker); err != nil {
+		logger.Fatal(err)
+	}
+	safety.Recheck(safetyRepository, linksRepository, linkChecker, urlCache, safetyConfig.RecheckInterval, logger)
+
+	// destination health monitor
+
+	healthConfig := appConfig.Health
+	if healthConfig.Enabled {
+		healthMonitor := &health.Monitor{
+			Links:   linksRepository,
+			History: historyDB,
+			Prober: &health.Prober{
+				// destinations are user supplied, private network addresses are refused on every redirect hop
+				Client:          safety.NewClient(healthConfig.Timeout),
+				HostConcurrency: healthConfig.HostConcurrency,
+				Backoff:         healthConfig.Backoff,
+				MaxBackoff:      healthConfig.MaxBackoff,
+			},
+			Interval: healthConfig.Interval,
+			Timeout:  healthConfig.Timeout,
+			Workers:  healthConfig.Workers,
+			Logger:   logger,
+		}
+		if healthConfig.BrokenWebhook {
+			healthMonitor.OnBroken = webhooks.Send("link__broken")
+		}
+		healthMonitor.Start()
+	}
+
+	// trash purge
+
+	trashConfig := appConfig.Trash
+	trashPurger := &trash.Purger{
+		Links:     linksRepository,
+		History:   historyDB,
+		Billing:   billingLimiter,
+		Retention: trashConfig.Retention,
+		Interval:  trashConfig.PurgeInterval,
+		Logger:    logger,
+	}
+	trashPurger.Start()
+
+	// scheduled link changes
+
+	scheduleRepository := &schedule.Repository{DB: database, Logger: logger}
+	linkScheduler := &schedule.Scheduler{
+		Entries:   scheduleRepository,
+		Links:     linksRepository,
+		Checker:   linkChecker,
+		URLCache:  urlCache,
+		Interval:  appConfig.Schedule.Interval,
+		Logger:    logger,
+		OnApplied: webhooks.Send("link__scheduled"),
+	}
+	linkScheduler.Start()
+
+	// link-in-bio pages
+
+	pagesRepository := &pages.Repository{DB: database, Logger: logger}
+
+	// qr codes
+
+	qrRepository := &qr.Repository{DB: database, Logger: logger}
+	qrRenderer := qr.NewRenderer(qrRepository, appConfig.QR.CacheSize, logger)
+
+	// retargeting pixels
+
+	pixelsRepository := &pixels.Repository{DB: database, Logger: logger}
+
 	err = LoadHistoryFromDatabase(linksRepository, clicksRepository, historyDB)
 	if err != nil {
 		logger.Fatal(err)
@@ -366,7 +533,7 @@ func main() {
 	totalLinkCreatedPromMiddleware := utils.PrometheusMiddleware("totalLinksCreated", "TODO description")
 	r.Get("/api/v1/links", api.GetURLList(linksRepository, logger))
 	r.Post("/api/v1/links", totalLinkCreatedPromMiddleware(
-		api.CreateLink(linksRepository, urlCache, logger)))
+		api.CreateLink(linksRepository, linkChecker, urlCache, logger)))
 
 	// private api (with authorized access)
 	enforcer, err := rbac.NewEnforcer(database, appConfig.Casbin)
@@ -414,7 +581,8 @@ func main() {
 	))
 
 	// links api
-	api.LinksRoutes(r, auth, linksRepository, logger, historyDB)
+	api.LinksRoutes(r, auth, linksRepository, logger, historyDB, urlCache, linkChecker)
+	api.DomainsRoutes(r, auth, domainsRepository, logger)
 
 	// account api
 	usersRepository := &accounts.UsersRepository{DB: database}
@@ -434,10 +602,17 @@ func main() {
 		rbac.NewPermission("/api/v1/profile", "read_profile", "GET"),
 		api.GetProfile(usersRepository, rbacRepository, billingRepository, billingLimiter, logger),
 	))
+	r.Put("/api/v1/account/settings", auth(
+		rbac.NewPermission("/api/v1/account/settings", "update_account_settings", "PUT"),
+		api.UpdateAccountSettings(usersRepository, linksRepository, urlCache, logger),
+	))
+
+	idempotencyRepository := &idempotency.Repository{DB: database, Logger: logger}
+	idempotent := api.IdempotencyMiddleware(idempotencyRepository, appConfig.Idempotency.Window, logger)
 
 	r.Post("/api/v1/users/links/create", auth(
 		rbac.NewPermission("/api/v1/users/links/create", "create_link", "POST"),
-		urlBillingLimit(api.CreateUserLink(linksRepository, historyDB, urlCache, billingLimiter, logger)),
+		idempotent(urlBillingLimit(api.CreateUserLink(linksRepository, usersRepository, domainsRepository, historyDB, urlCache, billingLimiter, linkChecker, logger))),
 	))
 
 	r.Post("/api/v1/links/{id}/hide", auth(
@@ -450,14 +625,219 @@ func main() {
 		api.ActivateUserLink(linksRepository, urlCache, logger),
 	))
 
+	bulkJobsRepository := &bulk.Repository{DB: database, Logger: logger}
+	if n, err := bulkJobsRepository.FailInterruptedJobs(); err != nil {
+		logger.Println("interrupted bulk jobs error", err)
+	} else if n > 0 {
+		logger.Printf("%v interrupted bulk jobs are failed\n", n)
+	}
+
+	linksImporter := &importer.Importer{
+		Jobs:     bulkJobsRepository,
+		Links:    linksRepository,
+		Tags:     tagsRepository,
+		Users:    usersRepository,
+		Billing:  billingLimiter,
+		Checker:  linkChecker,
+		History:  historyDB,
+		URLCache: urlCache,
+		Logger:   logger,
+	}
+
 	r.Post("/api/v1/users/links/upload", auth(
 		rbac.NewPermission("/api/v1/users/links/upload", "upload_links", "POST"),
-		api.UploadLinksInBulk(billingLimiter, linksRepository, historyDB, urlCache, logger),
+		api.UploadLinksInBulk(linksImporter, logger),
 	))
 
 	r.Delete("/api/v1/users/links/delete", auth(
 		rbac.NewPermission("/api/v1/users/links/delete", "delete_link", "DELETE"),
-		api.DeleteUserLink(linksRepository, urlCache, billingLimiter, logger),
+		api.DeleteUserLink(linksRepository, urlCache, logger),
+	))
+
+	// bulk actions over links
+
+	bulkRunner := &bulk.Runner{
+		Jobs:      bulkJobsRepository,
+		Links:     linksRepository,
+		Tags:      tagsRepository,
+		Campaigns: campaignsRepository,
+		URLCache:  urlCache,
+		Logger:    logger,
+	}
+
+	r.Post("/api/v1/users/links/bulk", auth(
+		rbac.NewPermission("/api/v1/users/links/bulk", "create_bulk_job", "POST"),
+		api.CreateBulkJob(linksRepository, usersRepository, campaignsRepository, bulkRunner, logger),
+	))
+
+	r.Get("/api/v1/users/links/bulk", auth(
+		rbac.NewPermission("/api/v1/users/links/bulk", "read_bulk_jobs", "GET"),
+		api.GetBulkJobs(bulkJobsRepository, logger),
+	))
+
+	r.Get("/api/v1/users/links/bulk/{id}", auth(
+		rbac.NewPermission("/api/v1/users/links/bulk/{id}", "read_bulk_job", "GET"),
+		api.GetBulkJob(bulkJobsRepository, logger),
+	))
+
+	r.Get("/api/v1/users/links/export", auth(
+		rbac.NewPermission("/api/v1/users/links/export", "export_links", "GET"),
+		api.ExportLinks(linksRepository, historyDB, logger),
+	))
+
+	r.Get("/api/v1/users/links/export/stats", auth(
+		rbac.NewPermission("/api/v1/users/links/export/stats", "export_links_stats", "GET"),
+		api.ExportLinksStats(linksRepository, historyDB, logger),
+	))
+
+	r.Get("/api/v1/users/links/schedule", auth(
+		rbac.NewPermission("/api/v1/users/links/schedule", "read_link_schedule", "GET"),
+		api.GetScheduleEntries(scheduleRepository, logger),
+	))
+
+	r.Delete("/api/v1/users/links/schedule/{id}", auth(
+		rbac.NewPermission("/api/v1/users/links/schedule/{id}", "cancel_link_schedule", "DELETE"),
+		api.CancelScheduleEntry(scheduleRepository, logger),
+	))
+
+	r.Post("/api/v1/users/links/{id}/schedule", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/schedule", "create_link_schedule", "POST"),
+		api.CreateScheduleEntry(linksRepository, scheduleRepository, linkChecker, logger),
+	))
+
+	r.Get("/api/v1/users/links/trash", auth(
+		rbac.NewPermission("/api/v1/users/links/trash", "read_trash_links", "GET"),
+		api.GetTrashLinks(linksRepository, trashConfig.Retention, logger),
+	))
+
+	r.Post("/api/v1/users/links/trash/{id}/restore", auth(
+		rbac.NewPermission("/api/v1/users/links/trash/{id}/restore", "restore_link", "POST"),
+		api.RestoreLink(linksRepository, urlCache, logger),
+	))
+
+	r.Delete("/api/v1/users/links/trash/{id}", auth(
+		rbac.NewPermission("/api/v1/users/links/trash/{id}", "purge_link", "DELETE"),
+		api.PurgeTrashLink(linksRepository, trashPurger, logger),
+	))
+
+	r.Get("/api/v1/users/links/qr/export", auth(
+		rbac.NewPermission("/api/v1/users/links/qr/export", "export_qr_codes", "GET"),
+		api.ExportQRCodes(linksRepository, qrRepository, qrRenderer, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/qr", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/qr", "read_qr_code", "GET"),
+		api.LinkQRCode(linksRepository, qrRepository, qrRenderer, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/qr/style", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/qr/style", "read_qr_style", "GET"),
+		api.GetLinkQRStyle(linksRepository, qrRepository, logger),
+	))
+
+	r.Put("/api/v1/users/links/{id}/qr/style", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/qr/style", "update_qr_style", "PUT"),
+		api.SaveLinkQRStyle(linksRepository, qrRepository, logger),
+	))
+
+	r.Delete("/api/v1/users/links/{id}/qr/style", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/qr/style", "delete_qr_style", "DELETE"),
+		api.DeleteLinkQRStyle(linksRepository, qrRepository, logger),
+	))
+
+	r.Get("/api/v1/qr/logo", auth(
+		rbac.NewPermission("/api/v1/qr/logo", "read_qr_logo", "GET"),
+		api.GetQRLogo(qrRepository, logger),
+	))
+
+	r.Post("/api/v1/qr/logo", auth(
+		rbac.NewPermission("/api/v1/qr/logo", "upload_qr_logo", "POST"),
+		api.UploadQRLogo(qrRepository, logger),
+	))
+
+	r.Delete("/api/v1/qr/logo", auth(
+		rbac.NewPermission("/api/v1/qr/logo", "delete_qr_logo", "DELETE"),
+		api.DeleteQRLogo(qrRepository, logger),
+	))
+
+	r.Get("/api/v1/pages", auth(
+		rbac.NewPermission("/api/v1/pages", "read_pages", "GET"),
+		api.GetPages(pagesRepository, logger),
+	))
+
+	r.Post("/api/v1/pages", auth(
+		rbac.NewPermission("/api/v1/pages", "create_page", "POST"),
+		api.CreatePage(pagesRepository, logger),
+	))
+
+	r.Get("/api/v1/pages/{id}", auth(
+		rbac.NewPermission("/api/v1/pages/{id}", "read_page", "GET"),
+		api.GetPage(pagesRepository, logger),
+	))
+
+	r.Put("/api/v1/pages/{id}", auth(
+		rbac.NewPermission("/api/v1/pages/{id}", "update_page", "PUT"),
+		api.UpdatePage(pagesRepository, logger),
+	))
+
+	r.Delete("/api/v1/pages/{id}", auth(
+		rbac.NewPermission("/api/v1/pages/{id}", "delete_page", "DELETE"),
+		api.DeletePage(pagesRepository, historyDB, logger),
+	))
+
+	r.Put("/api/v1/pages/{id}/items", auth(
+		rbac.NewPermission("/api/v1/pages/{id}/items", "update_page_items", "PUT"),
+		api.SetPageItems(pagesRepository, linksRepository, logger),
+	))
+
+	r.Get("/api/v1/pages/{id}/views", auth(
+		rbac.NewPermission("/api/v1/pages/{id}/views", "read_page_views", "GET"),
+		api.GetPageViews(pagesRepository, historyDB, logger),
+	))
+
+	r.Get("/api/v1/pixels", auth(
+		rbac.NewPermission("/api/v1/pixels", "read_pixels", "GET"),
+		api.GetPixels(pixelsRepository, logger),
+	))
+
+	r.Post("/api/v1/pixels", auth(
+		rbac.NewPermission("/api/v1/pixels", "create_pixel", "POST"),
+		api.CreatePixel(pixelsRepository, logger),
+	))
+
+	r.Put("/api/v1/pixels/{id}", auth(
+		rbac.NewPermission("/api/v1/pixels/{id}", "update_pixel", "PUT"),
+		api.UpdatePixel(pixelsRepository, linksRepository, urlCache, logger),
+	))
+
+	r.Delete("/api/v1/pixels/{id}", auth(
+		rbac.NewPermission("/api/v1/pixels/{id}", "delete_pixel", "DELETE"),
+		api.DeletePixel(pixelsRepository, linksRepository, urlCache, logger),
+	))
+
+	r.Get("/api/v1/pixels/hosts", auth(
+		rbac.NewPermission("/api/v1/pixels/hosts", "read_pixel_hosts", "GET"),
+		api.GetPixelHosts(pixelsRepository, logger),
+	))
+
+	r.Post("/api/v1/pixels/hosts", auth(
+		rbac.NewPermission("/api/v1/pixels/hosts", "create_pixel_host", "POST"),
+		api.CreatePixelHost(pixelsRepository, logger),
+	))
+
+	r.Delete("/api/v1/pixels/hosts/{id}", auth(
+		rbac.NewPermission("/api/v1/pixels/hosts/{id}", "delete_pixel_host", "DELETE"),
+		api.DeletePixelHost(pixelsRepository, logger),
+	))
+
+	r.Get("/api/v1/users/links/{id}/pixels", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/pixels", "read_link_pixels", "GET"),
+		api.GetLinkPixels(linksRepository, logger),
+	))
+
+	r.Put("/api/v1/users/links/{id}/pixels", auth(
+		rbac.NewPermission("/api/v1/users/links/{id}/pixels", "update_link_pixels", "PUT"),
+		api.SetLinkPixels(linksRepository, pixelsRepository, urlCache, logger),
 	))
 
 	r.Get("/api/v1/groups", auth(
@@ -486,7 +866,7 @@ func main() {
 	))
 
 	api.RbacRoutes(r, auth, permissionRegistry, usersRepository, rbacRepository, logger)
-	api.CampaignRoutes(r, auth, campaignsRepository, logger)
+	api.CampaignRoutes(r, auth, campaignsRepository, urlCache, logger)
 	api.WebhooksRoutes(r, auth, webhooksRepository, logger)
 	api.DashboardsRoutes(r, auth, dashboardsRepository, logger)
 	api.ClicksRoutes(r, auth, clicksRepository, historyDB, billingLimiter, logger)
@@ -518,6 +898,9 @@ func main() {
 		database, appConfig.GeoIP.DownloadURL, appConfig.GeoIP.DatabasePath, appConfig.GeoIP.LicenseKey, logger,
 	)))
 	r.Post("/maintance/ip_info", basicAuth(api.GetIPInfo(appConfig.GeoIP.DatabasePath)))
+	r.Get("/maintance/blocklist", basicAuth(api.GetBlocklist(safetyRepository, logger)))
+	r.Post("/maintance/blocklist", basicAuth(api.CreateBlocklistEntry(safetyRepository, linkChecker, logger)))
+	r.Delete("/maintance/blocklist/{id}", basicAuth(api.DeleteBlocklistEntry(safetyRepository, linkChecker, logger)))
 	r.Post("/maintance/load_geoip_database", basicAuth(
 		api.UploadGeoIPDatabase(appConfig.GeoIP.DatabasePath, logger)))
 	r.Post("/maintance/load_stripe_fixtures", basicAuth(
@@ -533,10 +916,16 @@ func main() {
 	} else {
 		logger.Fatal("incorrect config params for redirect logger")
 	}
-	r.Get("/qr/*", api.QrCodeHandler(linksRepository, urlCache, logger))
+	r.Get("/api/v1/expand/{code}", api.ExpandLink(linksRepository, domainsRepository, historyDB, urlCache, logger))
+	r.Get("/qr/*", api.QrCodeHandler(domainsRepository, qrRepository, qrRenderer, urlCache, logger))
 	r.Get("/metrics", promhttp.Handler().(http.HandlerFunc))
-	r.Get("/*", totalRedirectsPromMiddleware(api.Redirect(
-		linksRepository, dbLogger, historyDB, urlCache, logger, appConfig.GeoIP.DatabasePath)))
+	r.Get("/p/{handle}", api.ShowPage(pagesRepository, linksRepository, historyDB, logger))
+	redirectHandler := totalRedirectsPromMiddleware(api.Redirect(
+		linksRepository, domainsRepository, dbLogger, historyDB, urlCache, logger, appConfig.GeoIP.DatabasePath,
+		appConfig.Pixels.Delay))
+	r.Get("/*", redirectHandler)
+	// unlock form of password protected links
+	r.Post("/*", redirectHandler)
 	var srv *http.Server
 	// server running
 	go func() {
@@ -556,7 +945,7 @@ func main() {
 		}
 	}()
 
-	shutdownCh := make(chan os.Signal)
+	shutdownCh := make(chan os.Signal, 1)
 	doneCh := make(chan struct{})
 
 	signal.Notify(shutdownCh, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1 @@
+DROP TABLE public.reserved_slugs;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE public.reserved_slugs
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL DEFAULT 0,
+    slug character varying(20) NOT NULL,
+    CONSTRAINT reserved_slugs_pk PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX reserved_slugs_account_slug_idx ON public.reserved_slugs (account_id, lower(slug));
@@ -0,0 +1,3 @@
+ALTER TABLE public.links DROP COLUMN expires_at;
+ALTER TABLE public.links DROP COLUMN max_clicks;
+ALTER TABLE public.links DROP COLUMN fallback_url;
@@ -0,0 +1,3 @@
+ALTER TABLE public.links ADD COLUMN expires_at timestamp with time zone;
+ALTER TABLE public.links ADD COLUMN max_clicks bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.links ADD COLUMN fallback_url text NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE public.links DROP COLUMN password;
@@ -0,0 +1 @@
+ALTER TABLE public.links ADD COLUMN password character varying NOT NULL DEFAULT '';
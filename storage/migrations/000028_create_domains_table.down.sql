@@ -0,0 +1,4 @@
+ALTER TABLE public.links DROP CONSTRAINT short_url_unique;
+ALTER TABLE public.links ADD CONSTRAINT short_url_unique UNIQUE (short_url);
+ALTER TABLE public.links DROP COLUMN domain_id;
+DROP TABLE public.domains;
@@ -0,0 +1,18 @@
+CREATE TABLE public.domains
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL,
+    host character varying NOT NULL,
+    verification_token character varying NOT NULL,
+    verified boolean NOT NULL DEFAULT false,
+    verified_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now(),
+    CONSTRAINT domains_pk PRIMARY KEY (id),
+    CONSTRAINT domains_account_host_unique UNIQUE (account_id, host)
+);
+
+CREATE UNIQUE INDEX domains_verified_host_idx ON public.domains (host) WHERE verified;
+
+ALTER TABLE public.links ADD COLUMN domain_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.links DROP CONSTRAINT short_url_unique;
+ALTER TABLE public.links ADD CONSTRAINT short_url_unique UNIQUE (domain_id, short_url);
@@ -0,0 +1,2 @@
+ALTER TABLE public.links DROP COLUMN redirect_type;
+ALTER TABLE public.accounts DROP COLUMN default_redirect_type;
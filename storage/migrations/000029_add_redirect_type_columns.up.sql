@@ -0,0 +1,2 @@
+ALTER TABLE public.links ADD COLUMN redirect_type smallint NOT NULL DEFAULT 0;
+ALTER TABLE public.accounts ADD COLUMN default_redirect_type smallint NOT NULL DEFAULT 0;
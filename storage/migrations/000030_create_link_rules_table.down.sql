@@ -0,0 +1,2 @@
+ALTER TABLE public.redirect_log DROP COLUMN rule_id;
+DROP TABLE public.link_rules;
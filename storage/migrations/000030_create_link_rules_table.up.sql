@@ -0,0 +1,15 @@
+CREATE TABLE public.link_rules
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    link_id bigint NOT NULL,
+    "position" integer NOT NULL DEFAULT 0,
+    countries character varying[] NOT NULL DEFAULT '{}',
+    destination text NOT NULL,
+    created_at timestamp with time zone DEFAULT now(),
+    CONSTRAINT link_rules_pk PRIMARY KEY (id),
+    CONSTRAINT link_rules_link_fk FOREIGN KEY (link_id) REFERENCES public.links (id) ON DELETE CASCADE
+);
+
+CREATE INDEX link_rules_link_id_idx ON public.link_rules (link_id);
+
+ALTER TABLE public.redirect_log ADD COLUMN rule_id bigint;
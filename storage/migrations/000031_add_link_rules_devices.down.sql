@@ -0,0 +1,2 @@
+ALTER TABLE public.link_rules DROP COLUMN devices;
+ALTER TABLE public.link_rules DROP COLUMN fallback;
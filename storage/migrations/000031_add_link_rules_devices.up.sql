@@ -0,0 +1,2 @@
+ALTER TABLE public.link_rules ADD COLUMN devices character varying[] NOT NULL DEFAULT '{}';
+ALTER TABLE public.link_rules ADD COLUMN fallback text NOT NULL DEFAULT '';
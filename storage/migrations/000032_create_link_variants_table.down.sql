@@ -0,0 +1,3 @@
+ALTER TABLE public.redirect_log DROP COLUMN variant_id;
+ALTER TABLE public.links DROP COLUMN sticky_variants;
+DROP TABLE public.link_variants;
@@ -0,0 +1,15 @@
+CREATE TABLE public.link_variants
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    link_id bigint NOT NULL,
+    destination text NOT NULL,
+    weight integer NOT NULL DEFAULT 1,
+    created_at timestamp with time zone DEFAULT now(),
+    CONSTRAINT link_variants_pk PRIMARY KEY (id),
+    CONSTRAINT link_variants_link_fk FOREIGN KEY (link_id) REFERENCES public.links (id) ON DELETE CASCADE
+);
+
+CREATE INDEX link_variants_link_id_idx ON public.link_variants (link_id);
+
+ALTER TABLE public.links ADD COLUMN sticky_variants boolean NOT NULL DEFAULT false;
+ALTER TABLE public.redirect_log ADD COLUMN variant_id bigint;
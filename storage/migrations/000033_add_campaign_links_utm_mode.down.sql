@@ -0,0 +1 @@
+ALTER TABLE public.campaigns_channels_links DROP COLUMN utm_mode;
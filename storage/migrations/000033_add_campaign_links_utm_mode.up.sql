@@ -0,0 +1 @@
+ALTER TABLE public.campaigns_channels_links ADD COLUMN utm_mode character varying NOT NULL DEFAULT 'preserve';
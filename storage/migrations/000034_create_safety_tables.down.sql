@@ -0,0 +1,6 @@
+ALTER TABLE public.links DROP COLUMN safety_score;
+ALTER TABLE public.links DROP COLUMN safety_status;
+ALTER TABLE public.links DROP COLUMN safety_reasons;
+ALTER TABLE public.links DROP COLUMN safety_checked_at;
+
+DROP TABLE public.blocklist;
@@ -0,0 +1,15 @@
+CREATE TABLE public.blocklist
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    kind character varying NOT NULL,
+    value character varying NOT NULL,
+    reason character varying NOT NULL DEFAULT '',
+    created_at timestamp with time zone DEFAULT now(),
+    CONSTRAINT blocklist_pk PRIMARY KEY (id),
+    CONSTRAINT blocklist_unique UNIQUE (kind, value)
+);
+
+ALTER TABLE public.links ADD COLUMN safety_score smallint NOT NULL DEFAULT 0;
+ALTER TABLE public.links ADD COLUMN safety_status character varying NOT NULL DEFAULT 'ok';
+ALTER TABLE public.links ADD COLUMN safety_reasons character varying[] NOT NULL DEFAULT '{}';
+ALTER TABLE public.links ADD COLUMN safety_checked_at timestamp with time zone;
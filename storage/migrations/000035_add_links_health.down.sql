@@ -0,0 +1,3 @@
+ALTER TABLE public.links DROP COLUMN broken;
+ALTER TABLE public.links DROP COLUMN health_status_code;
+ALTER TABLE public.links DROP COLUMN health_checked_at;
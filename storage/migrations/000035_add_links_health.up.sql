@@ -0,0 +1,3 @@
+ALTER TABLE public.links ADD COLUMN broken boolean NOT NULL DEFAULT false;
+ALTER TABLE public.links ADD COLUMN health_status_code integer NOT NULL DEFAULT 0;
+ALTER TABLE public.links ADD COLUMN health_checked_at timestamp with time zone;
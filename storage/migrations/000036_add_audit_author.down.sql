@@ -0,0 +1,3 @@
+DROP INDEX public.audit_entity_idx;
+ALTER TABLE public.audit DROP COLUMN previous;
+ALTER TABLE public.audit DROP COLUMN user_id;
@@ -0,0 +1,3 @@
+ALTER TABLE public.audit ADD COLUMN user_id bigint;
+ALTER TABLE public.audit ADD COLUMN previous jsonb;
+CREATE INDEX audit_entity_idx ON public.audit (entity, entity_id);
@@ -0,0 +1,2 @@
+DROP INDEX public.links_deleted_at_idx;
+ALTER TABLE public.links DROP COLUMN deleted_at;
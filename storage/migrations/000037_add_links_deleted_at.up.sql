@@ -0,0 +1,2 @@
+ALTER TABLE public.links ADD COLUMN deleted_at timestamp with time zone;
+CREATE INDEX links_deleted_at_idx ON public.links (deleted_at) WHERE deleted_at IS NOT NULL;
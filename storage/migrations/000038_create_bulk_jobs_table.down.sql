@@ -0,0 +1 @@
+DROP TABLE public.bulk_jobs;
@@ -0,0 +1,17 @@
+CREATE TABLE public.bulk_jobs
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    action character varying NOT NULL,
+    status character varying NOT NULL DEFAULT 'running',
+    total integer NOT NULL DEFAULT 0,
+    processed integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    results jsonb,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    finished_at timestamp with time zone,
+    CONSTRAINT bulk_jobs_pk PRIMARY KEY (id)
+);
+
+CREATE INDEX bulk_jobs_account_idx ON public.bulk_jobs (account_id);
@@ -0,0 +1,2 @@
+DROP SEQUENCE public.link_codes_seq;
+DROP TABLE public.link_code_settings;
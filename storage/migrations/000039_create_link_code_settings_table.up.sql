@@ -0,0 +1,12 @@
+CREATE TABLE public.link_code_settings
+(
+    account_id bigint NOT NULL,
+    strategy character varying(16) NOT NULL DEFAULT 'random',
+    alphabet character varying(64) NOT NULL DEFAULT '',
+    min_length smallint NOT NULL DEFAULT 5,
+    case_sensitive boolean NOT NULL DEFAULT true,
+    salt character varying(32) NOT NULL DEFAULT '',
+    CONSTRAINT link_code_settings_pk PRIMARY KEY (account_id)
+);
+
+CREATE SEQUENCE public.link_codes_seq;
@@ -0,0 +1,3 @@
+DROP TABLE public.idempotency_keys;
+DROP INDEX public.links_account_normalized_url_idx;
+ALTER TABLE public.links DROP COLUMN normalized_url;
@@ -0,0 +1,13 @@
+ALTER TABLE public.links ADD COLUMN normalized_url text NOT NULL DEFAULT '';
+CREATE INDEX links_account_normalized_url_idx ON public.links (account_id, md5(normalized_url)) WHERE deleted_at IS NULL;
+
+CREATE TABLE public.idempotency_keys
+(
+    account_id bigint NOT NULL,
+    key character varying(255) NOT NULL,
+    request_hash character varying(64) NOT NULL,
+    status_code smallint NOT NULL DEFAULT 0,
+    response bytea,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT idempotency_keys_pk PRIMARY KEY (account_id, key)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE public.link_schedules
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    link_id bigint NOT NULL,
+    action character varying(16) NOT NULL,
+    destination text NOT NULL DEFAULT '',
+    run_at timestamp with time zone NOT NULL,
+    status character varying(16) NOT NULL DEFAULT 'pending',
+    error text NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    claimed_at timestamp with time zone,
+    applied_at timestamp with time zone,
+    CONSTRAINT link_schedules_pk PRIMARY KEY (id),
+    CONSTRAINT link_schedules_link_fk FOREIGN KEY (link_id) REFERENCES public.links (id) ON DELETE CASCADE
+);
+
+CREATE INDEX link_schedules_account_idx ON public.link_schedules (account_id, link_id);
+CREATE INDEX link_schedules_pending_idx ON public.link_schedules (run_at) WHERE status = 'pending';
+CREATE INDEX link_schedules_running_idx ON public.link_schedules (claimed_at) WHERE status = 'running';
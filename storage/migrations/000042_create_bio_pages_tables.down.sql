@@ -0,0 +1,2 @@
+DROP TABLE public.bio_page_items;
+DROP TABLE public.bio_pages;
@@ -0,0 +1,32 @@
+CREATE TABLE public.bio_pages
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL,
+    handle character varying(32) NOT NULL,
+    title character varying(100) NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    avatar_url text NOT NULL DEFAULT '',
+    theme character varying(16) NOT NULL DEFAULT 'light',
+    published boolean NOT NULL DEFAULT false,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT bio_pages_pk PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX bio_pages_handle_idx ON public.bio_pages (lower(handle));
+CREATE INDEX bio_pages_account_idx ON public.bio_pages (account_id);
+
+CREATE TABLE public.bio_page_items
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    page_id bigint NOT NULL,
+    link_id bigint NOT NULL,
+    position integer NOT NULL,
+    title character varying(100) NOT NULL DEFAULT '',
+    icon text NOT NULL DEFAULT '',
+    CONSTRAINT bio_page_items_pk PRIMARY KEY (id),
+    CONSTRAINT bio_page_items_page_fk FOREIGN KEY (page_id) REFERENCES public.bio_pages (id) ON DELETE CASCADE,
+    CONSTRAINT bio_page_items_link_fk FOREIGN KEY (link_id) REFERENCES public.links (id) ON DELETE CASCADE
+);
+
+CREATE INDEX bio_page_items_page_idx ON public.bio_page_items (page_id, position);
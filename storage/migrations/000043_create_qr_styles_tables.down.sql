@@ -0,0 +1,2 @@
+DROP TABLE public.qr_logos;
+DROP TABLE public.link_qr_styles;
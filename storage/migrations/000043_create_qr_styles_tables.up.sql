@@ -0,0 +1,23 @@
+CREATE TABLE public.link_qr_styles
+(
+    link_id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    size integer NOT NULL,
+    level character varying(1) NOT NULL,
+    foreground character varying(7) NOT NULL,
+    background character varying(7) NOT NULL,
+    quiet_zone integer NOT NULL,
+    format character varying(3) NOT NULL,
+    logo boolean NOT NULL DEFAULT false,
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT link_qr_styles_pk PRIMARY KEY (link_id),
+    CONSTRAINT link_qr_styles_link_fk FOREIGN KEY (link_id) REFERENCES public.links (id) ON DELETE CASCADE
+);
+
+CREATE TABLE public.qr_logos
+(
+    account_id bigint NOT NULL,
+    content bytea NOT NULL,
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT qr_logos_pk PRIMARY KEY (account_id)
+);
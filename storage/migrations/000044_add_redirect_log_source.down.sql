@@ -0,0 +1 @@
+ALTER TABLE public.redirect_log DROP COLUMN source;
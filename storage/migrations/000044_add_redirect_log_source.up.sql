@@ -0,0 +1 @@
+ALTER TABLE public.redirect_log ADD COLUMN source character varying(16) NOT NULL DEFAULT '';
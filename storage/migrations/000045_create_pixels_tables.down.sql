@@ -0,0 +1,4 @@
+ALTER TABLE public.links DROP COLUMN pixel_delay;
+DROP TABLE public.link_pixels;
+DROP TABLE public.pixels;
+DROP TABLE public.pixel_hosts;
@@ -0,0 +1,38 @@
+CREATE TABLE public.pixel_hosts
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL,
+    host character varying(253) NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pixel_hosts_pk PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX pixel_hosts_account_host_idx ON public.pixel_hosts (account_id, host);
+
+CREATE TABLE public.pixels
+(
+    id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+    account_id bigint NOT NULL,
+    name character varying(100) NOT NULL,
+    kind character varying(16) NOT NULL,
+    url text NOT NULL,
+    host character varying(253) NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pixels_pk PRIMARY KEY (id)
+);
+
+CREATE INDEX pixels_account_idx ON public.pixels (account_id, host);
+
+CREATE TABLE public.link_pixels
+(
+    link_id bigint NOT NULL,
+    pixel_id bigint NOT NULL,
+    CONSTRAINT link_pixels_pk PRIMARY KEY (link_id, pixel_id),
+    CONSTRAINT link_pixels_link_fk FOREIGN KEY (link_id) REFERENCES public.links (id) ON DELETE CASCADE,
+    CONSTRAINT link_pixels_pixel_fk FOREIGN KEY (pixel_id) REFERENCES public.pixels (id) ON DELETE CASCADE
+);
+
+CREATE INDEX link_pixels_pixel_idx ON public.link_pixels (pixel_id);
+
+ALTER TABLE public.links ADD COLUMN pixel_delay integer NOT NULL DEFAULT 0;
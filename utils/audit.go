@@ -26,7 +26,7 @@ func (i *AuditQuery) doInsertQuery(entityName string, tx *sql.Tx, query string,
 	}
 	_, err = tx.Exec(fmt.Sprintf(`
 		insert into audit (entity, entity_id, snapshot, timestamp, action) values ($1, $2, 
-			(select row_to_json(e, true) from %s as e where id = $2), now(), 'create'
+			(select `+auditSnapshot+` from %s as e where id = $2), now(), 'create'
 		)
 	`, entityName), entityName, rowID)
 	return rowID, errors.Wrap(err, "audit error")
@@ -36,7 +36,7 @@ func (i *AuditQuery) doDeleteQuery(entityName string, tx *sql.Tx, query string,
 	var rowID int64
 	var snapshot string
 
-	snapshotQuery := strings.Replace(query, "delete", "select row_to_json(e)", -1)
+	snapshotQuery := strings.Replace(query, "delete", "select "+auditSnapshot, -1)
 	snapshotQuery = strings.Replace(snapshotQuery, "returning id", "", -1)
 	fmt.Println(snapshotQuery)
 	if err := tx.QueryRow(snapshotQuery, args...).Scan(&snapshot); err != nil {
@@ -54,6 +54,40 @@ func (i *AuditQuery) doDeleteQuery(entityName string, tx *sql.Tx, query string,
 	return rowID, err
 }
 
+// auditSnapshot is a snapshot of a row in audit, password hashes are never stored
+const auditSnapshot = `(to_jsonb(e) - 'password')`
+
+// columnsSnapshot is a snapshot of listed columns of a row, all columns are stored when the list is empty
+func columnsSnapshot(columns []string) string {
+	if len(columns) == 0 {
+		return auditSnapshot
+	}
+	fields := make([]string, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, fmt.Sprintf("'%s', e.%s", column, column))
+	}
+	return "jsonb_build_object(" + strings.Join(fields, ", ") + ")"
+}
+
+// doUpdateQuery stores snapshots of a row before and after update together with an author of the change
+func (i *AuditQuery) doUpdateQuery(entityName string, columns []string, tx *sql.Tx, userID, rowID int64, query string, args ...interface{}) error {
+	snapshot := columnsSnapshot(columns)
+	var previous string
+	err := tx.QueryRow(fmt.Sprintf(`select %s from %s as e where id = $1 for update`, snapshot, entityName), rowID).Scan(&previous)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf(`
+		insert into audit (entity, entity_id, snapshot, previous, user_id, timestamp, action) values ($1, $2,
+			(select %s from %s as e where id = $2), $3, $4, now(), 'update'
+		)
+	`, snapshot, entityName), entityName, rowID, previous, userID)
+	return errors.Wrap(err, "audit error")
+}
+
 func (i *AuditQuery) Create(entityName string, tx *sql.Tx, query string, args ...interface{}) (int64, error) {
 	return i.doInsertQuery(entityName, tx, query, args...)
 }
@@ -62,6 +96,12 @@ func (i *AuditQuery) Delete(entityName string, tx *sql.Tx, query string, args ..
 	return i.doDeleteQuery(entityName, tx, query, args...)
 }
 
+// Update runs an update query of a single row identified by rowID, userID is an author of the change,
+// snapshots keep listed columns only, all columns are kept for an empty list
+func (i *AuditQuery) Update(entityName string, columns []string, tx *sql.Tx, userID, rowID int64, query string, args ...interface{}) error {
+	return i.doUpdateQuery(entityName, columns, tx, userID, rowID, query, args...)
+}
+
 func (i *AuditQuery) CreateTx(entityName string, db *sql.DB, query string, args ...interface{}) (int64, error) {
 	tx, err := db.Begin()
 	if err != nil {
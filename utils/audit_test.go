@@ -0,0 +1,15 @@
+package utils
+
+import "testing"
+
+func TestColumnsSnapshot(t *testing.T) {
+
+	if snapshot := columnsSnapshot(nil); snapshot != auditSnapshot {
+		t.Errorf("expected a full row snapshot without passwords, got %s", snapshot)
+	}
+
+	expected := "jsonb_build_object('long_url', e.long_url, 'description', e.description)"
+	if snapshot := columnsSnapshot([]string{"long_url", "description"}); snapshot != expected {
+		t.Errorf("expected %s, got %s", expected, snapshot)
+	}
+}
@@ -11,12 +11,15 @@ import (
 )
 
 type linkRedirect struct {
-	ShortUrl string
-	LongUrl  string
-	Headers  map[string]interface{}
-	IPAddr   string
-	Country  string
-	Referer  string
+	ShortUrl  string
+	LongUrl   string
+	Headers   map[string]interface{}
+	IPAddr    string
+	Country   string
+	Referer   string
+	RuleID    int64
+	VariantID int64
+	Source    string
 }
 
 // DbLogger ...
@@ -69,8 +72,8 @@ func (l *SyncLogger) Push(body []byte) error {
 	}
 
 	_, err = l.db.Exec(`
-		insert into redirect_log(short_url, long_url, headers, country, ip_addr, referer, timestamp) 
-		values ($1, $2, $3, $4, $5, $6, now())
+		insert into redirect_log(short_url, long_url, headers, country, ip_addr, referer, rule_id, variant_id, source, timestamp) 
+		values ($1, $2, $3, $4, $5, $6, nullif($7, 0), nullif($8, 0), $9, now())
 	`,
 		msg.ShortUrl,
 		msg.LongUrl,
@@ -78,6 +81,9 @@ func (l *SyncLogger) Push(body []byte) error {
 		msg.Country,
 		msg.IPAddr,
 		msg.Referer,
+		msg.RuleID,
+		msg.VariantID,
+		msg.Source,
 	)
 
 	return err
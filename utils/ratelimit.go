@@ -59,3 +59,67 @@ func RateLimit(getLimiter func(w http.ResponseWriter, r *http.Request) *rate.Lim
 		})
 	}
 }
+
+// KeyRateLimiter keeps a separate rate limiter for every key (ip address, link, etc.)
+type KeyRateLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*IPRateLimiter
+	limit    rate.Limit
+	burst    int
+}
+
+// NewKeyRateLimiter ...
+func NewKeyRateLimiter(limit rate.Limit, burst int) *KeyRateLimiter {
+
+	l := &KeyRateLimiter{
+		limiters: make(map[string]*IPRateLimiter),
+		limit:    limit,
+		burst:    burst,
+	}
+
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			l.mu.Lock()
+			for k, v := range l.limiters {
+				if time.Since(v.created) > 3*time.Minute {
+					delete(l.limiters, k)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+
+	return l
+}
+
+func (l *KeyRateLimiter) get(key string) *IPRateLimiter {
+	limiter, exists := l.limiters[key]
+	if !exists {
+		limiter = &IPRateLimiter{rate.NewLimiter(l.limit, l.burst), Now()}
+		l.limiters[key] = limiter
+	} else {
+		limiter.created = Now()
+	}
+	return limiter
+}
+
+// Exceeded reports whether the key has no events left, the check itself is not counted as an event
+func (l *KeyRateLimiter) Exceeded(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	reservation := l.get(key).ReserveN(now, 1)
+	defer reservation.CancelAt(now)
+
+	return !reservation.OK() || reservation.DelayFrom(now) > 0
+}
+
+// Hit registers an event for the key
+func (l *KeyRateLimiter) Hit(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.get(key).Allow()
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"strings"
+)
+
+// Device classes of a user agent
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Operating systems of a user agent
+const (
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+)
+
+// UserAgent is a classified User-Agent header
+type UserAgent struct {
+	Device string
+	OS     string
+}
+
+var botMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview", "curl/", "wget/", "python-requests", "go-http-client",
+}
+
+// ParseUserAgent classifies User-Agent header by device class and operating system,
+// it only looks for well known platform tokens and doesn't try to detect browsers
+func ParseUserAgent(ua string) UserAgent {
+
+	s := strings.ToLower(ua)
+
+	if s == "" {
+		return UserAgent{Device: DeviceOther, OS: OSOther}
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(s, marker) {
+			return UserAgent{Device: DeviceBot, OS: OSOther}
+		}
+	}
+
+	switch {
+	case strings.Contains(s, "windows phone"):
+		return UserAgent{Device: DeviceMobile, OS: OSWindows}
+	case strings.Contains(s, "ipad"):
+		return UserAgent{Device: DeviceTablet, OS: OSIOS}
+	case strings.Contains(s, "iphone"), strings.Contains(s, "ipod"):
+		return UserAgent{Device: DeviceMobile, OS: OSIOS}
+	case strings.Contains(s, "android"):
+		// android tablets omit "Mobile" token
+		if strings.Contains(s, "mobile") {
+			return UserAgent{Device: DeviceMobile, OS: OSAndroid}
+		}
+		return UserAgent{Device: DeviceTablet, OS: OSAndroid}
+	case strings.Contains(s, "cros "):
+		return UserAgent{Device: DeviceDesktop, OS: OSChromeOS}
+	case strings.Contains(s, "windows"):
+		return UserAgent{Device: DeviceDesktop, OS: OSWindows}
+	case strings.Contains(s, "macintosh"), strings.Contains(s, "mac os x"):
+		return UserAgent{Device: DeviceDesktop, OS: OSMacOS}
+	case strings.Contains(s, "linux"), strings.Contains(s, "x11"):
+		return UserAgent{Device: DeviceDesktop, OS: OSLinux}
+	}
+
+	return UserAgent{Device: DeviceOther, OS: OSOther}
+}
+
+// Matches checks whether user agent belongs to a device class or an operating system
+func (ua UserAgent) Matches(target string) bool {
+	target = strings.ToLower(target)
+	return target == ua.Device || target == ua.OS
+}
+
+// UserAgentTargets is a list of values accepted by UserAgent.Matches
+var UserAgentTargets = []string{
+	DeviceMobile, DeviceTablet, DeviceDesktop, DeviceBot,
+	OSIOS, OSAndroid, OSWindows, OSMacOS, OSLinux, OSChromeOS,
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"testing"
+)
+
+// userAgentCorpus is a set of real User-Agent headers
+var userAgentCorpus = []struct {
+	ua     string
+	device string
+	os     string
+}{
+	// iOS
+	{"Mozilla/5.0 (iPhone; CPU iPhone OS 14_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.1 Mobile/15E148 Safari/604.1", DeviceMobile, OSIOS},
+	{"Mozilla/5.0 (iPhone; CPU iPhone OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/80.0.3987.95 Mobile/15E148 Safari/604.1", DeviceMobile, OSIOS},
+	{"Mozilla/5.0 (iPhone; CPU iPhone OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 155.0.0.37.107", DeviceMobile, OSIOS},
+	{"Mozilla/5.0 (iPad; CPU OS 12_4_8 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.2 Mobile/15E148 Safari/604.1", DeviceTablet, OSIOS},
+	{"Mozilla/5.0 (iPod touch; CPU iPhone OS 12_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.2 Mobile/15E148 Safari/604.1", DeviceMobile, OSIOS},
+	// Android
+	{"Mozilla/5.0 (Linux; Android 10; SM-G975F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.198 Mobile Safari/537.36", DeviceMobile, OSAndroid},
+	{"Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.66 Mobile Safari/537.36", DeviceMobile, OSAndroid},
+	{"Mozilla/5.0 (Android 10; Mobile; rv:83.0) Gecko/83.0 Firefox/83.0", DeviceMobile, OSAndroid},
+	{"Mozilla/5.0 (Linux; Android 9; SM-T510) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.198 Safari/537.36", DeviceTablet, OSAndroid},
+	{"Mozilla/5.0 (Linux; U; Android 4.0.3; ko-kr; LG-L160L Build/IML74K) AppleWebkit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30", DeviceMobile, OSAndroid},
+	// desktop
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36", DeviceDesktop, OSWindows},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36 Edg/87.0.664.66", DeviceDesktop, OSWindows},
+	{"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko", DeviceDesktop, OSWindows},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.1 Safari/605.1.15", DeviceDesktop, OSMacOS},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:84.0) Gecko/20100101 Firefox/84.0", DeviceDesktop, OSMacOS},
+	{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:83.0) Gecko/20100101 Firefox/83.0", DeviceDesktop, OSLinux},
+	{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36", DeviceDesktop, OSLinux},
+	{"Mozilla/5.0 (X11; CrOS x86_64 13421.89.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.199 Safari/537.36", DeviceDesktop, OSChromeOS},
+	// other
+	{"Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; Lumia 950) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Mobile Safari/537.36 Edge/15.14977", DeviceMobile, OSWindows},
+	{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", DeviceBot, OSOther},
+	{"Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/41.0.2272.96 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", DeviceBot, OSOther},
+	{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", DeviceBot, OSOther},
+	{"Twitterbot/1.0", DeviceBot, OSOther},
+	{"curl/7.64.1", DeviceBot, OSOther},
+	{"", DeviceOther, OSOther},
+	{"Opera/9.80 (J2ME/MIDP; Opera Mini/4.2.14912/870; U; id) Presto/2.4.15", DeviceOther, OSOther},
+}
+
+func TestParseUserAgent(t *testing.T) {
+	for _, c := range userAgentCorpus {
+		ua := ParseUserAgent(c.ua)
+		if ua.Device != c.device || ua.OS != c.os {
+			t.Errorf("ua(%s): expected %s/%s, got %s/%s", c.ua, c.device, c.os, ua.Device, ua.OS)
+		}
+	}
+}
+
+func TestUserAgentMatches(t *testing.T) {
+
+	ua := ParseUserAgent("Mozilla/5.0 (iPhone; CPU iPhone OS 14_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.1 Mobile/15E148 Safari/604.1")
+
+	for _, target := range []string{"ios", "mobile", "IOS"} {
+		if !ua.Matches(target) {
+			t.Errorf("expected %s to match iphone", target)
+		}
+	}
+
+	for _, target := range []string{"android", "desktop", "macos"} {
+		if ua.Matches(target) {
+			t.Errorf("expected %s not to match iphone", target)
+		}
+	}
+}